@@ -1,13 +1,47 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/gundamkid/anti-gravity-quota/internal/api"
 	"github.com/gundamkid/anti-gravity-quota/internal/auth"
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
 	"github.com/gundamkid/anti-gravity-quota/internal/ui"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// revokeTimeout bounds the revocation request made before a token is removed
+const revokeTimeout = 15 * time.Second
+
+var (
+	localOnlyFlag bool
+	yesFlag       bool
+
+	metaAlias        string
+	metaLabels       []string
+	metaAddLabels    []string
+	metaRemoveLabels []string
+	metaNotes        string
+	metaColor        string
+
+	pruneUnusedFor string
+	pruneDryRun    bool
+
+	bundleOutput            string
+	bundleConflict          string
+	bundleWithNotifications bool
 )
 
 // accountsCmd represents the accounts command
@@ -18,9 +52,16 @@ var accountsCmd = &cobra.Command{
 	
 Examples:
   ag-quota accounts list              # List all saved accounts
+  ag-quota accounts list --label ci   # List accounts with a label
+  ag-quota accounts set user@gmail.com --alias work --add-label team-A
   ag-quota accounts default user@gmail.com  # Set default account
   ag-quota accounts switch user@gmail.com   # Alias for default
-  ag-quota accounts remove user@gmail.com   # Remove account`,
+  ag-quota accounts remove user@gmail.com   # Revoke and remove account
+  ag-quota accounts revoke-all              # Revoke and remove all accounts
+  ag-quota accounts prune --unused-for 30d  # Remove accounts unused for 30 days
+  ag-quota accounts export -o accounts.bundle  # Export accounts to move them
+  ag-quota accounts import accounts.bundle     # Import accounts on another machine
+  ag-quota accounts refresh-metadata        # Re-resolve project and tier of all accounts`,
 	RunE: runAccountsList, // Default action: list accounts
 }
 
@@ -42,13 +83,98 @@ var accountsDefaultCmd = &cobra.Command{
 	RunE:    runAccountsDefault,
 }
 
+// accountsPruneCmd represents the accounts prune command
+var accountsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove accounts that have not been used for a while",
+	Long: `Revoke and remove accounts whose last quota fetch, token refresh or login is
+older than --unused-for. Accounts without recorded activity are judged by when
+their token was last saved.`,
+	Example: `  ag-quota accounts prune --unused-for 30d --dry-run
+  ag-quota accounts prune --unused-for 2w --yes`,
+	Args: cobra.NoArgs,
+	RunE: runAccountsPrune,
+}
+
+// accountsExportCmd represents the accounts export command
+var accountsExportCmd = &cobra.Command{
+	Use:   "export [email|alias...]",
+	Short: "Export accounts to an encrypted bundle",
+	Long: `Write the tokens and metadata of the selected accounts, and the default account,
+to a single passphrase-encrypted bundle that can be imported on another machine.
+Without arguments every account is exported; --label narrows the selection.
+
+The passphrase is read from ` + auth.EnvBundlePassphrase + ` or asked for interactively.`,
+	Example: `  ag-quota accounts export -o accounts.bundle
+  ag-quota accounts export work personal@gmail.com -o two.bundle
+  ag-quota accounts export --label ci --with-notifications -o ci.bundle`,
+	RunE: runAccountsExport,
+}
+
+// accountsImportCmd represents the accounts import command
+var accountsImportCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Import accounts from an encrypted bundle",
+	Long: `Save the accounts of a bundle created by "accounts export" using the local vault
+backend. Accounts that already exist are handled by --on-conflict:
+  skip       keep the local account (default)
+  overwrite  replace the local account
+  newest     keep whichever token was issued last
+
+The bundle's default account becomes the default if none is set locally.
+Use "-" to read the bundle from stdin.`,
+	Example: `  ag-quota accounts import accounts.bundle
+  ag-quota accounts import ci.bundle --on-conflict newest --with-notifications`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAccountsImport,
+}
+
+// accountsSetCmd represents the accounts set command
+var accountsSetCmd = &cobra.Command{
+	Use:   "set <email|alias>",
+	Short: "Set the alias, labels, notes or color of an account",
+	Long: `Record metadata about a saved account. The alias can be used instead of the
+email wherever an account is expected, e.g. --account work. Labels group
+accounts, e.g. 'ag-quota quota --all --label team-A'.
+
+Pass an empty value (e.g. --alias "") to clear a field.`,
+	Example: `  ag-quota accounts set someone.long.name+work@gmail.com --alias work
+  ag-quota accounts set work --labels team-A,ci --color cyan
+  ag-quota accounts set work --add-label personal --remove-label ci
+  ag-quota accounts set work --notes "Shared with the build runners"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAccountsSet,
+}
+
 // accountsRemoveCmd represents the accounts remove command
 var accountsRemoveCmd = &cobra.Command{
 	Use:   "remove <email>",
 	Short: "Remove a saved account",
-	Long:  `Delete the saved authentication token for the specified account.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runAccountsRemove,
+	Long: `Revoke the account's token at Google and delete it locally.
+If revocation fails the local token is kept; pass --local-only to delete it anyway.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAccountsRemove,
+}
+
+// accountsRevokeAllCmd represents the accounts revoke-all command
+var accountsRevokeAllCmd = &cobra.Command{
+	Use:   "revoke-all",
+	Short: "Revoke and remove every saved account",
+	Long: `Revoke the tokens of all saved accounts at Google and delete them locally,
+e.g. when offboarding a machine. Accounts whose revocation fails are kept so
+the command can be run again.`,
+	Args: cobra.NoArgs,
+	RunE: runAccountsRevokeAll,
+}
+
+// accountsRefreshMetadataCmd represents the accounts refresh-metadata command
+var accountsRefreshMetadataCmd = &cobra.Command{
+	Use:   "refresh-metadata [email]",
+	Short: "Re-resolve the project and tier of accounts",
+	Long: `Discard the cached project ID and tier and resolve them again from the API.
+Without an email, the metadata of every saved account is refreshed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAccountsRefreshMetadata,
 }
 
 // runAccountsList handles listing all saved accounts
@@ -72,19 +198,31 @@ func runAccountsList(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if labelFlag != "" {
+		accounts = auth.FilterByLabel(accounts, labelFlag)
+		if len(accounts) == 0 {
+			color.Yellow("No accounts with label %q", labelFlag)
+			return nil
+		}
+	}
+
 	ui.DisplayAccountsList(accounts)
 	return nil
 }
 
 // runAccountsDefault handles setting the default account
 func runAccountsDefault(cmd *cobra.Command, args []string) error {
-	email := args[0]
-
 	mgr, err := auth.NewAccountManager()
 	if err != nil {
 		return fmt.Errorf("failed to initialize account manager: %w", err)
 	}
 
+	email, err := mgr.ResolveAccount(args[0])
+	if err != nil {
+		fmt.Printf("Account %s not found.\n", args[0])
+		return nil
+	}
+
 	// Set the account as default
 	if err := mgr.SetDefaultAccount(email); err != nil {
 		// Check if it's a "not found" error
@@ -101,13 +239,17 @@ func runAccountsDefault(cmd *cobra.Command, args []string) error {
 
 // runAccountsRemove handles removing a saved account
 func runAccountsRemove(cmd *cobra.Command, args []string) error {
-	email := args[0]
-
 	mgr, err := auth.NewAccountManager()
 	if err != nil {
 		return fmt.Errorf("failed to initialize account manager: %w", err)
 	}
 
+	email, err := mgr.ResolveAccount(args[0])
+	if err != nil {
+		fmt.Printf("Account %s not found.\n", args[0])
+		return nil
+	}
+
 	// For simplicity, we skip confirmation for now, or we could add a --force flag.
 	// But let's follow standard CLI practice if we can.
 	// Simple confirmation for now.
@@ -119,15 +261,493 @@ func runAccountsRemove(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	if err := mgr.RemoveAccount(email); err != nil {
-		if errors.Is(err, auth.ErrAccountNotFound) {
-			fmt.Printf("Account %s not found.\n", email)
+	mgr.ChooseDefault = chooseDefaultAccount
+	previousDefault := currentDefaultAccount(mgr)
+
+	removed, failed := removeAccounts(cmd.Context(), mgr, []string{email}, localOnlyFlag)
+	if failed > 0 {
+		return fmt.Errorf("account %s was not removed", email)
+	}
+
+	for _, email := range removed {
+		fmt.Printf("✅ Account %s removed successfully.\n", email)
+	}
+	reportDefaultChange(mgr, previousDefault)
+	return nil
+}
+
+// runAccountsSet handles updating the metadata of an account
+func runAccountsSet(cmd *cobra.Command, args []string) error {
+	mgr, err := auth.NewAccountManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize account manager: %w", err)
+	}
+
+	email, err := mgr.ResolveAccount(args[0])
+	if err != nil {
+		fmt.Printf("Account %s not found.\n", args[0])
+		return nil
+	}
+
+	token, err := auth.LoadTokenForAccount(email)
+	if err != nil {
+		return fmt.Errorf("failed to load account: %w", err)
+	}
+
+	meta := token.Metadata
+	flags := cmd.Flags()
+	if flags.Changed("alias") {
+		meta.Alias = metaAlias
+	}
+	if flags.Changed("labels") {
+		meta.Labels = metaLabels
+	}
+	if flags.Changed("add-label") {
+		meta.Labels = append(meta.Labels, metaAddLabels...)
+	}
+	if flags.Changed("remove-label") {
+		meta.Labels = slices.DeleteFunc(meta.Labels, func(label string) bool {
+			return slices.ContainsFunc(metaRemoveLabels, func(remove string) bool {
+				return strings.EqualFold(label, remove)
+			})
+		})
+	}
+	if flags.Changed("notes") {
+		meta.Notes = metaNotes
+	}
+	if flags.Changed("color") {
+		meta.Color = metaColor
+	}
+
+	if !flags.Changed("alias") && !flags.Changed("labels") && !flags.Changed("add-label") &&
+		!flags.Changed("remove-label") && !flags.Changed("notes") && !flags.Changed("color") {
+		color.Yellow("No changes provided. Use --alias, --labels, --add-label, --remove-label, --notes or --color flags.")
+		return nil
+	}
+
+	if err := mgr.SetAccountMetadata(email, meta); err != nil {
+		return fmt.Errorf("failed to update account: %w", err)
+	}
+
+	fmt.Printf("✅ Account %s updated.\n", email)
+	return nil
+}
+
+// runAccountsPrune handles removing accounts that have not been used recently
+func runAccountsPrune(cmd *cobra.Command, args []string) error {
+	unusedFor, err := parseAge(pruneUnusedFor)
+	if err != nil {
+		return fmt.Errorf("invalid --unused-for: %w", err)
+	}
+
+	mgr, err := auth.NewAccountManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize account manager: %w", err)
+	}
+
+	unused, err := mgr.UnusedAccounts(unusedFor, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to list accounts: %w", err)
+	}
+	if labelFlag != "" {
+		unused = auth.FilterByLabel(unused, labelFlag)
+	}
+
+	if len(unused) == 0 {
+		fmt.Printf("No accounts unused for %s.\n", pruneUnusedFor)
+		return nil
+	}
+
+	fmt.Printf("Accounts unused for %s:\n", pruneUnusedFor)
+	for _, acc := range unused {
+		lastUsed := "never recorded"
+		if !acc.LastUsed.IsZero() {
+			lastUsed = "last used " + acc.LastUsed.Local().Format("2006-01-02")
+		}
+		fmt.Printf("  - %s (%s)\n", acc.Email, lastUsed)
+	}
+
+	if pruneDryRun {
+		return nil
+	}
+
+	if !yesFlag {
+		fmt.Printf("Remove %d account(s)? (y/N): ", len(unused))
+		var response string
+		_, _ = fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	emails := make([]string, 0, len(unused))
+	for _, acc := range unused {
+		emails = append(emails, acc.Email)
+	}
+
+	previousDefault := currentDefaultAccount(mgr)
+	removed, failed := removeAccounts(cmd.Context(), mgr, emails, localOnlyFlag)
+
+	fmt.Printf("✅ Pruned %d account(s).\n", len(removed))
+	reportDefaultChange(mgr, previousDefault)
+	if failed > 0 {
+		return fmt.Errorf("%d account(s) could not be revoked and were kept", failed)
+	}
+	return nil
+}
+
+// parseAge parses a duration that may also be given in days or weeks, e.g. 30d or 2w
+func parseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("a duration is required, e.g. 30d")
+	}
+
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	if unit, ok := units[value[len(value)-1:]]; ok {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n) * unit, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+// runAccountsExport handles writing accounts to an encrypted bundle
+func runAccountsExport(cmd *cobra.Command, args []string) error {
+	if bundleOutput == "" {
+		return fmt.Errorf("--output is required (use - for stdout)")
+	}
+
+	passphrase, err := bundlePassphrase(true)
+	if err != nil {
+		return err
+	}
+
+	bundle, emails, err := auth.ExportBundle(passphrase, auth.ExportOptions{
+		Emails:               args,
+		Label:                labelFlag,
+		IncludeNotifications: bundleWithNotifications,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export accounts: %w", err)
+	}
+
+	if bundleOutput == "-" {
+		_, err = os.Stdout.Write(append(bundle, '\n'))
+		return err
+	}
+	if err := config.AtomicWrite(bundleOutput, bundle, 0600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	fmt.Printf("✅ Exported %d account(s) to %s:\n", len(emails), bundleOutput)
+	for _, email := range emails {
+		fmt.Printf("  - %s\n", email)
+	}
+	if bundleWithNotifications {
+		fmt.Println("Notification settings included.")
+	}
+	return nil
+}
+
+// runAccountsImport handles restoring accounts from an encrypted bundle
+func runAccountsImport(cmd *cobra.Command, args []string) error {
+	var (
+		bundle []byte
+		err    error
+	)
+	if args[0] == "-" {
+		bundle, err = io.ReadAll(os.Stdin)
+	} else {
+		bundle, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	passphrase, err := bundlePassphrase(false)
+	if err != nil {
+		return err
+	}
+
+	result, err := auth.ImportBundle(bundle, passphrase, auth.ImportOptions{
+		Conflict:             bundleConflict,
+		IncludeNotifications: bundleWithNotifications,
+	})
+	if result != nil {
+		for _, email := range result.Imported {
+			fmt.Printf("  + %s\n", email)
+		}
+		for _, email := range result.Overwritten {
+			fmt.Printf("  ~ %s (overwritten)\n", email)
+		}
+		for _, email := range result.Skipped {
+			fmt.Printf("  = %s (already present, skipped)\n", email)
+		}
+		for _, warning := range result.Warnings {
+			color.Yellow("⚠️  %s", warning)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to import accounts: %w", err)
+	}
+
+	fmt.Printf("✅ Imported %d, overwrote %d, skipped %d account(s).\n",
+		len(result.Imported), len(result.Overwritten), len(result.Skipped))
+	if result.DefaultAccount != "" {
+		fmt.Printf("Default account is now: %s\n", result.DefaultAccount)
+	}
+	if result.NotificationsApplied {
+		fmt.Println("Notification settings applied.")
+	}
+	return nil
+}
+
+// bundlePassphrase returns the bundle passphrase from the environment or the
+// terminal, asking twice when a new bundle is being created
+func bundlePassphrase(confirm bool) (string, error) {
+	if env := os.Getenv(auth.EnvBundlePassphrase); env != "" {
+		return env, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("stdin is not a terminal, set %s instead", auth.EnvBundlePassphrase)
+	}
+
+	first, err := readPassphrase("🔐 Bundle passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if first == "" {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+	if confirm {
+		second, err := readPassphrase("🔐 Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if first != second {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return first, nil
+}
+
+// runAccountsRevokeAll handles revoking and removing all saved accounts
+func runAccountsRevokeAll(cmd *cobra.Command, args []string) error {
+	mgr, err := auth.NewAccountManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize account manager: %w", err)
+	}
+
+	accounts, err := mgr.ListAccounts()
+	if err != nil {
+		return fmt.Errorf("failed to list accounts: %w", err)
+	}
+
+	if labelFlag != "" {
+		accounts = auth.FilterByLabel(accounts, labelFlag)
+	}
+
+	if len(accounts) == 0 {
+		color.Yellow("No accounts found")
+		return nil
+	}
+
+	if !yesFlag {
+		if labelFlag != "" {
+			fmt.Printf("Revoke and remove all %d account(s) labeled %q? (y/N): ", len(accounts), labelFlag)
+		} else {
+			fmt.Printf("Revoke and remove all %d account(s)? (y/N): ", len(accounts))
+		}
+		var response string
+		_, _ = fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Aborted.")
 			return nil
 		}
-		return fmt.Errorf("failed to remove account: %w", err)
 	}
 
-	fmt.Printf("✅ Account %s removed successfully.\n", email)
+	emails := make([]string, 0, len(accounts))
+	for _, acc := range accounts {
+		emails = append(emails, acc.Email)
+	}
+
+	removed, failed := removeAccounts(cmd.Context(), mgr, emails, false)
+
+	fmt.Println()
+	fmt.Printf("✅ Revoked and removed %d account(s).\n", len(removed))
+	if failed > 0 {
+		return fmt.Errorf("%d account(s) could not be revoked and were kept", failed)
+	}
+	return nil
+}
+
+// removeAccounts revokes (unless localOnly) and removes each account, reporting
+// failures as it goes. Accounts whose revocation fails are kept.
+func removeAccounts(ctx context.Context, mgr *auth.AccountManager, emails []string, localOnly bool) (removed []string, failed int) {
+	for _, email := range emails {
+		if !localOnly && !revokeBeforeRemoval(ctx, email) {
+			failed++
+			continue
+		}
+		if err := mgr.RemoveAccount(email); err != nil {
+			if !errors.Is(err, auth.ErrDefaultNotPromoted) {
+				color.Red("❌ Failed to remove %s: %v", email, err)
+				failed++
+				continue
+			}
+			// The account is gone, only choosing the next default failed
+			color.Yellow("⚠️  Removed %s, but %v", email, err)
+		}
+		removed = append(removed, email)
+	}
+	return removed, failed
+}
+
+// currentDefaultAccount returns the configured default account, or "" if none is set
+func currentDefaultAccount(mgr *auth.AccountManager) string {
+	cfg, err := mgr.LoadConfig()
+	if err != nil {
+		return ""
+	}
+	return cfg.DefaultAccount
+}
+
+// reportDefaultChange tells the user which account became the default after a removal
+func reportDefaultChange(mgr *auth.AccountManager, previous string) {
+	current := currentDefaultAccount(mgr)
+	if current == previous {
+		return
+	}
+	if current == "" {
+		if accounts, err := mgr.ListAccounts(); err == nil && len(accounts) > 0 {
+			fmt.Println("No default account set. Choose one with 'ag-quota accounts default <email>'.")
+			return
+		}
+		fmt.Println("No accounts left. Run 'ag-quota login' to add one.")
+		return
+	}
+	fmt.Printf("⭐ Default account is now: %s\n", current)
+}
+
+// chooseDefaultAccount asks which account becomes the new default. Without a
+// terminal the first candidate is picked.
+func chooseDefaultAccount(candidates []string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return candidates[0], nil
+	}
+
+	fmt.Println("The default account was removed. Which account should become the default?")
+	for i, email := range candidates {
+		fmt.Printf("  %d) %s\n", i+1, email)
+	}
+	fmt.Print("Choice [1]: ")
+
+	var response string
+	_, _ = fmt.Scanln(&response)
+	if response == "" {
+		return candidates[0], nil
+	}
+
+	choice, err := strconv.Atoi(response)
+	if err != nil || choice < 1 || choice > len(candidates) {
+		return "", fmt.Errorf("invalid choice %q, set one with 'ag-quota accounts default <email>'", response)
+	}
+	return candidates[choice-1], nil
+}
+
+// revokeBeforeRemoval revokes the grant of an account at Google and reports the
+// outcome. It returns false when the local token must be kept because revocation failed.
+func revokeBeforeRemoval(ctx context.Context, email string) bool {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, revokeTimeout)
+	defer cancel()
+
+	err := auth.RevokeAccount(ctx, email)
+	switch {
+	case err == nil:
+		fmt.Printf("🔒 Revoked access of %s at Google\n", email)
+		return true
+	case errors.Is(err, auth.ErrTokenAlreadyInvalid):
+		color.Yellow("⚠️  Token of %s was already expired or revoked at Google", email)
+		return true
+	default:
+		color.Red("❌ Failed to revoke token of %s: %v", email, err)
+		fmt.Println("   The local token was kept. Try again, or pass --local-only to remove it without revoking.")
+		return false
+	}
+}
+
+// runAccountsRefreshMetadata handles re-resolving the project metadata of accounts
+func runAccountsRefreshMetadata(cmd *cobra.Command, args []string) error {
+	mgr, err := auth.NewAccountManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize account manager: %w", err)
+	}
+
+	var emails []string
+	if len(args) == 1 {
+		email, err := mgr.ResolveAccount(args[0])
+		if err != nil {
+			email = args[0]
+		}
+		emails = []string{email}
+	} else {
+		accounts, err := mgr.ListAccounts()
+		if err != nil {
+			return fmt.Errorf("failed to list accounts: %w", err)
+		}
+		for _, acc := range accounts {
+			emails = append(emails, acc.Email)
+		}
+	}
+
+	if len(emails) == 0 {
+		color.Yellow("No accounts found")
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	failed := 0
+	for _, email := range emails {
+		if _, err := auth.LoadTokenForAccount(email); err != nil {
+			fmt.Printf("Account %s not found.\n", email)
+			failed++
+			continue
+		}
+
+		client, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+
+		projectID, tierID, err := client.RefreshProjectMetadata(ctx, email)
+		if err != nil {
+			color.Red("❌ %s: %v", email, err)
+			failed++
+			continue
+		}
+
+		if projectID == "" {
+			projectID = "(none)"
+		}
+		fmt.Printf("✅ %s: project %s, tier %s\n", email, projectID, api.LookupTier(tierID).Label)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to refresh metadata for %d account(s)", failed)
+	}
 	return nil
 }
 
@@ -139,4 +759,31 @@ func init() {
 	accountsCmd.AddCommand(accountsListCmd)
 	accountsCmd.AddCommand(accountsDefaultCmd)
 	accountsCmd.AddCommand(accountsRemoveCmd)
+	accountsCmd.AddCommand(accountsRefreshMetadataCmd)
+	accountsCmd.AddCommand(accountsRevokeAllCmd)
+	accountsCmd.AddCommand(accountsSetCmd)
+	accountsCmd.AddCommand(accountsPruneCmd)
+	accountsCmd.AddCommand(accountsExportCmd)
+	accountsCmd.AddCommand(accountsImportCmd)
+
+	accountsExportCmd.Flags().StringVarP(&bundleOutput, "output", "o", "", "File to write the bundle to (- for stdout)")
+	accountsExportCmd.Flags().BoolVar(&bundleWithNotifications, "with-notifications", false, "Include the notification settings")
+	accountsImportCmd.Flags().StringVar(&bundleConflict, "on-conflict", auth.ConflictSkip, "What to do with accounts that already exist: skip, overwrite or newest")
+	accountsImportCmd.Flags().BoolVar(&bundleWithNotifications, "with-notifications", false, "Apply the notification settings from the bundle")
+
+	accountsPruneCmd.Flags().StringVar(&pruneUnusedFor, "unused-for", "", "Remove accounts unused for this long, e.g. 30d, 2w or 72h")
+	accountsPruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Only list the accounts that would be removed")
+	accountsPruneCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "Skip the confirmation prompt")
+	accountsPruneCmd.Flags().BoolVar(&localOnlyFlag, "local-only", false, "Delete the local tokens without revoking them at Google")
+	_ = accountsPruneCmd.MarkFlagRequired("unused-for")
+
+	accountsSetCmd.Flags().StringVar(&metaAlias, "alias", "", "Short name usable instead of the email")
+	accountsSetCmd.Flags().StringSliceVar(&metaLabels, "labels", nil, "Replace the labels (comma-separated)")
+	accountsSetCmd.Flags().StringSliceVar(&metaAddLabels, "add-label", nil, "Add a label (repeatable)")
+	accountsSetCmd.Flags().StringSliceVar(&metaRemoveLabels, "remove-label", nil, "Remove a label (repeatable)")
+	accountsSetCmd.Flags().StringVar(&metaNotes, "notes", "", "Free-form notes")
+	accountsSetCmd.Flags().StringVar(&metaColor, "color", "", "Color of the account in listings: "+strings.Join(auth.AccountColors, ", "))
+
+	accountsRemoveCmd.Flags().BoolVar(&localOnlyFlag, "local-only", false, "Delete the local token without revoking it at Google")
+	accountsRevokeAllCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "Skip the confirmation prompt")
 }
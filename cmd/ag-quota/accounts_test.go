@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/auth"
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+	"github.com/gundamkid/anti-gravity-quota/internal/fakeserver"
+)
+
+// setupAccounts saves a valid token for each email, registered at a fresh fake server
+func setupAccounts(t *testing.T, emails ...string) *fakeserver.Server {
+	t.Helper()
+
+	srv := fakeserver.New()
+	t.Cleanup(srv.Close)
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	useFakeEndpoints(t, srv)
+
+	for _, email := range emails {
+		accessToken := srv.AddAccount(fakeserver.OnboardedAccount(email))
+		err := auth.SaveTokenForAccount(email, &auth.TokenData{
+			AccessToken:  accessToken,
+			RefreshToken: srv.RefreshToken(email),
+			Expiry:       time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("SaveTokenForAccount failed: %v", err)
+		}
+	}
+	return srv
+}
+
+func TestAccountsRevokeAll(t *testing.T) {
+	emails := []string{"alice@example.com", "bob@example.com", "carol@example.com"}
+	srv := setupAccounts(t, emails...)
+
+	// The first revocation fails, so that account must be kept
+	srv.InjectFaults(fakeserver.MethodRevoke, fakeserver.ServerError(http.StatusInternalServerError))
+
+	out, err := execute(t, "accounts", "revoke-all", "--yes")
+	if err == nil {
+		t.Error("expected an error for the account that could not be revoked")
+	}
+	if !strings.Contains(out, "Revoked and removed 2 account(s)") {
+		t.Errorf("unexpected summary:\n%s", out)
+	}
+
+	mgr, errMgr := auth.NewAccountManager()
+	if errMgr != nil {
+		t.Fatalf("NewAccountManager failed: %v", errMgr)
+	}
+
+	kept := 0
+	for _, email := range emails {
+		if mgr.HasAccount(email) {
+			kept++
+			if srv.Revoked(email) {
+				t.Errorf("%s was kept locally but revoked", email)
+			}
+		} else if !srv.Revoked(email) {
+			t.Errorf("%s was removed locally without being revoked", email)
+		}
+	}
+	if kept != 1 {
+		t.Errorf("expected 1 account to be kept, got %d", kept)
+	}
+}
+
+func TestLogout_PromotesDefault(t *testing.T) {
+	srv := setupAccounts(t, "alice@example.com", "bob@example.com")
+
+	mgr, err := auth.NewAccountManager()
+	if err != nil {
+		t.Fatalf("NewAccountManager failed: %v", err)
+	}
+	if err := mgr.SetDefaultAccount("alice@example.com"); err != nil {
+		t.Fatalf("SetDefaultAccount failed: %v", err)
+	}
+
+	out, err := execute(t, "logout")
+	if err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+	if !srv.Revoked("alice@example.com") || srv.Revoked("bob@example.com") {
+		t.Error("expected only the default account to be revoked")
+	}
+	if !strings.Contains(out, "Default account is now: bob@example.com") {
+		t.Errorf("expected the promotion to be reported:\n%s", out)
+	}
+
+	token, err := auth.LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken after logout failed: %v", err)
+	}
+	if token.Email != "bob@example.com" {
+		t.Errorf("expected bob to be the default, got %s", token.Email)
+	}
+}
+
+func TestRemoveAccounts_PromotionFails(t *testing.T) {
+	setupAccounts(t, "alice@example.com", "bob@example.com", "carol@example.com")
+
+	mgr, err := auth.NewAccountManager()
+	if err != nil {
+		t.Fatalf("NewAccountManager failed: %v", err)
+	}
+	if err := mgr.SetDefaultAccount("alice@example.com"); err != nil {
+		t.Fatalf("SetDefaultAccount failed: %v", err)
+	}
+	mgr.ChooseDefault = func(candidates []string) (string, error) {
+		return "", errors.New("invalid choice")
+	}
+
+	removed, failed := removeAccounts(t.Context(), mgr, []string{"alice@example.com"}, true)
+	if failed != 0 || len(removed) != 1 {
+		t.Errorf("expected alice to count as removed, got removed=%v failed=%d", removed, failed)
+	}
+	if mgr.HasAccount("alice@example.com") {
+		t.Error("expected alice to be removed")
+	}
+
+	out := captureStdout(t, func() { reportDefaultChange(mgr, "alice@example.com") })
+	if !strings.Contains(out, "No default account set") {
+		t.Errorf("expected the missing default to be reported:\n%s", out)
+	}
+}
+
+func TestLogout_AccountAndAll(t *testing.T) {
+	srv := setupAccounts(t, "alice@example.com", "bob@example.com", "carol@example.com")
+
+	// --account logs out a single account, --local-only skips revocation
+	if _, err := execute(t, "logout", "--account", "carol@example.com", "--local-only"); err != nil {
+		t.Fatalf("logout --account failed: %v", err)
+	}
+	if srv.Revoked("carol@example.com") {
+		t.Error("--local-only must not revoke the token")
+	}
+
+	mgr, err := auth.NewAccountManager()
+	if err != nil {
+		t.Fatalf("NewAccountManager failed: %v", err)
+	}
+	if mgr.HasAccount("carol@example.com") {
+		t.Error("expected carol to be logged out")
+	}
+
+	if _, err := execute(t, "logout", "--all"); err != nil {
+		t.Fatalf("logout --all failed: %v", err)
+	}
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if mgr.HasAccount(email) || !srv.Revoked(email) {
+			t.Errorf("expected %s to be revoked and removed", email)
+		}
+	}
+
+	if _, err := auth.LoadToken(); !errors.Is(err, auth.ErrNotLoggedIn) {
+		t.Errorf("expected ErrNotLoggedIn after logging out of everything, got %v", err)
+	}
+}
+
+func TestBulkRemoval_Label(t *testing.T) {
+	srv := setupAccounts(t, "alice@example.com", "bob@example.com", "carol@example.com")
+
+	mgr, err := auth.NewAccountManager()
+	if err != nil {
+		t.Fatalf("NewAccountManager failed: %v", err)
+	}
+	for _, email := range []string{"alice@example.com", "carol@example.com"} {
+		if err := mgr.SetAccountMetadata(email, auth.AccountMetadata{Labels: []string{"ci"}}); err != nil {
+			t.Fatalf("SetAccountMetadata failed: %v", err)
+		}
+	}
+
+	// Commands that do not filter by label must not ignore it
+	for _, args := range [][]string{
+		{"logout", "--label", "ci"},
+		{"accounts", "remove", "bob@example.com", "--label", "ci"},
+		{"config", "set-slack", "--webhook-url", "https://hooks.slack.com/x", "--label", "ci"},
+	} {
+		if _, err := execute(t, args...); err == nil || !strings.Contains(err.Error(), "--label") {
+			t.Errorf("%v: expected a --label error, got %v", args, err)
+		}
+	}
+
+	if _, err := execute(t, "logout", "--all", "--label", "CI"); err != nil {
+		t.Fatalf("logout --all --label failed: %v", err)
+	}
+	for email, kept := range map[string]bool{"alice@example.com": false, "bob@example.com": true, "carol@example.com": false} {
+		if mgr.HasAccount(email) != kept || srv.Revoked(email) == kept {
+			t.Errorf("%s: expected kept=%v, got kept=%v revoked=%v", email, kept, mgr.HasAccount(email), srv.Revoked(email))
+		}
+	}
+
+	if _, err := execute(t, "accounts", "revoke-all", "--label", "ci", "--yes"); err != nil {
+		t.Fatalf("revoke-all --label failed: %v", err)
+	}
+	if !mgr.HasAccount("bob@example.com") {
+		t.Error("revoke-all --label must keep accounts without the label")
+	}
+}
+
+func TestAccountsPrune(t *testing.T) {
+	srv := setupAccounts(t, "fresh@example.com", "stale@example.com")
+
+	data, err := json.Marshal(map[string]auth.AccountActivity{
+		"stale@example.com": {LastUsed: time.Now().Add(-40 * 24 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := config.GetActivityPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.RecordAccountActivity("fresh@example.com", nil); err != nil {
+		t.Fatalf("RecordAccountActivity failed: %v", err)
+	}
+
+	out, err := execute(t, "accounts", "prune", "--unused-for", "30d", "--dry-run")
+	if err != nil {
+		t.Fatalf("prune --dry-run failed: %v", err)
+	}
+	if !strings.Contains(out, "stale@example.com") || strings.Contains(out, "fresh@example.com") {
+		t.Errorf("unexpected dry-run listing:\n%s", out)
+	}
+
+	mgr, err := auth.NewAccountManager()
+	if err != nil {
+		t.Fatalf("NewAccountManager failed: %v", err)
+	}
+	if !mgr.HasAccount("stale@example.com") {
+		t.Fatal("--dry-run must not remove accounts")
+	}
+
+	if _, err := execute(t, "accounts", "prune", "--unused-for", "30d", "--yes"); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if mgr.HasAccount("stale@example.com") || !srv.Revoked("stale@example.com") {
+		t.Error("expected the stale account to be revoked and removed")
+	}
+	if !mgr.HasAccount("fresh@example.com") || srv.Revoked("fresh@example.com") {
+		t.Error("expected the recently used account to be kept")
+	}
+
+	if _, err := execute(t, "accounts", "prune", "--unused-for", "soon"); err == nil {
+		t.Error("expected an invalid duration to be rejected")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"72h": 72 * time.Hour,
+	}
+	for input, want := range tests {
+		got, err := parseAge(input)
+		if err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+
+	for _, input := range []string{"", "d", "-3d", "0w", "forever"} {
+		if _, err := parseAge(input); err == nil {
+			t.Errorf("parseAge(%q) should fail", input)
+		}
+	}
+}
+
+func TestAccountsExportImport(t *testing.T) {
+	setupAccounts(t, "alice@example.com", "bob@example.com")
+	t.Setenv(auth.EnvBundlePassphrase, "moving day")
+
+	bundlePath := filepath.Join(t.TempDir(), "accounts.bundle")
+	out, err := execute(t, "accounts", "export", "bob@example.com", "-o", bundlePath)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if !strings.Contains(out, "Exported 1 account(s)") {
+		t.Errorf("unexpected export output:\n%s", out)
+	}
+
+	// Import on a machine that has never seen the account
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	out, err = execute(t, "accounts", "import", bundlePath)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, "+ bob@example.com") {
+		t.Errorf("unexpected import output:\n%s", out)
+	}
+
+	out, err = execute(t, "accounts", "import", bundlePath)
+	if err != nil {
+		t.Fatalf("second import failed: %v", err)
+	}
+	if !strings.Contains(out, "bob@example.com (already present, skipped)") {
+		t.Errorf("expected the existing account to be skipped:\n%s", out)
+	}
+
+	token, err := auth.LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken failed: %v", err)
+	}
+	if token.Email != "bob@example.com" {
+		t.Errorf("expected bob to be the default, got %s", token.Email)
+	}
+}
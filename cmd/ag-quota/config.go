@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"maps"
+	"net/http"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/gundamkid/anti-gravity-quota/internal/auth"
 	"github.com/gundamkid/anti-gravity-quota/internal/config"
 	"github.com/gundamkid/anti-gravity-quota/internal/notify"
 	"github.com/gundamkid/anti-gravity-quota/internal/ui"
@@ -15,6 +20,42 @@ import (
 var (
 	telegramToken  string
 	telegramChatID string
+
+	slackWebhookURL   string
+	discordWebhookURL string
+
+	emailHost     string
+	emailPort     int
+	emailSecurity string
+	emailUsername string
+	emailPassword string
+	emailFrom     string
+	emailTo       []string
+	emailSubject  string
+
+	ntfyTopicURL string
+	ntfyToken    string
+	gotifyURL    string
+	gotifyToken  string
+
+	apiEndpointSetting   string
+	apiProxySetting      string
+	apiTimeoutSetting    int
+	apiUserAgentSetting  string
+	apiOnboardingTimeout int
+
+	vaultBackend          string
+	vaultKeyFile          string
+	vaultGenerateKey      bool
+	vaultChangePassphrase bool
+
+	webhookURL             string
+	webhookMethod          string
+	webhookHeaders         []string
+	webhookSecret          string
+	webhookSignatureHeader string
+	webhookTemplate        string
+	webhookTemplateFile    string
 )
 
 // configCmd represents the config command
@@ -69,7 +110,10 @@ var setTelegramCmd = &cobra.Command{
 			cfg.Notifications.Enabled = true
 		}
 
-		if err := config.SaveConfig(cfg); err != nil {
+		err = saveNotifierSettings(func(n *config.NotificationSettings) {
+			n.Telegram = cfg.Notifications.Telegram
+		}, cfg.Notifications.Enabled)
+		if err != nil {
 			ui.DisplayError("Failed to save config", err)
 			os.Exit(1)
 		}
@@ -100,18 +144,587 @@ var getTelegramCmd = &cobra.Command{
 	},
 }
 
+// setSlackCmd represents the set-slack command
+var setSlackCmd = &cobra.Command{
+	Use:   "set-slack",
+	Short: "Set the Slack incoming webhook for notifications",
+	Long: `Configure the Slack incoming webhook that notifications are posted to.
+Create one under "Incoming Webhooks" of a Slack app; it posts to the channel
+chosen there. Pass --webhook-url "" to remove it.`,
+	Example: `  ag-quota config set-slack --webhook-url https://hooks.slack.com/services/T000/B000/XXXX`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !cmd.Flags().Changed("webhook-url") {
+			color.Yellow("No changes provided. Use the --webhook-url flag.")
+			return
+		}
+		if slackWebhookURL != "" {
+			if err := validateHTTPURL(slackWebhookURL); err != nil {
+				ui.DisplayError("Invalid webhook URL", err)
+				os.Exit(1)
+			}
+		}
+
+		enable := slackWebhookURL != ""
+		err := saveNotifierSettings(func(n *config.NotificationSettings) {
+			n.Slack.WebhookURL = slackWebhookURL
+		}, enable)
+		if err != nil {
+			ui.DisplayError("Failed to save config", err)
+			os.Exit(1)
+		}
+
+		color.Green("✓ Slack configuration updated successfully")
+		if enable {
+			color.Cyan("Notifications are now ENABLED")
+			fmt.Println("Run 'ag-quota config test-notify' to post a test message.")
+		}
+	},
+}
+
+// getSlackCmd represents the get-slack command
+var getSlackCmd = &cobra.Command{
+	Use:   "get-slack",
+	Short: "View Slack notification settings",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Slack Configuration")
+		fmt.Println("===================")
+		fmt.Printf("Notifications: %s\n", statusString(cfg.Notifications.Enabled))
+		fmt.Printf("Webhook URL:   %s\n", maskToken(cfg.Notifications.Slack.WebhookURL))
+	},
+}
+
+// setDiscordCmd represents the set-discord command
+var setDiscordCmd = &cobra.Command{
+	Use:   "set-discord",
+	Short: "Set the Discord webhook for notifications",
+	Long: `Configure the Discord webhook that notifications are posted to.
+Create one under Server Settings > Integrations > Webhooks; it posts to the
+channel chosen there. Pass --webhook-url "" to remove it.`,
+	Example: `  ag-quota config set-discord --webhook-url https://discord.com/api/webhooks/123/abc`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !cmd.Flags().Changed("webhook-url") {
+			color.Yellow("No changes provided. Use the --webhook-url flag.")
+			return
+		}
+		if discordWebhookURL != "" {
+			if err := validateHTTPURL(discordWebhookURL); err != nil {
+				ui.DisplayError("Invalid webhook URL", err)
+				os.Exit(1)
+			}
+
+			// Validate webhook before saving
+			fmt.Print("Validating Discord Webhook... ")
+			if err := notify.NewDiscordNotifier(discordWebhookURL).Validate(cmd.Context()); err != nil {
+				fmt.Println(color.RedString("FAILED"))
+				ui.DisplayError("Invalid Discord webhook", err)
+				os.Exit(1)
+			}
+			fmt.Println(color.GreenString("OK"))
+		}
+
+		enable := discordWebhookURL != ""
+		err := saveNotifierSettings(func(n *config.NotificationSettings) {
+			n.Discord.WebhookURL = discordWebhookURL
+		}, enable)
+		if err != nil {
+			ui.DisplayError("Failed to save config", err)
+			os.Exit(1)
+		}
+
+		color.Green("✓ Discord configuration updated successfully")
+		if enable {
+			color.Cyan("Notifications are now ENABLED")
+		}
+	},
+}
+
+// getDiscordCmd represents the get-discord command
+var getDiscordCmd = &cobra.Command{
+	Use:   "get-discord",
+	Short: "View Discord notification settings",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Discord Configuration")
+		fmt.Println("=====================")
+		fmt.Printf("Notifications: %s\n", statusString(cfg.Notifications.Enabled))
+		fmt.Printf("Webhook URL:   %s\n", maskToken(cfg.Notifications.Discord.WebhookURL))
+	},
+}
+
+// setEmailCmd represents the set-email command
+var setEmailCmd = &cobra.Command{
+	Use:   "set-email",
+	Short: "Send notifications by email over SMTP",
+	Long: `Configure the SMTP server, sender and recipients for email notifications.
+Each notification is sent as one email with a plain-text and an HTML part.
+Pass an empty value (e.g. --subject "") to restore the default.
+
+Security:
+  starttls  connect in plain text and upgrade with STARTTLS (default, port 587)
+  tls       connect with TLS from the start (port 465)
+  none      no encryption, e.g. for a relay on localhost (port 25)
+
+The subject is a Go text/template executed with .Title, .Severity (info,
+recovery, warning or critical), .Changes and .Accounts; the upper function
+capitalizes. The default is: ` + notify.DefaultEmailSubjectTemplate,
+	Example: `  ag-quota config set-email --host smtp.example.com --username bot --password "$SMTP_PASSWORD" \
+    --from "ag-quota <quota@example.com>" --to ops@example.com --to lead@example.com`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		email := cfg.Notifications.Email
+		updated := false
+		if cmd.Flags().Changed("host") {
+			email.Host = emailHost
+			updated = true
+		}
+		if cmd.Flags().Changed("port") {
+			if emailPort < 0 || emailPort > 65535 {
+				ui.DisplayError("Invalid port", fmt.Errorf("port must be between 1 and 65535 (0 = default)"))
+				os.Exit(1)
+			}
+			email.Port = emailPort
+			updated = true
+		}
+		if cmd.Flags().Changed("security") {
+			email.Security = strings.ToLower(emailSecurity)
+			updated = true
+		}
+		if cmd.Flags().Changed("username") {
+			email.Username = emailUsername
+			updated = true
+		}
+		if cmd.Flags().Changed("password") {
+			email.Password = emailPassword
+			updated = true
+		}
+		if cmd.Flags().Changed("from") {
+			email.From = emailFrom
+			updated = true
+		}
+		if cmd.Flags().Changed("to") {
+			email.To = nil
+			for _, to := range emailTo {
+				if to = strings.TrimSpace(to); to != "" {
+					email.To = append(email.To, to)
+				}
+			}
+			updated = true
+		}
+		if cmd.Flags().Changed("subject") {
+			email.SubjectTemplate = emailSubject
+			updated = true
+		}
+
+		if !updated {
+			color.Yellow("No changes provided. Use --host, --port, --security, --username, --password, --from, --to or --subject flags.")
+			return
+		}
+
+		// Reject settings the notifier could not use
+		en, err := notify.NewEmailNotifier(emailOptions(email))
+		if err != nil {
+			ui.DisplayError("Invalid email settings", err)
+			os.Exit(1)
+		}
+
+		enable := en.IsEnabled()
+		err = saveNotifierSettings(func(n *config.NotificationSettings) {
+			n.Email = email
+		}, enable)
+		if err != nil {
+			ui.DisplayError("Failed to save config", err)
+			os.Exit(1)
+		}
+
+		color.Green("✓ Email configuration updated successfully")
+		if enable {
+			color.Cyan("Notifications are now ENABLED")
+		} else {
+			color.Yellow("Email is sent once --host, --from and --to are all set.")
+		}
+	},
+}
+
+// getEmailCmd represents the get-email command
+var getEmailCmd = &cobra.Command{
+	Use:   "get-email",
+	Short: "View email notification settings",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		email := cfg.Notifications.Email
+		port := "default"
+		if email.Port > 0 {
+			port = fmt.Sprintf("%d", email.Port)
+		}
+
+		fmt.Println("Email Configuration")
+		fmt.Println("===================")
+		fmt.Printf("Notifications: %s\n", statusString(cfg.Notifications.Enabled))
+		fmt.Printf("Host:          %s\n", valueOrNotSet(email.Host))
+		fmt.Printf("Port:          %s\n", port)
+		fmt.Printf("Security:      %s\n", valueOrDefault(email.Security))
+		fmt.Printf("Username:      %s\n", valueOrNotSet(email.Username))
+		fmt.Printf("Password:      %s\n", maskToken(email.Password))
+		fmt.Printf("From:          %s\n", valueOrNotSet(email.From))
+		fmt.Printf("To:            %s\n", valueOrNotSet(strings.Join(email.To, ", ")))
+		fmt.Printf("Subject:       %s\n", valueOrDefault(email.SubjectTemplate))
+	},
+}
+
+// setNtfyCmd represents the set-ntfy command
+var setNtfyCmd = &cobra.Command{
+	Use:   "set-ntfy",
+	Short: "Set the ntfy topic for push notifications",
+	Long: `Configure the ntfy topic that notifications are published to, on ntfy.sh or a
+self-hosted server. The priority follows the severity (critical is urgent) and
+the tags show the emoji of each status. Pass --topic-url "" to remove it.`,
+	Example: `  ag-quota config set-ntfy --topic-url https://ntfy.example.com/quota --token tk_XXXX`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		ntfy := cfg.Notifications.Ntfy
+		updated := false
+		if cmd.Flags().Changed("topic-url") {
+			if ntfyTopicURL != "" {
+				if _, _, err := notify.SplitNtfyTopicURL(ntfyTopicURL); err != nil {
+					ui.DisplayError("Invalid topic URL", err)
+					os.Exit(1)
+				}
+			}
+			ntfy.TopicURL = ntfyTopicURL
+			updated = true
+		}
+		if cmd.Flags().Changed("token") {
+			ntfy.Token = ntfyToken
+			updated = true
+		}
+
+		if !updated {
+			color.Yellow("No changes provided. Use --topic-url and --token flags.")
+			return
+		}
+
+		enable := ntfy.TopicURL != ""
+		err = saveNotifierSettings(func(n *config.NotificationSettings) {
+			n.Ntfy = ntfy
+		}, enable)
+		if err != nil {
+			ui.DisplayError("Failed to save config", err)
+			os.Exit(1)
+		}
+
+		color.Green("✓ ntfy configuration updated successfully")
+		if enable {
+			color.Cyan("Notifications are now ENABLED")
+		}
+	},
+}
+
+// getNtfyCmd represents the get-ntfy command
+var getNtfyCmd = &cobra.Command{
+	Use:   "get-ntfy",
+	Short: "View ntfy notification settings",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("ntfy Configuration")
+		fmt.Println("==================")
+		fmt.Printf("Notifications: %s\n", statusString(cfg.Notifications.Enabled))
+		fmt.Printf("Topic URL:     %s\n", valueOrNotSet(cfg.Notifications.Ntfy.TopicURL))
+		fmt.Printf("Token:         %s\n", maskToken(cfg.Notifications.Ntfy.Token))
+	},
+}
+
+// setGotifyCmd represents the set-gotify command
+var setGotifyCmd = &cobra.Command{
+	Use:   "set-gotify",
+	Short: "Set the Gotify server for push notifications",
+	Long: `Configure the Gotify server and application token that notifications are sent
+with. Create the token under Apps in the Gotify web UI. The priority follows
+the severity; critical notifications pop up on Android.`,
+	Example: `  ag-quota config set-gotify --url https://gotify.example.com --token AbCdEf123`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		gotify := cfg.Notifications.Gotify
+		updated := false
+		if cmd.Flags().Changed("url") {
+			if gotifyURL != "" {
+				if err := validateHTTPURL(gotifyURL); err != nil {
+					ui.DisplayError("Invalid server URL", err)
+					os.Exit(1)
+				}
+			}
+			gotify.URL = gotifyURL
+			updated = true
+		}
+		if cmd.Flags().Changed("token") {
+			gotify.Token = gotifyToken
+			updated = true
+		}
+
+		if !updated {
+			color.Yellow("No changes provided. Use --url and --token flags.")
+			return
+		}
+
+		enable := gotify.URL != "" && gotify.Token != ""
+		err = saveNotifierSettings(func(n *config.NotificationSettings) {
+			n.Gotify = gotify
+		}, enable)
+		if err != nil {
+			ui.DisplayError("Failed to save config", err)
+			os.Exit(1)
+		}
+
+		color.Green("✓ Gotify configuration updated successfully")
+		if enable {
+			color.Cyan("Notifications are now ENABLED")
+		}
+	},
+}
+
+// getGotifyCmd represents the get-gotify command
+var getGotifyCmd = &cobra.Command{
+	Use:   "get-gotify",
+	Short: "View Gotify notification settings",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Gotify Configuration")
+		fmt.Println("====================")
+		fmt.Printf("Notifications: %s\n", statusString(cfg.Notifications.Enabled))
+		fmt.Printf("Server URL:    %s\n", valueOrNotSet(cfg.Notifications.Gotify.URL))
+		fmt.Printf("Token:         %s\n", maskToken(cfg.Notifications.Gotify.Token))
+	},
+}
+
+// setWebhookCmd represents the set-webhook command
+var setWebhookCmd = &cobra.Command{
+	Use:   "set-webhook",
+	Short: "Send notifications as HTTP requests to any URL",
+	Long: `Configure a webhook that receives every notification as an HTTP request.
+Pass an empty value (e.g. --template "") to restore the default.
+
+The body is a Go text/template executed with:
+  .Title, .Body   the formatted notification
+  .Severity       info, recovery, warning or critical
+  .SentAt         when the notification was sent
+  .Changes        the status changes (.Account, .DisplayName, .OldStatus,
+                  .NewStatus, .OldPercentage, .NewPercentage, .ResetTime)
+  .Message        the notification message itself
+The json function encodes any value, e.g. {{json .Title}}. By default the body
+is a JSON object with all of the above.
+
+With --secret, the body is signed with HMAC-SHA256 and sent as
+"sha256=<hex>" in the X-AG-Quota-Signature header (see --signature-header).`,
+	Example: `  ag-quota config set-webhook --url https://alerts.example.com/hooks/quota --secret "$SECRET"
+  ag-quota config set-webhook --header "Authorization: Bearer TOKEN" --template-file payload.tmpl`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		webhook := cfg.Notifications.Webhook
+		updated := false
+		if cmd.Flags().Changed("url") {
+			if webhookURL != "" {
+				if err := validateHTTPURL(webhookURL); err != nil {
+					ui.DisplayError("Invalid webhook URL", err)
+					os.Exit(1)
+				}
+			}
+			webhook.URL = webhookURL
+			updated = true
+		}
+		if cmd.Flags().Changed("method") {
+			method := strings.ToUpper(webhookMethod)
+			if method != "" && method != http.MethodPost && method != http.MethodPut && method != http.MethodPatch {
+				ui.DisplayError("Invalid method", fmt.Errorf("method must be POST, PUT or PATCH"))
+				os.Exit(1)
+			}
+			webhook.Method = method
+			updated = true
+		}
+		if cmd.Flags().Changed("header") {
+			headers, err := parseWebhookHeaders(webhookHeaders)
+			if err != nil {
+				ui.DisplayError("Invalid header", err)
+				os.Exit(1)
+			}
+			webhook.Headers = headers
+			updated = true
+		}
+		if cmd.Flags().Changed("secret") {
+			webhook.Secret = webhookSecret
+			updated = true
+		}
+		if cmd.Flags().Changed("signature-header") {
+			webhook.SignatureHeader = webhookSignatureHeader
+			updated = true
+		}
+		if cmd.Flags().Changed("template") || cmd.Flags().Changed("template-file") {
+			text := webhookTemplate
+			if webhookTemplateFile != "" {
+				data, err := os.ReadFile(webhookTemplateFile)
+				if err != nil {
+					ui.DisplayError("Failed to read template", err)
+					os.Exit(1)
+				}
+				text = string(data)
+			}
+			if _, err := notify.ParseWebhookTemplate(text); err != nil {
+				ui.DisplayError("Invalid template", err)
+				os.Exit(1)
+			}
+			webhook.BodyTemplate = text
+			updated = true
+		}
+
+		if !updated {
+			color.Yellow("No changes provided. Use --url, --method, --header, --secret, --signature-header, --template or --template-file flags.")
+			return
+		}
+
+		enable := webhook.URL != ""
+		err = saveNotifierSettings(func(n *config.NotificationSettings) {
+			n.Webhook = webhook
+		}, enable)
+		if err != nil {
+			ui.DisplayError("Failed to save config", err)
+			os.Exit(1)
+		}
+
+		color.Green("✓ Webhook configuration updated successfully")
+		if enable {
+			color.Cyan("Notifications are now ENABLED")
+		}
+	},
+}
+
+// getWebhookCmd represents the get-webhook command
+var getWebhookCmd = &cobra.Command{
+	Use:   "get-webhook",
+	Short: "View webhook notification settings",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		webhook := cfg.Notifications.Webhook
+		method := webhook.Method
+		if method == "" {
+			method = notify.DefaultWebhookMethod
+		}
+		signatureHeader := webhook.SignatureHeader
+		if signatureHeader == "" {
+			signatureHeader = notify.DefaultWebhookSignatureHeader
+		}
+
+		fmt.Println("Webhook Configuration")
+		fmt.Println("=====================")
+		fmt.Printf("Notifications: %s\n", statusString(cfg.Notifications.Enabled))
+		fmt.Printf("URL:           %s\n", valueOrNotSet(webhook.URL))
+		fmt.Printf("Method:        %s\n", method)
+		fmt.Printf("Secret:        %s\n", maskToken(webhook.Secret))
+		fmt.Printf("Signature:     %s\n", signatureHeader)
+
+		names := slices.Sorted(maps.Keys(webhook.Headers))
+		if len(names) == 0 {
+			fmt.Printf("Headers:       %s\n", color.HiBlackString("none"))
+		} else {
+			fmt.Println("Headers:")
+			for _, name := range names {
+				fmt.Printf("  %s: %s\n", name, maskToken(webhook.Headers[name]))
+			}
+		}
+
+		if webhook.BodyTemplate == "" {
+			fmt.Printf("Template:      %s\n", valueOrDefault(""))
+		} else {
+			fmt.Println("Template:")
+			fmt.Println(webhook.BodyTemplate)
+		}
+	},
+}
+
 // testNotifyCmd represents the test-notify command
 var testNotifyCmd = &cobra.Command{
-	Use:   "test-notify",
+	Use:   "test-notify [notifier...]",
 	Short: "Send a test notification with dummy data to verify formatting",
+	Long: `Send a test notification with dummy data to every configured notifier, or
+only to the named ones (telegram, slack, discord, email, webhook, ntfy, gotify).`,
+	Example: `  ag-quota config test-notify
+  ag-quota config test-notify ntfy gotify`,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if notifRegistry == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return notifRegistry.List(), cobra.ShellCompDirectiveNoFileComp
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if notifRegistry == nil || len(notifRegistry.List()) == 0 {
 			color.Yellow("No notification providers are registered or enabled.")
-			fmt.Println("Use 'ag-quota config set-telegram' to configure Telegram.")
+			fmt.Println("Configure one with 'ag-quota config set-telegram', 'set-slack', 'set-discord', 'set-email', 'set-ntfy', 'set-gotify' or 'set-webhook'.")
 			return
 		}
 
-		fmt.Println("Sending test notification (dummy data)...")
+		// Target only the named notifiers
+		registry := notifRegistry
+		if len(args) > 0 {
+			registry = notify.NewRegistry()
+			for _, name := range args {
+				n, ok := notifRegistry.Get(strings.ToLower(name))
+				if !ok {
+					ui.DisplayError("Unknown notifier", fmt.Errorf("%q is not configured (configured: %s)", name, strings.Join(notifRegistry.List(), ", ")))
+					os.Exit(1)
+				}
+				registry.Register(n)
+			}
+		}
+
+		fmt.Printf("Sending test notification (dummy data) to %s...\n", strings.Join(registry.List(), ", "))
 
 		// Create dummy changes for 2 accounts to show off the new format
 		dummyChanges := []notify.StatusChange{
@@ -179,7 +792,7 @@ var testNotifyCmd = &cobra.Command{
 		msg := msgFormatter.FormatChanges(dummyChanges)
 		msg.Title = "Test notification (dummy data) 🚀"
 
-		errs := notifRegistry.NotifyAll(cmd.Context(), msg)
+		errs := registry.NotifyAll(cmd.Context(), msg)
 		if len(errs) > 0 {
 			for _, err := range errs {
 				ui.DisplayError("Failed to send notification", err)
@@ -188,10 +801,280 @@ var testNotifyCmd = &cobra.Command{
 		}
 
 		color.Green("✓ Test notification with dummy data sent successfully!")
-		fmt.Println("Check your Telegram to see the new grouping format.")
+		fmt.Printf("Check %s to see the new grouping format.\n", strings.Join(registry.List(), ", "))
+	},
+}
+
+// setAPICmd represents the set-api command
+var setAPICmd = &cobra.Command{
+	Use:   "set-api",
+	Short: "Configure how the Cloud Code API is reached",
+	Long: `Override the Cloud Code API endpoint, proxy, timeouts and user agent.
+Pass an empty value (e.g. --endpoint "") to restore the default.
+
+The endpoint can also be overridden per run with --api-endpoint or the
+AG_QUOTA_API_ENDPOINT environment variable.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		updated := false
+		if cmd.Flags().Changed("endpoint") {
+			if apiEndpointSetting != "" {
+				if err := validateHTTPURL(apiEndpointSetting); err != nil {
+					ui.DisplayError("Invalid endpoint", err)
+					os.Exit(1)
+				}
+			}
+			cfg.API.Endpoint = apiEndpointSetting
+			updated = true
+		}
+		if cmd.Flags().Changed("proxy") {
+			if apiProxySetting != "" {
+				if err := validateHTTPURL(apiProxySetting); err != nil {
+					ui.DisplayError("Invalid proxy", err)
+					os.Exit(1)
+				}
+			}
+			cfg.API.Proxy = apiProxySetting
+			updated = true
+		}
+		if cmd.Flags().Changed("timeout") {
+			if apiTimeoutSetting < 0 {
+				ui.DisplayError("Invalid timeout", fmt.Errorf("timeout must not be negative"))
+				os.Exit(1)
+			}
+			cfg.API.TimeoutSeconds = apiTimeoutSetting
+			updated = true
+		}
+		if cmd.Flags().Changed("user-agent") {
+			cfg.API.UserAgent = apiUserAgentSetting
+			updated = true
+		}
+		if cmd.Flags().Changed("onboarding-timeout") {
+			if apiOnboardingTimeout < 0 {
+				ui.DisplayError("Invalid onboarding timeout", fmt.Errorf("onboarding timeout must not be negative"))
+				os.Exit(1)
+			}
+			cfg.API.OnboardingTimeoutSeconds = apiOnboardingTimeout
+			updated = true
+		}
+
+		if !updated {
+			color.Yellow("No changes provided. Use --endpoint, --proxy, --timeout, --user-agent or --onboarding-timeout flags.")
+			return
+		}
+
+		err = config.UpdateConfig(func(latest *config.Config) error {
+			latest.API = cfg.API
+			return nil
+		})
+		if err != nil {
+			ui.DisplayError("Failed to save config", err)
+			os.Exit(1)
+		}
+
+		color.Green("✓ API configuration updated successfully")
+	},
+}
+
+// getAPICmd represents the get-api command
+var getAPICmd = &cobra.Command{
+	Use:   "get-api",
+	Short: "View Cloud Code API settings",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		timeout := "default"
+		if cfg.API.TimeoutSeconds > 0 {
+			timeout = fmt.Sprintf("%ds", cfg.API.TimeoutSeconds)
+		}
+
+		onboardingTimeout := "default"
+		if cfg.API.OnboardingTimeoutSeconds > 0 {
+			onboardingTimeout = fmt.Sprintf("%ds", cfg.API.OnboardingTimeoutSeconds)
+		}
+
+		fmt.Println("API Configuration")
+		fmt.Println("=================")
+		fmt.Printf("Endpoint:   %s\n", valueOrDefault(cfg.API.Endpoint))
+		fmt.Printf("Proxy:      %s\n", valueOrDefault(cfg.API.Proxy))
+		fmt.Printf("Timeout:    %s\n", timeout)
+		fmt.Printf("User Agent: %s\n", valueOrDefault(cfg.API.UserAgent))
+		fmt.Printf("Onboarding: %s\n", onboardingTimeout)
+		if env := os.Getenv(envAPIEndpoint); env != "" {
+			color.Yellow("%s is set and overrides the endpoint: %s", envAPIEndpoint, env)
+		}
 	},
 }
 
+// setVaultCmd represents the set-vault command
+var setVaultCmd = &cobra.Command{
+	Use:   "set-vault",
+	Short: "Choose how account tokens are stored at rest",
+	Long: `Choose the backend that stores account tokens and move existing tokens into it.
+
+Backends:
+  plaintext  JSON files readable only by you (default)
+  encrypted  AES-256-GCM encrypted files, unlocked by a passphrase or key file
+  keyring    the Secret Service keyring (GNOME Keyring, KWallet) via secret-tool
+
+The encrypted backend reads the passphrase from AG_QUOTA_PASSPHRASE, a key file
+(--key-file or AG_QUOTA_KEY_FILE), or asks for it on the terminal.`,
+	Example: `  ag-quota config set-vault --backend encrypted
+  ag-quota config set-vault --backend encrypted --key-file ~/.config/ag-quota/vault.key --generate-key
+  ag-quota config set-vault --change-passphrase
+  ag-quota config set-vault --backend plaintext`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		settings := cfg.Vault
+		if cmd.Flags().Changed("backend") {
+			settings.Backend = vaultBackend
+		}
+		if cmd.Flags().Changed("key-file") {
+			settings.KeyFile = vaultKeyFile
+		}
+
+		if vaultChangePassphrase {
+			if settings.Backend != auth.VaultEncrypted || settings.KeyFile != "" {
+				ui.DisplayError("Invalid flags", fmt.Errorf("--change-passphrase needs the encrypted backend without a key file"))
+				os.Exit(1)
+			}
+			// The environment takes precedence over the new passphrase
+			if os.Getenv(auth.EnvVaultPassphrase) != "" || os.Getenv(auth.EnvVaultKeyFile) != "" {
+				ui.DisplayError("Invalid environment", fmt.Errorf("unset %s and %s to change the passphrase", auth.EnvVaultPassphrase, auth.EnvVaultKeyFile))
+				os.Exit(1)
+			}
+		}
+
+		if settings == cfg.Vault && !vaultGenerateKey && !vaultChangePassphrase {
+			color.Yellow("No changes provided. Use --backend, --key-file, --generate-key or --change-passphrase flags.")
+			return
+		}
+
+		// The tokens are read with the current passphrase, ask for it before the new one
+		if vaultChangePassphrase && cfg.Vault.Backend == auth.VaultEncrypted && cfg.Vault.KeyFile == "" {
+			current, err := readPassphrase("🔐 Current vault passphrase: ")
+			if err != nil {
+				ui.DisplayError("Failed to read passphrase", err)
+				os.Exit(1)
+			}
+			auth.UnlockVault(current)
+		}
+
+		if vaultGenerateKey {
+			if settings.KeyFile == "" {
+				ui.DisplayError("Invalid flags", fmt.Errorf("--generate-key needs --key-file"))
+				os.Exit(1)
+			}
+			if _, err := os.Stat(settings.KeyFile); err == nil {
+				ui.DisplayError("Refusing to overwrite key file", fmt.Errorf("%s already exists", settings.KeyFile))
+				os.Exit(1)
+			}
+			if err := auth.GenerateVaultKeyFile(settings.KeyFile); err != nil {
+				ui.DisplayError("Failed to generate key file", err)
+				os.Exit(1)
+			}
+			color.Green("✓ Generated key file %s, keep a backup of it", settings.KeyFile)
+		}
+
+		// A new passphrase vault asks for the passphrase twice before anything is encrypted
+		newPassphrase := ""
+		if settings.Backend == auth.VaultEncrypted && settings.KeyFile == "" &&
+			os.Getenv(auth.EnvVaultPassphrase) == "" && os.Getenv(auth.EnvVaultKeyFile) == "" {
+			newPassphrase, err = readNewPassphrase()
+			if err != nil {
+				ui.DisplayError("Failed to read passphrase", err)
+				os.Exit(1)
+			}
+		}
+
+		count, err := auth.ReconfigureVault(settings, newPassphrase)
+		if err != nil {
+			ui.DisplayError("Failed to update vault", err)
+			os.Exit(1)
+		}
+
+		color.Green("✓ Vault backend set to %s (%d account(s) re-saved)", vaultBackendName(settings.Backend), count)
+	},
+}
+
+// getVaultCmd represents the get-vault command
+var getVaultCmd = &cobra.Command{
+	Use:   "get-vault",
+	Short: "View how account tokens are stored",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			ui.DisplayError("Failed to load config", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Token Vault")
+		fmt.Println("===========")
+		fmt.Printf("Backend:  %s\n", vaultBackendName(cfg.Vault.Backend))
+		fmt.Printf("Key File: %s\n", valueOrNotSet(cfg.Vault.KeyFile))
+		if env := os.Getenv(auth.EnvVaultPassphrase); env != "" {
+			color.Yellow("%s is set and unlocks the encrypted vault", auth.EnvVaultPassphrase)
+		}
+		if env := os.Getenv(auth.EnvVaultKeyFile); env != "" {
+			color.Yellow("%s is set and overrides the key file: %s", auth.EnvVaultKeyFile, env)
+		}
+	},
+}
+
+func readNewPassphrase() (string, error) {
+	first, err := readPassphrase("🔐 New vault passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if first == "" {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+
+	second, err := readPassphrase("🔐 Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if first != second {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return first, nil
+}
+
+func vaultBackendName(backend string) string {
+	if backend == "" {
+		return auth.VaultPlaintext
+	}
+	return backend
+}
+
+func valueOrNotSet(value string) string {
+	if value == "" {
+		return color.HiBlackString("not set")
+	}
+	return value
+}
+
+func valueOrDefault(value string) string {
+	if value == "" {
+		return color.HiBlackString("default")
+	}
+	return value
+}
+
 func statusString(enabled bool) string {
 	if enabled {
 		return color.GreenString("ENABLED")
@@ -199,6 +1082,53 @@ func statusString(enabled bool) string {
 	return color.RedString("DISABLED")
 }
 
+// emailOptions converts the stored email settings into notifier options
+func emailOptions(email config.EmailSettings) notify.EmailOptions {
+	return notify.EmailOptions{
+		Host:            email.Host,
+		Port:            email.Port,
+		Security:        email.Security,
+		Username:        email.Username,
+		Password:        email.Password,
+		From:            email.From,
+		To:              email.To,
+		SubjectTemplate: email.SubjectTemplate,
+	}
+}
+
+// parseWebhookHeaders parses "Name: value" pairs; empty entries are ignored so
+// that --header "" clears the headers
+func parseWebhookHeaders(values []string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		name, val, found := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("%q is not a \"Name: value\" header", value)
+		}
+		headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(val)
+	}
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	return headers, nil
+}
+
+// saveNotifierSettings writes back the settings of one notifier. The config is
+// re-read under its lock and apply copies only that notifier's settings into it,
+// so settings another process changed meanwhile are kept. Notifications are
+// enabled when enable is set, but never disabled here.
+func saveNotifierSettings(apply func(*config.NotificationSettings), enable bool) error {
+	return config.UpdateConfig(func(latest *config.Config) error {
+		apply(&latest.Notifications)
+		latest.Notifications.Enabled = latest.Notifications.Enabled || enable
+		return nil
+	})
+}
+
 func maskToken(token string) string {
 	if token == "" {
 		return color.HiBlackString("not set")
@@ -213,9 +1143,72 @@ func init() {
 	// Add subcommands to config
 	configCmd.AddCommand(setTelegramCmd)
 	configCmd.AddCommand(getTelegramCmd)
+	configCmd.AddCommand(setSlackCmd)
+	configCmd.AddCommand(getSlackCmd)
+	configCmd.AddCommand(setDiscordCmd)
+	configCmd.AddCommand(getDiscordCmd)
+	configCmd.AddCommand(setEmailCmd)
+	configCmd.AddCommand(getEmailCmd)
+	configCmd.AddCommand(setNtfyCmd)
+	configCmd.AddCommand(getNtfyCmd)
+	configCmd.AddCommand(setGotifyCmd)
+	configCmd.AddCommand(getGotifyCmd)
+	configCmd.AddCommand(setWebhookCmd)
+	configCmd.AddCommand(getWebhookCmd)
 	configCmd.AddCommand(testNotifyCmd)
+	configCmd.AddCommand(setAPICmd)
+	configCmd.AddCommand(getAPICmd)
+	configCmd.AddCommand(setVaultCmd)
+	configCmd.AddCommand(getVaultCmd)
 
 	// Add flags to set-telegram
 	setTelegramCmd.Flags().StringVar(&telegramToken, "token", "", "Telegram bot token")
 	setTelegramCmd.Flags().StringVar(&telegramChatID, "chat-id", "", "Telegram chat ID")
+
+	// Add flags to set-slack
+	setSlackCmd.Flags().StringVar(&slackWebhookURL, "webhook-url", "", "Slack incoming webhook URL")
+
+	// Add flags to set-discord
+	setDiscordCmd.Flags().StringVar(&discordWebhookURL, "webhook-url", "", "Discord channel webhook URL")
+
+	// Add flags to set-email
+	setEmailCmd.Flags().StringVar(&emailHost, "host", "", "SMTP server host name")
+	setEmailCmd.Flags().IntVar(&emailPort, "port", 0, "SMTP server port (0 = default for the security mode)")
+	setEmailCmd.Flags().StringVar(&emailSecurity, "security", "", "Connection security: starttls (default), tls or none")
+	setEmailCmd.Flags().StringVar(&emailUsername, "username", "", "SMTP user name; leave empty to send without authentication")
+	setEmailCmd.Flags().StringVar(&emailPassword, "password", "", "SMTP password")
+	setEmailCmd.Flags().StringVar(&emailFrom, "from", "", `Sender address, e.g. "ag-quota <quota@example.com>"`)
+	setEmailCmd.Flags().StringSliceVar(&emailTo, "to", nil, "Recipient address; repeat or separate with commas, replaces the stored recipients")
+	setEmailCmd.Flags().StringVar(&emailSubject, "subject", "", "Go template for the subject line")
+
+	// Add flags to set-ntfy
+	setNtfyCmd.Flags().StringVar(&ntfyTopicURL, "topic-url", "", "ntfy topic URL, e.g. https://ntfy.sh/my-topic")
+	setNtfyCmd.Flags().StringVar(&ntfyToken, "token", "", "ntfy access token for protected topics")
+
+	// Add flags to set-gotify
+	setGotifyCmd.Flags().StringVar(&gotifyURL, "url", "", "Gotify server URL")
+	setGotifyCmd.Flags().StringVar(&gotifyToken, "token", "", "Gotify application token")
+
+	// Add flags to set-webhook
+	setWebhookCmd.Flags().StringVar(&webhookURL, "url", "", "URL that receives notifications")
+	setWebhookCmd.Flags().StringVar(&webhookMethod, "method", "", "HTTP method: POST (default), PUT or PATCH")
+	setWebhookCmd.Flags().StringArrayVar(&webhookHeaders, "header", nil, `Header sent with each request as "Name: value"; repeat for more, replaces the stored headers`)
+	setWebhookCmd.Flags().StringVar(&webhookSecret, "secret", "", "Secret used to sign request bodies with HMAC-SHA256")
+	setWebhookCmd.Flags().StringVar(&webhookSignatureHeader, "signature-header", "", "Header that carries the signature (default X-AG-Quota-Signature)")
+	setWebhookCmd.Flags().StringVar(&webhookTemplate, "template", "", "Go template for the request body")
+	setWebhookCmd.Flags().StringVar(&webhookTemplateFile, "template-file", "", "File containing the Go template for the request body")
+	setWebhookCmd.MarkFlagsMutuallyExclusive("template", "template-file")
+
+	// Add flags to set-api
+	setAPICmd.Flags().StringVar(&apiEndpointSetting, "endpoint", "", "Cloud Code API endpoint URL")
+	setAPICmd.Flags().StringVar(&apiProxySetting, "proxy", "", "HTTP(S) proxy URL for API requests")
+	setAPICmd.Flags().IntVar(&apiTimeoutSetting, "timeout", 0, "HTTP timeout in seconds (0 = default)")
+	setAPICmd.Flags().StringVar(&apiUserAgentSetting, "user-agent", "", "User-Agent header for API requests")
+	setAPICmd.Flags().IntVar(&apiOnboardingTimeout, "onboarding-timeout", 0, "Seconds to wait for onboarding per run (0 = default)")
+
+	// Add flags to set-vault
+	setVaultCmd.Flags().StringVar(&vaultBackend, "backend", "", "Token storage backend: plaintext, encrypted or keyring")
+	setVaultCmd.Flags().StringVar(&vaultKeyFile, "key-file", "", "Key file that unlocks the encrypted backend instead of a passphrase")
+	setVaultCmd.Flags().BoolVar(&vaultGenerateKey, "generate-key", false, "Create a new random key file at --key-file")
+	setVaultCmd.Flags().BoolVar(&vaultChangePassphrase, "change-passphrase", false, "Re-encrypt the tokens with a new passphrase")
 }
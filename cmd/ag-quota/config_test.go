@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+	"github.com/gundamkid/anti-gravity-quota/internal/fakeserver"
+	"github.com/gundamkid/anti-gravity-quota/internal/notify"
+)
+
+func TestConfigSetWebhook(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var received struct {
+		Severity string                `json:"severity"`
+		Changes  []notify.StatusChange `json:"changes"`
+	}
+	var header http.Header
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("webhook body is not JSON: %v\n%s", err, body)
+		}
+	}))
+	defer srv.Close()
+
+	_, err := execute(t, "config", "set-webhook",
+		"--url", srv.URL,
+		"--method", "put",
+		"--header", "authorization: Bearer abc",
+		"--secret", "s3cret",
+	)
+	if err != nil {
+		t.Fatalf("set-webhook failed: %v", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	webhook := cfg.Notifications.Webhook
+	if !cfg.Notifications.Enabled || webhook.URL != srv.URL || webhook.Method != http.MethodPut || webhook.Secret != "s3cret" {
+		t.Errorf("unexpected settings: enabled=%v %+v", cfg.Notifications.Enabled, webhook)
+	}
+	if webhook.Headers["Authorization"] != "Bearer abc" {
+		t.Errorf("expected canonical Authorization header, got %v", webhook.Headers)
+	}
+
+	t.Cleanup(func() { notifRegistry, stateTracker, msgFormatter = nil, nil, nil })
+	initNotifications()
+	if _, err := execute(t, "config", "test-notify"); err != nil {
+		t.Fatalf("test-notify failed: %v", err)
+	}
+
+	if received.Severity != "critical" || len(received.Changes) == 0 {
+		t.Errorf("expected critical payload with changes, got %+v", received)
+	}
+	if want := "sha256=" + notify.SignWebhookBody("s3cret", body); header.Get(notify.DefaultWebhookSignatureHeader) != want {
+		t.Errorf("expected signature %s, got %s", want, header.Get(notify.DefaultWebhookSignatureHeader))
+	}
+	if header.Get("Authorization") != "Bearer abc" {
+		t.Errorf("configured header not sent: %v", header)
+	}
+}
+
+func TestConfigSetSlack(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var payload struct {
+		Text        string `json:"text"`
+		Attachments []struct {
+			Color string `json:"color"`
+		} `json:"attachments"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid Slack payload: %v", err)
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	if _, err := execute(t, "config", "set-slack", "--webhook-url", srv.URL+"/services/T/B/X"); err != nil {
+		t.Fatalf("set-slack failed: %v", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !cfg.Notifications.Enabled || cfg.Notifications.Slack.WebhookURL != srv.URL+"/services/T/B/X" {
+		t.Errorf("unexpected settings: %+v", cfg.Notifications)
+	}
+
+	t.Cleanup(func() { notifRegistry, stateTracker, msgFormatter = nil, nil, nil })
+	initNotifications()
+	if _, ok := notifRegistry.Get("slack"); !ok {
+		t.Fatalf("slack notifier not registered: %v", notifRegistry.List())
+	}
+	if _, err := execute(t, "config", "test-notify"); err != nil {
+		t.Fatalf("test-notify failed: %v", err)
+	}
+
+	// The dummy data covers two accounts
+	if !strings.Contains(payload.Text, "Test notification") || len(payload.Attachments) != 2 {
+		t.Errorf("unexpected Slack payload: %+v", payload)
+	}
+}
+
+func TestConfigSetDiscord(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var posted int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"id": "1", "name": "quota"}`))
+		case http.MethodPost:
+			posted++
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	if _, err := execute(t, "config", "set-discord", "--webhook-url", srv.URL+"/api/webhooks/1/x"); err != nil {
+		t.Fatalf("set-discord failed: %v", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !cfg.Notifications.Enabled || cfg.Notifications.Discord.WebhookURL != srv.URL+"/api/webhooks/1/x" {
+		t.Errorf("unexpected settings: %+v", cfg.Notifications)
+	}
+
+	t.Cleanup(func() { notifRegistry, stateTracker, msgFormatter = nil, nil, nil })
+	initNotifications()
+	if _, err := execute(t, "config", "test-notify"); err != nil {
+		t.Fatalf("test-notify failed: %v", err)
+	}
+	if posted != 1 {
+		t.Errorf("expected 1 Discord message, got %d", posted)
+	}
+}
+
+func TestConfigSetEmail(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	srv := fakeserver.NewSMTP(fakeserver.SMTPOptions{Username: "bot", Password: "pw"})
+	defer srv.Close()
+
+	_, err := execute(t, "config", "set-email",
+		"--host", srv.Host(),
+		"--port", strconv.Itoa(srv.Port()),
+		"--security", "none",
+		"--username", "bot",
+		"--password", "pw",
+		"--from", "ag-quota <quota@example.com>",
+		"--to", "ops@example.com,lead@example.com",
+		"--subject", "{{upper .Severity}} {{.Accounts}} accounts",
+	)
+	if err != nil {
+		t.Fatalf("set-email failed: %v", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	email := cfg.Notifications.Email
+	if !cfg.Notifications.Enabled || email.Host != srv.Host() || email.Security != "none" || len(email.To) != 2 {
+		t.Errorf("unexpected settings: enabled=%v %+v", cfg.Notifications.Enabled, email)
+	}
+
+	t.Cleanup(func() { notifRegistry, stateTracker, msgFormatter = nil, nil, nil })
+	initNotifications()
+	if _, err := execute(t, "config", "test-notify"); err != nil {
+		t.Fatalf("test-notify failed: %v", err)
+	}
+
+	messages := srv.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 email, got %d", len(messages))
+	}
+	if got := messages[0]; got.Username != "bot" || len(got.To) != 2 || !strings.Contains(got.Data, "Subject: CRITICAL 2 accounts") {
+		t.Errorf("unexpected email: %+v", got)
+	}
+}
+
+func TestConfigTestNotifyTargets(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	calls := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/ntfy/" && r.Header.Get("Authorization") == "Bearer tk_1":
+			calls["ntfy"]++
+		case r.URL.Path == "/gotify/message" && r.Header.Get("X-Gotify-Key") == "app":
+			calls["gotify"]++
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	if _, err := execute(t, "config", "set-ntfy", "--topic-url", srv.URL+"/ntfy/quota", "--token", "tk_1"); err != nil {
+		t.Fatalf("set-ntfy failed: %v", err)
+	}
+	if _, err := execute(t, "config", "set-gotify", "--url", srv.URL+"/gotify", "--token", "app"); err != nil {
+		t.Fatalf("set-gotify failed: %v", err)
+	}
+
+	t.Cleanup(func() { notifRegistry, stateTracker, msgFormatter = nil, nil, nil })
+	initNotifications()
+	if names := notifRegistry.List(); strings.Join(names, ",") != "gotify,ntfy" {
+		t.Fatalf("expected gotify and ntfy to be registered, got %v", names)
+	}
+
+	if _, err := execute(t, "config", "test-notify", "ntfy"); err != nil {
+		t.Fatalf("test-notify ntfy failed: %v", err)
+	}
+	if calls["ntfy"] != 1 || calls["gotify"] != 0 {
+		t.Errorf("expected only ntfy to be notified, got %v", calls)
+	}
+
+	if _, err := execute(t, "config", "test-notify"); err != nil {
+		t.Fatalf("test-notify failed: %v", err)
+	}
+	if calls["ntfy"] != 2 || calls["gotify"] != 1 {
+		t.Errorf("expected both to be notified, got %v", calls)
+	}
+}
+
+func TestParseWebhookHeaders(t *testing.T) {
+	headers, err := parseWebhookHeaders([]string{"x-team: quota ", "Authorization:Bearer a:b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if headers["X-Team"] != "quota" || headers["Authorization"] != "Bearer a:b" {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+
+	if headers, err := parseWebhookHeaders([]string{""}); err != nil || headers != nil {
+		t.Errorf("expected an empty entry to clear headers, got %v, %v", headers, err)
+	}
+
+	for _, bad := range []string{"no-colon", ": value", "Bad Name: value"} {
+		if _, err := parseWebhookHeaders([]string{bad}); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"sort"
@@ -18,6 +21,7 @@ import (
 	"github.com/gundamkid/anti-gravity-quota/internal/ui"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/term"
 )
 
 var (
@@ -25,9 +29,12 @@ var (
 	jsonOutput    bool
 	accountFlag   string
 	allFlag       bool
+	labelFlag     string
 	watchInterval int
 	compactFlag   bool
 	noCompactFlag bool
+	apiEndpoint   string
+	noBrowserFlag bool
 
 	// Notifications
 	notifRegistry *notify.Registry
@@ -66,7 +73,10 @@ var quotaCmd = &cobra.Command{
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Login with Google account",
-	Long:  `Start OAuth2 login flow to authenticate with your Google account.`,
+	Long: `Start OAuth2 login flow to authenticate with your Google account.
+
+Use --no-browser on remote machines (e.g. over SSH): the authorization URL is
+printed, and the redirect URL (or code) is pasted back into the terminal.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		runLogin(cmd, args)
 	},
@@ -86,7 +96,13 @@ var statusCmd = &cobra.Command{
 var logoutCmd = &cobra.Command{
 	Use:   "logout",
 	Short: "Logout and clear stored tokens",
-	Long:  `Remove stored authentication tokens and logout from the current account.`,
+	Long: `Revoke an account's token at Google and remove it locally.
+
+Without flags the default account is logged out; --account logs out another
+account and --all logs out every account. When the default account is logged
+out, another saved account becomes the default.
+
+If revocation fails the local token is kept; pass --local-only to remove it anyway.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		runLogout(cmd, args)
 	},
@@ -144,19 +160,35 @@ func runQuota(ctx context.Context, cmd *cobra.Command, args []string) {
 func fetchAndDisplayQuota(ctx context.Context, triggerNotify bool) {
 	var finalResults []*ui.AccountQuotaResult
 
+	// --label limits --all to the accounts carrying it
+	if labelFlag != "" {
+		allFlag = true
+	}
+
 	// Handle --all flag
 	if allFlag {
 		finalResults = runQuotaForAllAccounts(ctx)
 	} else {
 		// Handle --account flag or default
 		email := accountFlag
-		if email == "" {
+		if email != "" {
+			resolved, err := resolveAccountFlag(email)
+			if err != nil {
+				if jsonOutput {
+					ui.DisplayErrorJSON("account not found", "", err)
+				} else {
+					ui.DisplayError("Account not found", err)
+				}
+				os.Exit(1)
+			}
+			email = resolved
+		} else {
 			token, err := auth.LoadToken()
 			if err != nil {
 				if jsonOutput {
-					fmt.Fprintf(os.Stderr, `{"error": "not logged in"}%s`, "\n")
+					ui.DisplayErrorJSON("not logged in", api.CodeUnauthenticated, err)
 				} else {
-					ui.DisplayNotLoggedIn()
+					displayNoAccount(err)
 				}
 				os.Exit(1)
 			}
@@ -167,9 +199,12 @@ func fetchAndDisplayQuota(ctx context.Context, triggerNotify bool) {
 		if err != nil {
 			if ctx.Err() == nil {
 				if jsonOutput {
-					fmt.Fprintf(os.Stderr, `{"error": "failed to fetch quota", "message": "%s"}%s`, err.Error(), "\n")
+					ui.DisplayErrorJSON("failed to fetch quota", api.ErrorCode(err), err)
 				} else {
 					ui.DisplayError("Failed to fetch quota information", err)
+					if errors.Is(err, api.ErrUnauthenticated) {
+						fmt.Println("Run 'ag-quota login' to re-authenticate")
+					}
 				}
 				os.Exit(1)
 			}
@@ -199,16 +234,9 @@ func fetchAndDisplayQuota(ctx context.Context, triggerNotify bool) {
 
 	// Display results
 	if jsonOutput {
-		if allFlag {
-			if err := ui.DisplayAllAccountsQuotaJSON(finalResults); err != nil {
-				fmt.Fprintf(os.Stderr, "Error displaying JSON: %v\n", err)
-				os.Exit(1)
-			}
-		} else if len(finalResults) > 0 {
-			if err := ui.DisplayQuotaSummaryJSON(finalResults[0].QuotaSummary); err != nil {
-				fmt.Fprintf(os.Stderr, "Error displaying JSON: %v\n", err)
-				os.Exit(1)
-			}
+		if err := ui.DisplayQuotaReportJSON(finalResults); err != nil {
+			fmt.Fprintf(os.Stderr, "Error displaying JSON: %v\n", err)
+			os.Exit(1)
 		}
 	} else {
 		if allFlag {
@@ -241,7 +269,16 @@ func fetchQuotaForAccountResult(ctx context.Context, email string) (*ui.AccountQ
 		fmt.Printf("Fetching quota for %s... ", email)
 	}
 
-	client := api.NewClient()
+	var client *api.Client
+	var err error
+	if !jsonOutput && !allFlag {
+		client, err = newAPIClient(api.WithOnboardingProgress(printOnboardingProgress))
+	} else {
+		client, err = newAPIClient()
+	}
+	if err != nil {
+		return nil, err
+	}
 	quotaInfo, err := client.GetQuotaInfoForAccount(ctx, email)
 	if err != nil {
 		return nil, err
@@ -262,7 +299,7 @@ func runQuotaForAllAccounts(ctx context.Context) []*ui.AccountQuotaResult {
 	mgr, err := auth.NewAccountManager()
 	if err != nil {
 		if jsonOutput {
-			fmt.Fprintf(os.Stderr, `{"error": "failed to initialize account manager", "message": "%s"}%s`, err.Error(), "\n")
+			ui.DisplayErrorJSON("failed to initialize account manager", "", err)
 		} else {
 			ui.DisplayError("Failed to initialize account manager", err)
 		}
@@ -272,7 +309,7 @@ func runQuotaForAllAccounts(ctx context.Context) []*ui.AccountQuotaResult {
 	accounts, err := mgr.ListAccounts()
 	if err != nil {
 		if jsonOutput {
-			fmt.Fprintf(os.Stderr, `{"error": "failed to list accounts", "message": "%s"}%s`, err.Error(), "\n")
+			ui.DisplayErrorJSON("failed to list accounts", "", err)
 		} else {
 			ui.DisplayError("Failed to list accounts", err)
 		}
@@ -281,19 +318,41 @@ func runQuotaForAllAccounts(ctx context.Context) []*ui.AccountQuotaResult {
 
 	if len(accounts) == 0 {
 		if jsonOutput {
-			fmt.Fprintf(os.Stderr, `{"error": "no accounts found"}%s`, "\n")
+			ui.DisplayErrorJSON("no accounts found", "", nil)
 		} else {
 			color.Yellow("No accounts found. Please run 'ag-quota login' first.")
 		}
 		os.Exit(1)
 	}
 
+	if labelFlag != "" {
+		accounts = auth.FilterByLabel(accounts, labelFlag)
+		if len(accounts) == 0 {
+			if jsonOutput {
+				ui.DisplayErrorJSON("no accounts with label "+labelFlag, "", nil)
+			} else {
+				color.Yellow("No accounts with label %q. Add one with 'ag-quota accounts set <email> --add-label %s'.", labelFlag, labelFlag)
+			}
+			os.Exit(1)
+		}
+	}
+
 	if !jsonOutput && !allFlag { // Redundant but for clarity
 		fmt.Println()
 		fmt.Printf("Fetching quota for %d account(s)...\n", len(accounts))
 		fmt.Println()
 	}
 
+	apiOpts, err := apiClientOptions()
+	if err != nil {
+		if jsonOutput {
+			ui.DisplayErrorJSON("invalid API configuration", "", err)
+		} else {
+			ui.DisplayError("Invalid API configuration", err)
+		}
+		os.Exit(1)
+	}
+
 	// Fetch quota for each account in parallel using errgroup
 	quotaResults := make([]*ui.AccountQuotaResult, len(accounts))
 	g, gCtx := errgroup.WithContext(ctx)
@@ -303,7 +362,7 @@ func runQuotaForAllAccounts(ctx context.Context) []*ui.AccountQuotaResult {
 		idx, email := i, acc.Email
 		g.Go(func() error {
 			// Create a new client per goroutine to avoid race conditions
-			client := api.NewClient()
+			client := api.NewClient(apiOpts...)
 			quotaInfo, apiErr := client.GetQuotaInfoForAccount(gCtx, email)
 
 			mu.Lock()
@@ -313,8 +372,9 @@ func runQuotaForAllAccounts(ctx context.Context) []*ui.AccountQuotaResult {
 				// Record individual account error instead of returning it to errgroup
 				// This prevents one bad account from stopping the entire --all fetch.
 				quotaResults[idx] = &ui.AccountQuotaResult{
-					Email: email,
-					Error: apiErr.Error(),
+					Email:     email,
+					Error:     apiErr.Error(),
+					ErrorCode: api.ErrorCode(apiErr),
 				}
 				return nil
 			}
@@ -336,7 +396,7 @@ func runQuotaForAllAccounts(ctx context.Context) []*ui.AccountQuotaResult {
 	if err != nil {
 		// For other fatal errors that might still propagate
 		if jsonOutput {
-			fmt.Fprintf(os.Stderr, `{"error": "fatal error", "message": "%s"}%s`, err.Error(), "\n")
+			ui.DisplayErrorJSON("fatal error", api.ErrorCode(err), err)
 		} else {
 			ui.DisplayError("fatal error during fetch", err)
 		}
@@ -358,23 +418,151 @@ func runQuotaForAllAccounts(ctx context.Context) []*ui.AccountQuotaResult {
 	return finalResults
 }
 
+// envAPIEndpoint overrides the API endpoint when --api-endpoint is not set
+const envAPIEndpoint = "AG_QUOTA_API_ENDPOINT"
+
+var (
+	apiOptsOnce sync.Once
+	apiOpts     []api.Option
+	apiOptsErr  error
+)
+
+// apiClientOptions resolves API client options once. The endpoint is taken from
+// --api-endpoint, then AG_QUOTA_API_ENDPOINT, then the config file.
+func apiClientOptions() ([]api.Option, error) {
+	apiOptsOnce.Do(func() {
+		apiOpts, apiOptsErr = buildAPIClientOptions()
+	})
+	return apiOpts, apiOptsErr
+}
+
+func buildAPIClientOptions() ([]api.Option, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []api.Option
+
+	endpoint := apiEndpoint
+	if endpoint == "" {
+		endpoint = os.Getenv(envAPIEndpoint)
+	}
+	if endpoint == "" {
+		endpoint = cfg.API.Endpoint
+	}
+	if endpoint != "" {
+		if err := validateHTTPURL(endpoint); err != nil {
+			return nil, fmt.Errorf("invalid API endpoint: %w", err)
+		}
+		opts = append(opts, api.WithBaseURL(endpoint))
+	}
+
+	if cfg.API.Proxy != "" {
+		if err := validateHTTPURL(cfg.API.Proxy); err != nil {
+			return nil, fmt.Errorf("invalid API proxy: %w", err)
+		}
+		proxyURL, _ := url.Parse(cfg.API.Proxy)
+		transport := &http.Transport{}
+		if defaultTransport, ok := http.DefaultTransport.(*http.Transport); ok {
+			transport = defaultTransport.Clone()
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+		opts = append(opts, api.WithTransport(transport))
+	}
+
+	if cfg.API.TimeoutSeconds > 0 {
+		opts = append(opts, api.WithTimeout(time.Duration(cfg.API.TimeoutSeconds)*time.Second))
+	}
+
+	if cfg.API.UserAgent != "" {
+		opts = append(opts, api.WithUserAgent(cfg.API.UserAgent))
+	}
+
+	if cfg.API.OnboardingTimeoutSeconds > 0 {
+		opts = append(opts, api.WithOnboardingTimeout(time.Duration(cfg.API.OnboardingTimeoutSeconds)*time.Second))
+	}
+
+	return opts, nil
+}
+
+// newAPIClient creates an API client with the resolved options, followed by extra options
+func newAPIClient(extra ...api.Option) (*api.Client, error) {
+	opts, err := apiClientOptions()
+	if err != nil {
+		return nil, err
+	}
+	return api.NewClient(append(append([]api.Option{}, opts...), extra...)...), nil
+}
+
+// printOnboardingProgress shows the progress of an account onboarding
+func printOnboardingProgress(p api.OnboardingProgress) {
+	elapsed := p.Elapsed.Round(time.Second)
+	switch {
+	case p.Done:
+		color.Green("\n✓ Onboarding to %s completed after %s", p.TierID, elapsed)
+	case p.Resumed:
+		color.Yellow("\n⏳ Resuming onboarding to %s (started %s ago), next check in %s", p.TierID, elapsed, p.NextPoll.Round(time.Second))
+	default:
+		color.Yellow("\n⏳ Onboarding to %s in progress (%s elapsed), next check in %s", p.TierID, elapsed, p.NextPoll.Round(time.Second))
+	}
+}
+
+// validateHTTPURL checks that raw is an absolute http(s) URL
+func validateHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute http(s) URL", raw)
+	}
+	return nil
+}
+
 // runLogin handles the login command
 func runLogin(cmd *cobra.Command, args []string) {
 	fmt.Println("Starting authentication flow...")
 	fmt.Println()
 
-	if err := auth.Login(); err != nil {
+	login := auth.Login
+	if noBrowserFlag {
+		login = func() error { return auth.LoginNoBrowser(os.Stdin, os.Stdout) }
+	}
+
+	if err := login(); err != nil {
 		color.Red("Error: %v", err)
 		os.Exit(1)
 	}
+
+	// Onboard new accounts right away so the first quota check is fast
+	token, err := auth.LoadToken()
+	if err != nil {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client, err := newAPIClient(api.WithOnboardingProgress(printOnboardingProgress))
+	if err != nil {
+		color.Yellow("⚠ Could not resolve project: %v", err)
+		return
+	}
+	if _, _, err := client.RefreshProjectMetadata(ctx, token.Email); err != nil {
+		if errors.Is(err, api.ErrOnboardingPending) {
+			color.Yellow("⚠ Onboarding is still in progress. Run 'ag-quota quota' later to resume it.")
+			return
+		}
+		color.Yellow("⚠ Could not resolve project: %v", err)
+	}
 }
 
 // runStatus handles the status command
 func runStatus(cmd *cobra.Command, args []string) {
 	token, err := auth.LoadToken()
 	if err != nil {
-		color.Red("Not logged in")
-		fmt.Println("Run 'ag-quota login' to authenticate")
+		displayNoAccount(err)
 		os.Exit(1)
 	}
 
@@ -385,7 +573,7 @@ func runStatus(cmd *cobra.Command, args []string) {
 	if token.Email != "" {
 		tier := token.TierName
 		if tier == "" {
-			tier = "Free 📦 (Run 'ag-quota quota' to update)"
+			tier = "Unknown (Run 'ag-quota quota' to update)"
 		}
 		color.Green("✓ Logged in as: %s [%s]", token.Email, tier)
 	} else {
@@ -405,6 +593,21 @@ func runStatus(cmd *cobra.Command, args []string) {
 		color.Green("✓ Token valid for: %s", timeUntilExpiry.Round(time.Minute))
 	}
 
+	// Show active API endpoint
+	endpoint := api.ActiveEndpoint()
+	if client, err := newAPIClient(); err == nil {
+		endpoint = client.Endpoint()
+	}
+	fmt.Println()
+	switch {
+	case api.IsBackupEndpoint(endpoint):
+		color.Yellow("⚠ API endpoint: %s (failover)", endpoint)
+	case endpoint != api.BaseURL:
+		fmt.Printf("API endpoint: %s (custom)\n", endpoint)
+	default:
+		fmt.Printf("API endpoint: %s\n", endpoint)
+	}
+
 	// Show config directory
 	configDir, err := config.GetConfigDir()
 	if err == nil {
@@ -415,20 +618,115 @@ func runStatus(cmd *cobra.Command, args []string) {
 
 // runLogout handles the logout command
 func runLogout(cmd *cobra.Command, args []string) {
-	// Check if logged in first
-	_, err := auth.LoadToken()
+	mgr, err := auth.NewAccountManager()
 	if err != nil {
+		color.Red("Error logging out: %v", err)
+		os.Exit(1)
+	}
+
+	var emails []string
+	switch {
+	case allFlag:
+		accounts, err := mgr.ListAccounts()
+		if err != nil {
+			color.Red("Error logging out: %v", err)
+			os.Exit(1)
+		}
+		if labelFlag != "" {
+			accounts = auth.FilterByLabel(accounts, labelFlag)
+			if len(accounts) == 0 {
+				color.Yellow("No accounts with label %q", labelFlag)
+				return
+			}
+		}
+		for _, acc := range accounts {
+			emails = append(emails, acc.Email)
+		}
+	case accountFlag != "":
+		email, err := mgr.ResolveAccount(accountFlag)
+		if err != nil {
+			color.Yellow("Account %s not found", accountFlag)
+			os.Exit(1)
+		}
+		emails = []string{email}
+		mgr.ChooseDefault = chooseDefaultAccount
+	default:
+		email, err := mgr.DefaultAccount()
+		if err != nil {
+			if errors.Is(err, auth.ErrNotLoggedIn) {
+				color.Yellow("Not logged in")
+				return
+			}
+			displayNoAccount(err)
+			os.Exit(1)
+		}
+		emails = []string{email}
+		mgr.ChooseDefault = chooseDefaultAccount
+	}
+
+	if len(emails) == 0 {
 		color.Yellow("Not logged in")
 		return
 	}
 
-	// Delete token
-	if err := auth.DeleteToken(); err != nil {
-		color.Red("Error logging out: %v", err)
+	previousDefault := currentDefaultAccount(mgr)
+	removed, failed := removeAccounts(cmd.Context(), mgr, emails, localOnlyFlag)
+
+	for _, email := range removed {
+		color.Green("✓ Logged out of %s", email)
+	}
+	reportDefaultChange(mgr, previousDefault)
+
+	if failed > 0 {
 		os.Exit(1)
 	}
+}
+
+// checkLabelFlag rejects --label on commands that do not filter accounts by it.
+// The flag is persistent, and silently ignoring it would make a bulk removal
+// act on every account instead of the labeled ones.
+func checkLabelFlag(cmd *cobra.Command) error {
+	if labelFlag == "" {
+		return nil
+	}
 
-	color.Green("✓ Logged out successfully")
+	switch cmd {
+	case rootCmd, quotaCmd, accountsCmd, accountsListCmd, accountsExportCmd, accountsPruneCmd, accountsRevokeAllCmd:
+		return nil
+	case logoutCmd:
+		if allFlag {
+			return nil
+		}
+		return fmt.Errorf("--label needs --all for %s", cmd.CommandPath())
+	}
+	return fmt.Errorf("--label is not supported by %s", cmd.CommandPath())
+}
+
+// resolveAccountFlag returns the email of the account named by --account, which may be an alias
+func resolveAccountFlag(name string) (string, error) {
+	mgr, err := auth.NewAccountManager()
+	if err != nil {
+		return "", err
+	}
+	return mgr.ResolveAccount(name)
+}
+
+// displayNoAccount explains why no account could be used, e.g. when several
+// accounts are saved but none is the default
+func displayNoAccount(err error) {
+	switch {
+	case errors.Is(err, auth.ErrNotLoggedIn):
+		ui.DisplayNotLoggedIn()
+	case errors.Is(err, auth.ErrNoDefaultAccount):
+		color.Yellow("No default account set")
+		fmt.Println()
+		fmt.Println("Choose one of your saved accounts:")
+		color.Cyan("  ag-quota accounts default <email>")
+		fmt.Println("or pass --account <email> to this command.")
+		fmt.Println()
+	default:
+		ui.DisplayError("Failed to load account", err)
+	}
 }
 
 func init() {
@@ -439,30 +737,73 @@ func init() {
 	rootCmd.AddCommand(logoutCmd)
 	rootCmd.AddCommand(configCmd)
 
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return checkLabelFlag(cmd)
+	}
+
 	// Add flags to root asPersistentFlags so they are available to subcommands and when running root directly
 	rootCmd.PersistentFlags().BoolVarP(&jsonOutput, "json", "j", false, "Output in JSON format")
-	rootCmd.PersistentFlags().StringVar(&accountFlag, "account", "", "Check quota for specific account")
+	rootCmd.PersistentFlags().StringVar(&accountFlag, "account", "", "Check quota for specific account (email or alias)")
 	rootCmd.PersistentFlags().BoolVar(&allFlag, "all", false, "Check quota for all accounts")
+	rootCmd.PersistentFlags().StringVar(&labelFlag, "label", "", "Only use accounts with this label (implies --all for quota; also for accounts list, export, prune, revoke-all and logout --all)")
 	rootCmd.PersistentFlags().IntVarP(&watchInterval, "watch", "w", 0, "Watch quota periodically (default 5m)")
 	rootCmd.PersistentFlags().BoolVar(&compactFlag, "compact", false, "Force compact mode display")
 	rootCmd.PersistentFlags().BoolVar(&noCompactFlag, "no-compact", false, "Force full mode display (disable auto-compact)")
+	rootCmd.PersistentFlags().StringVar(&apiEndpoint, "api-endpoint", "", "Override the Cloud Code API endpoint (env: "+envAPIEndpoint+")")
 	rootCmd.PersistentFlags().Lookup("watch").NoOptDefVal = "5"
+
+	logoutCmd.Flags().BoolVar(&localOnlyFlag, "local-only", false, "Remove the local token without revoking it at Google")
+	loginCmd.Flags().BoolVar(&noBrowserFlag, "no-browser", false, "Print the login URL and paste the redirect URL instead of opening a browser")
 }
 
 func main() {
 	// Initialize notifications
 	initNotifications()
 
+	// Ask for the vault passphrase on demand when the encrypted vault is used interactively
+	auth.PassphrasePrompt = promptVaultPassphrase
+
 	// Perform migration if needed (from single-account to multi-account format)
 	if err := auth.MigrateIfNeeded(); err != nil {
 		fmt.Fprintf(os.Stderr, "Migration warning: %v\n", err)
 	}
 
+	// Store every account under its canonical ID
+	if err := auth.MigrateAccountIDsIfNeeded(); err != nil {
+		fmt.Fprintf(os.Stderr, "Account migration warning: %v\n", err)
+	}
+
+	// Move tokens into the configured vault backend
+	if err := auth.MigrateVaultIfNeeded(); err != nil {
+		fmt.Fprintf(os.Stderr, "Vault migration warning: %v\n", err)
+	}
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
+
+// promptVaultPassphrase reads the vault passphrase from the terminal without echoing it
+func promptVaultPassphrase() (string, error) {
+	return readPassphrase("🔐 Vault passphrase: ")
+}
+
+func readPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("stdin is not a terminal, set %s instead", auth.EnvVaultPassphrase)
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
 func initNotifications() {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -484,4 +825,50 @@ func initNotifications() {
 			cfg.Notifications.Telegram.ChatID,
 		))
 	}
+
+	// Register Slack if configured
+	if cfg.Notifications.Slack.WebhookURL != "" {
+		notifRegistry.Register(notify.NewSlackNotifier(cfg.Notifications.Slack.WebhookURL))
+	}
+
+	// Register Discord if configured
+	if cfg.Notifications.Discord.WebhookURL != "" {
+		notifRegistry.Register(notify.NewDiscordNotifier(cfg.Notifications.Discord.WebhookURL))
+	}
+
+	// Register email if configured
+	if cfg.Notifications.Email.Host != "" {
+		en, err := notify.NewEmailNotifier(emailOptions(cfg.Notifications.Email))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Email notifications disabled: %v\n", err)
+		} else if en.IsEnabled() {
+			notifRegistry.Register(en)
+		}
+	}
+
+	// Register ntfy if configured
+	if cfg.Notifications.Ntfy.TopicURL != "" {
+		notifRegistry.Register(notify.NewNtfyNotifier(cfg.Notifications.Ntfy.TopicURL, cfg.Notifications.Ntfy.Token))
+	}
+
+	// Register Gotify if configured
+	if cfg.Notifications.Gotify.URL != "" && cfg.Notifications.Gotify.Token != "" {
+		notifRegistry.Register(notify.NewGotifyNotifier(cfg.Notifications.Gotify.URL, cfg.Notifications.Gotify.Token))
+	}
+
+	// Register the webhook if configured
+	if webhook := cfg.Notifications.Webhook; webhook.URL != "" {
+		wn, err := notify.NewWebhookNotifier(webhook.URL, notify.WebhookOptions{
+			Method:          webhook.Method,
+			Headers:         webhook.Headers,
+			Secret:          webhook.Secret,
+			SignatureHeader: webhook.SignatureHeader,
+			BodyTemplate:    webhook.BodyTemplate,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Webhook notifications disabled: %v\n", err)
+		} else {
+			notifRegistry.Register(wn)
+		}
+	}
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/auth"
+	"github.com/gundamkid/anti-gravity-quota/internal/fakeserver"
+	"github.com/gundamkid/anti-gravity-quota/internal/ui"
+)
+
+// useFakeEndpoints points the OAuth endpoints at the fake server for the duration of the test
+func useFakeEndpoints(t *testing.T, srv *fakeserver.Server) {
+	t.Helper()
+
+	endpoints := auth.Endpoints
+	auth.Endpoints = auth.OAuthEndpoints{AuthURL: srv.AuthURL(), TokenURL: srv.TokenURL(), UserInfoURL: srv.UserInfoURL(), RevokeURL: srv.RevokeURL()}
+	t.Cleanup(func() { auth.Endpoints = endpoints })
+}
+
+// captureStdout runs fn and returns what it wrote to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+
+	original := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = original }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
+// execute runs the root command with args and returns what it wrote to stdout.
+// Flag variables are reset first because they outlive a single Execute call.
+func execute(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	jsonOutput, accountFlag, allFlag, labelFlag = false, "", false, ""
+	localOnlyFlag, yesFlag = false, false
+	pruneUnusedFor, pruneDryRun = "", false
+	bundleOutput, bundleConflict, bundleWithNotifications = "", auth.ConflictSkip, false
+	apiOptsOnce = sync.Once{}
+
+	var err error
+	out := captureStdout(t, func() {
+		rootCmd.SetArgs(args)
+		err = rootCmd.Execute()
+	})
+	return out, err
+}
+
+func TestQuotaAllAccountsOffline(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	useFakeEndpoints(t, srv)
+
+	accounts := []fakeserver.Account{
+		fakeserver.OnboardedAccount("alice@example.com"),
+		fakeserver.OnboardedAccount("bob@example.com"),
+	}
+	for _, acc := range accounts {
+		srv.AddAccount(acc)
+		// Expired access tokens force a refresh through the fake token endpoint
+		err := auth.SaveTokenForAccount(acc.Email, &auth.TokenData{
+			AccessToken:  "expired",
+			RefreshToken: srv.RefreshToken(acc.Email),
+			Expiry:       time.Now().Add(-time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("SaveTokenForAccount failed: %v", err)
+		}
+	}
+
+	out, err := execute(t, "quota", "--all", "--json", "--api-endpoint", srv.URL())
+	if err != nil {
+		t.Errorf("command failed: %v", err)
+	}
+
+	var report ui.QuotaReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, out)
+	}
+
+	if report.SchemaVersion != ui.QuotaReportSchemaVersion {
+		t.Errorf("expected schema version %d, got %d", ui.QuotaReportSchemaVersion, report.SchemaVersion)
+	}
+	if len(report.Accounts) != 2 {
+		t.Fatalf("expected 2 accounts, got %d", len(report.Accounts))
+	}
+	for _, acc := range report.Accounts {
+		if acc.Error != nil {
+			t.Errorf("unexpected error for %s: %+v", acc.Email, acc.Error)
+			continue
+		}
+		if len(acc.Models) != len(fakeserver.DefaultModels()) {
+			t.Errorf("expected %d models for %s, got %d", len(fakeserver.DefaultModels()), acc.Email, len(acc.Models))
+		}
+	}
+
+	if srv.Calls(fakeserver.MethodToken) != 2 {
+		t.Errorf("expected one refresh per account, got %d", srv.Calls(fakeserver.MethodToken))
+	}
+}
+
+func TestQuotaLabelAndAlias(t *testing.T) {
+	srv := setupAccounts(t, "alice@example.com", "bob@example.com")
+
+	mgr, err := auth.NewAccountManager()
+	if err != nil {
+		t.Fatalf("NewAccountManager failed: %v", err)
+	}
+	err = mgr.SetAccountMetadata("bob@example.com", auth.AccountMetadata{Alias: "bob", Labels: []string{"team-A"}})
+	if err != nil {
+		t.Fatalf("SetAccountMetadata failed: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"quota", "--all", "--label", "team-A"},
+		{"quota", "--account", "bob"},
+	} {
+		out, err := execute(t, append(args, "--json", "--api-endpoint", srv.URL())...)
+		if err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+
+		var report ui.QuotaReport
+		if err := json.Unmarshal([]byte(out), &report); err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, out)
+		}
+		if len(report.Accounts) != 1 || report.Accounts[0].Email != "bob@example.com" {
+			t.Errorf("%v: expected only bob, got %+v", args, report.Accounts)
+		}
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/gundamkid/anti-gravity-quota/internal/auth"
@@ -28,25 +31,117 @@ const (
 
 	// RetryDelay initial delay between retries
 	RetryDelay = 1 * time.Second
+
+	// ProjectCacheTTL is how long a resolved project ID and tier are reused before loadCodeAssist is called again
+	ProjectCacheTTL = 24 * time.Hour
 )
 
+// TokenSource supplies access tokens for API requests
+type TokenSource interface {
+	// Token returns a valid access token, refreshing it if it has expired
+	Token(ctx context.Context) (string, error)
+	// Refresh forces a new access token after staleToken was rejected by the API
+	Refresh(ctx context.Context, staleToken string) (string, error)
+}
+
 // Client represents a Cloud Code API client
 type Client struct {
 	httpClient *http.Client
 	baseURL    string
+	endpoints  *endpointPool
 	token      string
-	projectID  string
-	tierID     string
+	// tokenSource supplies and refreshes the token of the account the client is bound to
+	tokenSource TokenSource
+	projectID   string
+	tierID      string
+	retryDelay  time.Duration
+	// retryBudget caps the total wait between retries of a single call
+	retryBudget time.Duration
+	// lastEndpoint is the endpoint that served the last successful request
+	lastEndpoint string
+
+	// Settings from options, see options.go
+	transport http.RoundTripper
+	timeout   time.Duration
+	userAgent string
+	metadata  *models.Metadata
+
+	// Onboarding poller settings, see onboarding.go
+	onboardingTimeout     time.Duration
+	onboardingInterval    time.Duration
+	onboardingMaxInterval time.Duration
+	onboardingProgress    func(OnboardingProgress)
+	// onboarding is the onboarding started by this client or resumed from an earlier run
+	onboarding onboardingState
 }
 
 // NewClient creates a new Cloud Code API client
-func NewClient() *Client {
-	return &Client{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+func NewClient(opts ...Option) *Client {
+	c := &Client{
 		baseURL: BaseURL,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{
+			Timeout: DefaultTimeout,
+		}
+	}
+
+	// Apply transport and timeout on a copy so a caller-provided client is left untouched
+	if c.transport != nil || c.timeout != 0 {
+		httpClient := *c.httpClient
+		if c.transport != nil {
+			httpClient.Transport = c.transport
+		}
+		if c.timeout != 0 {
+			httpClient.Timeout = c.timeout
+		}
+		c.httpClient = &httpClient
+	}
+
+	// Only the default endpoint takes part in the shared failover pool
+	if c.baseURL == BaseURL {
+		c.endpoints = sharedEndpointPool()
+	}
+
+	return c
+}
+
+// endpointPool returns the endpoints used by this client.
+// Clients without a pool only talk to their base URL.
+func (c *Client) endpointPool() *endpointPool {
+	if c.endpoints == nil {
+		c.endpoints = newEndpointPool(c.baseURL)
+	}
+	return c.endpoints
+}
+
+// Endpoint returns the endpoint that served the last successful request,
+// or the currently active endpoint if no request succeeded yet
+func (c *Client) Endpoint() string {
+	if c.lastEndpoint != "" {
+		return c.lastEndpoint
+	}
+	return c.endpointPool().current()
+}
+
+// getUserAgent returns the User-Agent header value
+func (c *Client) getUserAgent() string {
+	if c.userAgent != "" {
+		return c.userAgent
+	}
+	return UserAgent
+}
+
+// getMetadata returns the client metadata sent with project requests
+func (c *Client) getMetadata() models.Metadata {
+	if c.metadata != nil {
+		return *c.metadata
+	}
+	return models.GetDefaultMetadata()
 }
 
 // SetToken sets the authentication token
@@ -54,6 +149,13 @@ func (c *Client) SetToken(token string) {
 	c.token = token
 }
 
+// SetTokenSource binds the client to a token source. The token is fetched lazily
+// and refreshed once if the API rejects it with 401.
+func (c *Client) SetTokenSource(ts TokenSource) {
+	c.tokenSource = ts
+	c.token = ""
+}
+
 // SetProjectID sets the project ID
 func (c *Client) SetProjectID(projectID string) {
 	c.projectID = projectID
@@ -64,99 +166,183 @@ func (c *Client) GetProjectID() string {
 	return c.projectID
 }
 
-// doRequest performs an HTTP request with authentication headers and retry logic
+// doRequest performs an HTTP request. If the token is rejected with 401 and the client
+// has a token source, the token is refreshed and the request replayed once.
 func (c *Client) doRequest(ctx context.Context, method, endpoint string, body interface{}) ([]byte, error) {
+	data, err := c.doRequestWithFailover(ctx, method, endpoint, body)
+	if err == nil || c.tokenSource == nil || !isUnauthorized(err) {
+		return data, err
+	}
+
+	token, refreshErr := c.tokenSource.Refresh(ctx, c.token)
+	if refreshErr != nil {
+		return nil, &Error{
+			Code:       CodeUnauthenticated,
+			HTTPStatus: http.StatusUnauthorized,
+			Message:    "token rejected and refresh failed",
+			Err:        refreshErr,
+		}
+	}
+	c.SetToken(token)
+
+	return c.doRequestWithFailover(ctx, method, endpoint, body)
+}
+
+// isUnauthorized reports whether the API rejected the request with 401
+func isUnauthorized(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.HTTPStatus == http.StatusUnauthorized
+}
+
+// doRequestWithFailover performs an HTTP request against the healthiest endpoint, failing
+// over to the next endpoint when retries against the current one run out
+func (c *Client) doRequestWithFailover(ctx context.Context, method, endpoint string, body interface{}) ([]byte, error) {
+	pool := c.endpointPool()
+
+	// The retry budget is shared by all endpoints tried for this call
+	budget := c.retryBudget
+	if budget == 0 {
+		budget = MaxRetryBudget
+	}
+	deadline := time.Now().Add(budget)
+
 	var lastErr error
+	for _, baseURL := range pool.candidates() {
+		data, failover, err := c.doRequestTo(ctx, baseURL, method, endpoint, body, deadline)
+		if err == nil {
+			pool.markHealthy(baseURL)
+			c.lastEndpoint = baseURL
+			return data, nil
+		}
+
+		if !failover || ctx.Err() != nil {
+			return nil, err
+		}
+
+		pool.markFailed(baseURL)
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+// doRequestTo performs an HTTP request against a single endpoint with authentication headers and retry logic.
+// Retries stop once the next delay would pass the deadline. The returned bool reports
+// whether the failure is worth retrying on another endpoint.
+func (c *Client) doRequestTo(ctx context.Context, baseURL, method, endpoint string, body interface{}, deadline time.Time) ([]byte, bool, error) {
+	// Marshal request body
+	var payload []byte
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		payload = jsonData
+	}
+
+	retryDelay := c.retryDelay
+	if retryDelay == 0 {
+		retryDelay = RetryDelay
+	}
+
+	retryErr := &RetryError{Endpoint: baseURL}
+	failover := false
+	var delay time.Duration
 
 	for attempt := 0; attempt <= MaxRetries; attempt++ {
 		// Check context before each attempt
 		if err := ctx.Err(); err != nil {
-			return nil, fmt.Errorf("context error: %w", err)
+			return nil, false, fmt.Errorf("context error: %w", err)
 		}
 
 		if attempt > 0 {
-			// Exponential backoff with context awareness
-			delay := RetryDelay * time.Duration(1<<uint(attempt-1))
+			// Wait for the chosen delay with context awareness
 			select {
 			case <-ctx.Done():
-				return nil, fmt.Errorf("context cancelled during retry backoff: %w", ctx.Err())
+				return nil, false, fmt.Errorf("context cancelled during retry backoff: %w", ctx.Err())
 			case <-time.After(delay):
 			}
 		}
 
-		// Marshal request body
+		// Create request
 		var bodyReader io.Reader
-		if body != nil {
-			jsonData, err := json.Marshal(body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal request: %w", err)
-			}
-			bodyReader = bytes.NewReader(jsonData)
+		if payload != nil {
+			bodyReader = bytes.NewReader(payload)
 		}
-
-		// Create request
-		url := c.baseURL + endpoint
-		req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+		req, err := http.NewRequestWithContext(ctx, method, baseURL+endpoint, bodyReader)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
+			return nil, false, fmt.Errorf("failed to create request: %w", err)
 		}
 
 		// Set headers
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("User-Agent", UserAgent)
+		req.Header.Set("User-Agent", c.getUserAgent())
 		if c.token != "" {
 			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
 		}
 
-		// Perform request
+		retryErr.Attempts = attempt + 1
+		var header http.Header
+		var responseBody []byte
+
 		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			// If context was cancelled, return immediately
-			if ctx.Err() != nil {
-				return nil, fmt.Errorf("request failed due to context: %w", ctx.Err())
-			}
-			lastErr = fmt.Errorf("request failed: %w", err)
-			continue
+		if err == nil {
+			header = resp.Header
+			responseBody, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
 		}
 
-		// Read response
-		defer resp.Body.Close()
-		responseBody, err := io.ReadAll(resp.Body)
-		if err != nil {
+		switch {
+		case err != nil:
+			// If context was cancelled, return immediately
 			if ctx.Err() != nil {
-				return nil, fmt.Errorf("failed to read response due to context: %w", ctx.Err())
+				return nil, false, fmt.Errorf("request failed due to context: %w", ctx.Err())
 			}
-			lastErr = fmt.Errorf("failed to read response: %w", err)
-			continue
-		}
+			retryErr.StatusCode = 0
+			retryErr.Err = newNetworkError("request failed", err)
+			failover = true
 
-		// Check status code
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			return responseBody, nil
-		}
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return responseBody, false, nil
+
+		case resp.StatusCode == 429:
+			// Rate limited, retry with backoff. Another endpoint shares the same quota, so don't fail over.
+			retryErr.StatusCode = resp.StatusCode
+			retryErr.Err = newResponseError(resp.StatusCode, responseBody)
+			failover = false
+
+		case resp.StatusCode >= 500:
+			// Server error, retry
+			retryErr.StatusCode = resp.StatusCode
+			retryErr.Err = newResponseError(resp.StatusCode, responseBody)
+			failover = true
 
-		// Handle errors
-		if resp.StatusCode == 401 {
-			return nil, fmt.Errorf("unauthorized: token may be invalid or expired")
+		default:
+			// Client error (including 401), don't retry
+			return nil, false, newResponseError(resp.StatusCode, responseBody)
 		}
 
-		if resp.StatusCode == 429 {
-			// Rate limited, retry with backoff
-			lastErr = fmt.Errorf("rate limited (attempt %d/%d)", attempt+1, MaxRetries+1)
-			continue
+		if attempt == MaxRetries {
+			break
 		}
 
-		if resp.StatusCode >= 500 {
-			// Server error, retry
-			lastErr = fmt.Errorf("server error %d (attempt %d/%d)", resp.StatusCode, attempt+1, MaxRetries+1)
-			continue
+		// Prefer the server's hint over our own exponential backoff
+		if hint, ok := serverRetryDelay(header, responseBody, time.Now()); ok {
+			delay = withJitter(hint)
+			retryErr.FromServer = true
+		} else {
+			delay = backoffDelay(retryDelay, attempt+1)
+			retryErr.FromServer = false
 		}
+		retryErr.Delay = delay
 
-		// Client error, don't retry
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(responseBody))
+		if time.Now().Add(delay).After(deadline) {
+			retryErr.BudgetExceeded = true
+			return nil, failover, retryErr
+		}
 	}
 
-	return nil, fmt.Errorf("request failed after %d attempts: %w", MaxRetries+1, lastErr)
+	return nil, failover, retryErr
 }
 
 // EnsureAuthenticated ensures the client has a valid token
@@ -165,10 +351,19 @@ func (c *Client) EnsureAuthenticated() error {
 		return nil
 	}
 
+	// Bind to the default account if no token source was set
+	if c.tokenSource == nil {
+		token, err := auth.LoadToken()
+		if err != nil {
+			return &Error{Code: CodeUnauthenticated, Message: "authentication required", Err: err}
+		}
+		c.tokenSource = auth.NewAccountTokenSource(token.Email, auth.GetOAuthConfig())
+	}
+
 	// Get valid token (will auto-refresh if needed)
-	token, err := auth.GetValidToken(auth.GetOAuthConfig())
+	token, err := c.tokenSource.Token(context.Background())
 	if err != nil {
-		return fmt.Errorf("authentication required: %w", err)
+		return &Error{Code: CodeUnauthenticated, Message: "authentication required", Err: err}
 	}
 
 	c.SetToken(token)
@@ -203,7 +398,7 @@ func (c *Client) LoadCodeAssist(ctx context.Context) (*models.LoadCodeAssistResp
 	}
 
 	request := models.LoadCodeAssistRequest{
-		Metadata: models.GetDefaultMetadata(),
+		Metadata: c.getMetadata(),
 	}
 
 	responseData, err := c.doRequest(ctx, "POST", "/v1internal:loadCodeAssist", request)
@@ -216,6 +411,9 @@ func (c *Client) LoadCodeAssist(ctx context.Context) (*models.LoadCodeAssistResp
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
+	// Remember the tier metadata so it can be shown when only the tier ID is cached
+	registerTiers(&response)
+
 	// Try to resolve project ID from various fields
 	projectID := response.ProjectID
 	if projectID == "" {
@@ -231,40 +429,6 @@ func (c *Client) LoadCodeAssist(ctx context.Context) (*models.LoadCodeAssistResp
 	return &response, nil
 }
 
-// OnboardUser attempts to onboard the user to get a project ID
-func (c *Client) OnboardUser(ctx context.Context, tierID string) (string, error) {
-	request := models.OnboardUserRequest{
-		TierID:   tierID,
-		Metadata: models.GetDefaultMetadata(),
-	}
-
-	for attempt := 1; attempt <= 5; attempt++ {
-		responseData, err := c.doRequest(ctx, "POST", "/v1internal:onboardUser", request)
-		if err != nil {
-			return "", fmt.Errorf("onboardUser failed: %w", err)
-		}
-
-		var response models.OnboardUserResponse
-		if err := json.Unmarshal(responseData, &response); err != nil {
-			return "", fmt.Errorf("failed to parse onboard response: %w", err)
-		}
-
-		if response.Done {
-			projectID := extractProjectId(response.Response.CloudAICompanionProject)
-			if projectID != "" {
-				return projectID, nil
-			}
-			// Done but no project ID?
-			return "", nil
-		}
-
-		// Wait before retry
-		time.Sleep(2 * time.Second)
-	}
-
-	return "", fmt.Errorf("onboarding timed out")
-}
-
 // ResolveProjectID implements the full logic to get a project ID and tier
 func (c *Client) ResolveProjectID(ctx context.Context) (string, string, error) {
 	// Step 1: Call loadCodeAssist
@@ -295,9 +459,16 @@ func (c *Client) ResolveProjectID(ctx context.Context) (string, string, error) {
 	} else {
 		// Pick from allowed tiers
 		if len(resp.AllowedTiers) > 0 {
+			// Resume a pending onboarding with the tier it was started with
+			for _, t := range resp.AllowedTiers {
+				if c.onboarding.TierID != "" && t.ID == c.onboarding.TierID {
+					tierID = t.ID
+					break
+				}
+			}
 			// Find default
 			for _, t := range resp.AllowedTiers {
-				if t.IsDefault && t.ID != "" {
+				if tierID == "" && t.IsDefault && t.ID != "" {
 					tierID = t.ID
 					break
 				}
@@ -315,11 +486,15 @@ func (c *Client) ResolveProjectID(ctx context.Context) (string, string, error) {
 	}
 
 	if tierID == "" {
-		return "", "", fmt.Errorf("cannot determine tier for onboarding")
+		return "", "", &Error{Code: CodeNotOnboarded, Message: "cannot determine tier for onboarding"}
 	}
 
 	// Step 4: Onboard
+	c.tierID = tierID
 	projectID, err := c.OnboardUser(ctx, tierID)
+	if errors.Is(err, ErrOnboardingPending) {
+		return "", "", err
+	}
 	if err != nil {
 		return "", "", fmt.Errorf("onboarding failed for tier %s: %w", tierID, err)
 	}
@@ -361,105 +536,89 @@ func (c *Client) FetchAvailableModels(ctx context.Context) (*models.FetchAvailab
 	return &response, nil
 }
 
-// GetQuotaInfo retrieves complete quota information for all models
+// GetQuotaInfo retrieves complete quota information for all models of the default account
 func (c *Client) GetQuotaInfo(ctx context.Context) (*models.QuotaSummary, error) {
-	// First, resolve project ID (this handles onboarding if needed)
-	_, tierID, err := c.ResolveProjectID(ctx)
-	if err != nil {
-		if ctx.Err() == nil {
-			fmt.Printf("DEBUG: ResolveProjectID failed: %v\n", err)
-		}
-		// Not a fatal error, continue without project ID
-	}
-	tierName := models.MapTierToName(tierID)
-
-	// Fetch available models
-	modelsResp, err := c.FetchAvailableModels(ctx)
+	token, err := auth.LoadToken()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get models: %w", err)
+		return nil, fmt.Errorf("failed to load token: %w", err)
 	}
 
-	// Convert to QuotaSummary
-	quotaSummary := &models.QuotaSummary{
-		ProjectID:      c.projectID,
-		TierName:       tierName,
-		DefaultModelID: modelsResp.DefaultAgentModel,
-		FetchedAt:      time.Now(),
-		Models:         make([]models.ModelQuota, 0, len(modelsResp.Models)),
-	}
+	return c.GetQuotaInfoForAccount(ctx, token.Email)
+}
 
-	// Get email from token and update tier in token file
-	token, err := auth.LoadToken()
-	if err == nil {
-		quotaSummary.Email = token.Email
-		// Update tier in token file if it changed or is empty
-		if token.TierName != tierName {
-			token.TierName = tierName
-			if saveErr := auth.SaveToken(token); saveErr != nil {
-				fmt.Printf("DEBUG: Failed to save updated tier for %s: %v\n", token.Email, saveErr)
-			}
-		}
-	}
+// GetQuotaInfoForAccount retrieves quota information for a specific account.
+// The project ID and tier resolved for the account are cached in its token file
+// for ProjectCacheTTL, so later fetches skip the loadCodeAssist round trip.
+// The outcome is recorded as the account's last activity.
+func (c *Client) GetQuotaInfoForAccount(ctx context.Context, email string) (*models.QuotaSummary, error) {
+	summary, err := c.getQuotaInfoForAccount(ctx, email)
 
-	// Convert models to ModelQuota
-	for modelID, model := range modelsResp.Models {
-		quotaSummary.Models = append(quotaSummary.Models, model.ToModelQuota(modelID))
+	// A cancelled run says nothing about the account
+	if ctx.Err() == nil {
+		_ = auth.RecordAccountActivity(email, err)
 	}
 
-	return quotaSummary, nil
+	return summary, err
 }
 
-// GetQuotaInfoForAccount retrieves quota information for a specific account
-func (c *Client) GetQuotaInfoForAccount(ctx context.Context, email string) (*models.QuotaSummary, error) {
+func (c *Client) getQuotaInfoForAccount(ctx context.Context, email string) (*models.QuotaSummary, error) {
 	// Load token for the specific account
 	token, err := auth.LoadTokenForAccount(email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load token for %s: %w", email, err)
 	}
 
-	// Note: We don't call token.IsValid() here because GetValidTokenForAccount
-	// will handle the refresh logic if the access token is expired.
-
-	// Get valid access token (refresh if needed)
-	accessToken, err := auth.GetValidTokenForAccount(email, auth.GetOAuthConfig())
-	if err != nil {
+	// Bind the client to this account. The token source refreshes the access token
+	// if it expired, or if the API rejects it before its stored expiry.
+	c.SetTokenSource(auth.NewAccountTokenSource(email, auth.GetOAuthConfig()))
+	if err := c.EnsureAuthenticated(); err != nil {
 		return nil, fmt.Errorf("failed to get valid token for %s: %w", email, err)
 	}
 
-	// Set token for this client
-	c.SetToken(accessToken)
-
-	// First, resolve project ID (this handles onboarding if needed)
-	projectID, tierID, err := c.ResolveProjectID(ctx)
-	if err != nil {
-		if ctx.Err() == nil {
-			fmt.Printf("DEBUG: ResolveProjectID failed for %s: %v\n", email, err)
-		}
-		// Not a fatal error, continue without project ID
-	} else if projectID != "" {
-		c.SetProjectID(projectID)
-	}
-	tierName := models.MapTierToName(tierID)
-
-	// Update tier in token file
-	if token.TierName != tierName {
-		token.TierName = tierName
-		if saveErr := auth.SaveTokenForAccount(email, token); saveErr != nil {
-			fmt.Printf("DEBUG: Failed to save updated tier for account %s: %v\n", email, saveErr)
+	var tierID string
+	cached := token.HasFreshProject(ProjectCacheTTL)
+	if cached {
+		c.SetProjectID(token.ProjectID)
+		c.tierID = token.TierID
+		tierID = token.TierID
+	} else {
+		// Resolve project ID (this handles onboarding if needed)
+		tierID, err = c.resolveProject(ctx, email)
+		if err != nil {
+			// Not a fatal error, continue without project ID. Stdout is left
+			// alone so it does not break the --json output.
+			if ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not resolve project for %s: %v\n", email, err)
+			}
 		}
 	}
 
 	// Fetch available models
 	modelsResp, err := c.FetchAvailableModels(ctx)
+	if err != nil && cached && isProjectError(err) {
+		// The cached project may no longer be valid, resolve it again and retry once
+		tierID, err = c.resolveProject(ctx, email)
+		if err == nil {
+			modelsResp, err = c.FetchAvailableModels(ctx)
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch models for %s: %w", email, err)
 	}
 
+	// Fall back to the last known tier if it could not be resolved
+	if tierID == "" {
+		tierID = token.TierID
+	}
+	tier := LookupTier(tierID)
+
 	// Convert to QuotaSummary
 	quotaSummary := &models.QuotaSummary{
 		ProjectID:      c.projectID,
-		TierName:       tierName,
+		TierName:       tier.Label,
+		Tier:           &tier,
 		DefaultModelID: modelsResp.DefaultAgentModel,
+		Endpoint:       c.Endpoint(),
 		FetchedAt:      time.Now(),
 		Email:          email,
 		Models:         make([]models.ModelQuota, 0, len(modelsResp.Models)),
@@ -472,3 +631,75 @@ func (c *Client) GetQuotaInfoForAccount(ctx context.Context, email string) (*mod
 
 	return quotaSummary, nil
 }
+
+// RefreshProjectMetadata discards the cached project ID and tier of an account
+// and resolves them again. It returns the resolved project ID and tier ID.
+func (c *Client) RefreshProjectMetadata(ctx context.Context, email string) (string, string, error) {
+	c.SetTokenSource(auth.NewAccountTokenSource(email, auth.GetOAuthConfig()))
+	if err := c.EnsureAuthenticated(); err != nil {
+		return "", "", fmt.Errorf("failed to get valid token for %s: %w", email, err)
+	}
+
+	tierID, err := c.resolveProject(ctx, email)
+	if err != nil {
+		return "", "", err
+	}
+
+	return c.projectID, tierID, nil
+}
+
+// resolveProject resolves the project ID and tier of an account and caches them in its token file.
+// On failure the cached values are dropped so they are not reused. An onboarding that is still
+// pending is recorded in the token file so the next run resumes it.
+func (c *Client) resolveProject(ctx context.Context, email string) (string, error) {
+	c.SetProjectID("")
+
+	if token, err := auth.LoadTokenForAccount(email); err == nil && token.OnboardingTierID != "" {
+		c.ResumeOnboarding(token.OnboardingTierID, token.OnboardingStartedAt)
+	}
+
+	projectID, tierID, err := c.ResolveProjectID(ctx)
+	if err != nil {
+		pendingTier, startedAt, pending := c.PendingOnboarding()
+		saveErr := auth.UpdateTokenForAccount(email, func(t *auth.TokenData) {
+			t.ClearProject()
+			if pending && errors.Is(err, ErrOnboardingPending) {
+				t.OnboardingTierID = pendingTier
+				t.OnboardingStartedAt = startedAt
+			}
+		})
+		if saveErr != nil {
+			return "", errors.Join(err, fmt.Errorf("failed to clear project metadata for %s: %w", email, saveErr))
+		}
+		return "", err
+	}
+	c.SetProjectID(projectID)
+
+	// The token is re-read under lock since it may have been refreshed meanwhile
+	saveErr := auth.UpdateTokenForAccount(email, func(t *auth.TokenData) {
+		t.ProjectID = projectID
+		t.TierID = tierID
+		t.TierName = LookupTier(tierID).Label
+		t.ProjectResolvedAt = time.Now()
+		t.OnboardingTierID = ""
+		t.OnboardingStartedAt = time.Time{}
+	})
+	if saveErr != nil {
+		// The project is resolved, only the cache is stale; the next run resolves it again
+		fmt.Fprintf(os.Stderr, "Warning: failed to cache project for %s: %v\n", email, saveErr)
+	}
+
+	return tierID, nil
+}
+
+// isProjectError reports whether an error suggests the project used for the request is no longer valid
+func isProjectError(err error) bool {
+	switch ErrorCode(err) {
+	case CodePermissionDenied, CodeNotOnboarded, CodeNotFound:
+		return true
+	case CodeInvalidRequest:
+		var apiErr *Error
+		return errors.As(err, &apiErr) && strings.Contains(strings.ToLower(apiErr.Message), "project")
+	}
+	return false
+}
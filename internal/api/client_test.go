@@ -3,9 +3,12 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gundamkid/anti-gravity-quota/internal/models"
 )
@@ -89,3 +92,138 @@ func TestClient_LoadCodeAssist(t *testing.T) {
 		t.Errorf("client projectID not updated")
 	}
 }
+
+func TestClient_doRequestFailover(t *testing.T) {
+	primaryHits := 0
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryHits++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status": "backup"}`))
+	}))
+	defer backup.Close()
+
+	pool := newEndpointPool(primary.URL, backup.URL)
+	client := &Client{
+		httpClient: http.DefaultClient,
+		endpoints:  pool,
+		retryDelay: time.Millisecond,
+	}
+
+	data, err := client.doRequest(context.Background(), "GET", "/test", nil)
+	if err != nil {
+		t.Fatalf("doRequest failed: %v", err)
+	}
+	if string(data) != `{"status": "backup"}` {
+		t.Errorf("unexpected response: %s", string(data))
+	}
+	if primaryHits != MaxRetries+1 {
+		t.Errorf("expected %d attempts on primary, got %d", MaxRetries+1, primaryHits)
+	}
+	if client.Endpoint() != backup.URL || pool.current() != backup.URL {
+		t.Errorf("expected backup to be active, got %s", pool.current())
+	}
+
+	// Subsequent requests stick to the healthy backup
+	if _, err := client.doRequest(context.Background(), "GET", "/test", nil); err != nil {
+		t.Fatalf("doRequest failed: %v", err)
+	}
+	if primaryHits != MaxRetries+1 {
+		t.Errorf("primary should not be retried before the probe interval, got %d hits", primaryHits)
+	}
+}
+
+func TestEndpointPool_Probe(t *testing.T) {
+	pool := newEndpointPool("https://primary", "https://backup")
+	pool.markFailed("https://primary")
+
+	if got := pool.candidates(); got[0] != "https://backup" {
+		t.Errorf("expected backup first, got %v", got)
+	}
+
+	// Once the probe interval elapsed, the primary is tried first again
+	pool.failedAt = time.Now().Add(-EndpointProbeInterval)
+	if got := pool.candidates(); got[0] != "https://primary" {
+		t.Errorf("expected primary probe first, got %v", got)
+	}
+
+	pool.markHealthy("https://primary")
+	if pool.current() != "https://primary" {
+		t.Errorf("expected primary to be active after successful probe, got %s", pool.current())
+	}
+}
+
+// fakeTokenSource hands out a new token on every refresh
+type fakeTokenSource struct {
+	token     string
+	refreshes int
+}
+
+func (f *fakeTokenSource) Token(ctx context.Context) (string, error) {
+	return f.token, nil
+}
+
+func (f *fakeTokenSource) Refresh(ctx context.Context, staleToken string) (string, error) {
+	f.refreshes++
+	f.token = fmt.Sprintf("fresh-token-%d", f.refreshes)
+	return f.token, nil
+}
+
+func TestClient_doRequestRefreshOn401(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh-token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer server.Close()
+
+	ts := &fakeTokenSource{token: "revoked-token"}
+	client := &Client{
+		httpClient: server.Client(),
+		baseURL:    server.URL,
+	}
+	client.SetTokenSource(ts)
+	if err := client.EnsureAuthenticated(); err != nil {
+		t.Fatalf("EnsureAuthenticated failed: %v", err)
+	}
+
+	data, err := client.doRequest(context.Background(), "POST", "/test", nil)
+	if err != nil {
+		t.Fatalf("doRequest failed: %v", err)
+	}
+	if string(data) != `{"status": "ok"}` {
+		t.Errorf("unexpected response: %s", string(data))
+	}
+	if ts.refreshes != 1 {
+		t.Errorf("expected 1 refresh, got %d", ts.refreshes)
+	}
+}
+
+func TestClient_doRequestRefreshOnlyOnce(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	ts := &fakeTokenSource{token: "revoked-token"}
+	client := &Client{
+		httpClient: server.Client(),
+		baseURL:    server.URL,
+	}
+	client.SetTokenSource(ts)
+
+	_, err := client.doRequest(context.Background(), "POST", "/test", nil)
+	if !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected unauthenticated error, got %v", err)
+	}
+	if ts.refreshes != 1 || hits != 2 {
+		t.Errorf("expected a single replay, got %d refreshes and %d requests", ts.refreshes, hits)
+	}
+}
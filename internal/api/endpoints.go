@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+)
+
+// EndpointProbeInterval is how long the client stays on a fallback endpoint
+// before giving the primary endpoint another try
+const EndpointProbeInterval = 10 * time.Minute
+
+// endpointState is the persisted health state of the endpoint pool
+type endpointState struct {
+	Active   string    `json:"active"`
+	FailedAt time.Time `json:"failed_at,omitempty"`
+}
+
+// endpointPool tracks which Cloud Code endpoint is healthy and decides
+// the order in which endpoints are tried for a request
+type endpointPool struct {
+	mu       sync.Mutex
+	urls     []string
+	active   int
+	failedAt time.Time
+	persist  bool
+}
+
+var (
+	sharedPoolOnce sync.Once
+	sharedPool     *endpointPool
+)
+
+// newEndpointPool creates a pool with the given endpoints, the first one being the primary
+func newEndpointPool(urls ...string) *endpointPool {
+	return &endpointPool{urls: urls}
+}
+
+// sharedEndpointPool returns the pool used by clients created with NewClient.
+// Sharing it keeps a failover sticky across fetches (e.g. in watch mode), and
+// persisting it lets separate invocations agree on the active endpoint.
+func sharedEndpointPool() *endpointPool {
+	sharedPoolOnce.Do(func() {
+		sharedPool = newEndpointPool(BaseURL, BackupURL)
+		sharedPool.persist = true
+		sharedPool.load()
+	})
+	return sharedPool
+}
+
+// ActiveEndpoint returns the endpoint currently used by default clients
+func ActiveEndpoint() string {
+	return sharedEndpointPool().current()
+}
+
+// IsBackupEndpoint reports whether the given endpoint is the backup endpoint
+func IsBackupEndpoint(endpoint string) bool {
+	return endpoint == BackupURL
+}
+
+// current returns the endpoint requests are currently sent to
+func (p *endpointPool) current() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.urls[p.active]
+}
+
+// candidates returns the endpoints to try, in order, for the next request.
+// The active endpoint comes first, unless the probe interval has elapsed since
+// the primary failed, in which case the primary is tried again first.
+func (p *endpointPool) candidates() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	start := p.active
+	if p.active != 0 && time.Since(p.failedAt) >= EndpointProbeInterval {
+		start = 0
+	}
+
+	order := make([]string, 0, len(p.urls))
+	for i := range p.urls {
+		order = append(order, p.urls[(start+i)%len(p.urls)])
+	}
+	return order
+}
+
+// markHealthy makes the given endpoint the active one after a successful request
+func (p *endpointPool) markHealthy(url string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx := p.indexOf(url)
+	if idx < 0 || idx == p.active {
+		return
+	}
+
+	p.active = idx
+	if idx == 0 {
+		p.failedAt = time.Time{}
+	}
+	p.save()
+}
+
+// markFailed records that an endpoint ran out of retries and moves the pool
+// to the next endpoint if it was the active one
+func (p *endpointPool) markFailed(url string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx := p.indexOf(url)
+	if idx < 0 {
+		return
+	}
+
+	switch {
+	case idx == p.active:
+		p.active = (idx + 1) % len(p.urls)
+		p.failedAt = time.Now()
+	case idx == 0:
+		// A failed probe of the primary restarts the probe interval
+		p.failedAt = time.Now()
+	default:
+		return
+	}
+	p.save()
+}
+
+func (p *endpointPool) indexOf(url string) int {
+	for i, u := range p.urls {
+		if u == url {
+			return i
+		}
+	}
+	return -1
+}
+
+// load restores the persisted state. Errors are ignored, the pool simply starts on the primary.
+func (p *endpointPool) load() {
+	path, err := config.GetEndpointStatePath()
+	if err != nil {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	var state endpointState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return
+	}
+
+	if idx := p.indexOf(state.Active); idx >= 0 {
+		p.active = idx
+		p.failedAt = state.FailedAt
+	}
+}
+
+// save persists the state. It must be called while holding the lock.
+func (p *endpointPool) save() {
+	if !p.persist {
+		return
+	}
+
+	path, err := config.GetEndpointStatePath()
+	if err != nil {
+		return
+	}
+	if _, err := config.EnsureConfigDir(); err != nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(endpointState{
+		Active:   p.urls[p.active],
+		FailedAt: p.failedAt,
+	}, "", "  ")
+	if err != nil {
+		return
+	}
+
+	_ = config.AtomicWrite(path, data, 0600)
+}
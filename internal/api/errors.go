@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Machine-readable error codes exposed to callers and in JSON output
+const (
+	CodeUnauthenticated   = "UNAUTHENTICATED"
+	CodePermissionDenied  = "PERMISSION_DENIED"
+	CodeRateLimited       = "RATE_LIMITED"
+	CodeNotOnboarded      = "NOT_ONBOARDED"
+	CodeOnboardingPending = "ONBOARDING_PENDING"
+	CodeInvalidRequest    = "INVALID_REQUEST"
+	CodeNotFound          = "NOT_FOUND"
+	CodeNetwork           = "NETWORK_ERROR"
+	CodeServer            = "SERVER_ERROR"
+	CodeCancelled         = "CANCELLED"
+	CodeUnknown           = "UNKNOWN"
+)
+
+var (
+	// ErrUnauthenticated matches errors caused by a missing, invalid or expired token
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrPermissionDenied matches errors where the account lacks access
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrRateLimited matches errors caused by rate limiting or exhausted resources
+	ErrRateLimited = errors.New("rate limited")
+	// ErrNotOnboarded matches errors where the account has no Cloud Code project yet
+	ErrNotOnboarded = errors.New("account not onboarded")
+	// ErrOnboardingPending matches errors where onboarding was started but has not completed yet
+	ErrOnboardingPending = errors.New("onboarding still pending")
+	// ErrNetwork matches errors where the API could not be reached
+	ErrNetwork = errors.New("network error")
+	// ErrServer matches errors returned by the API with a 5xx status
+	ErrServer = errors.New("server error")
+)
+
+// sentinelCodes maps sentinel errors to the code an Error must carry to match them
+var sentinelCodes = map[error]string{
+	ErrUnauthenticated:   CodeUnauthenticated,
+	ErrPermissionDenied:  CodePermissionDenied,
+	ErrRateLimited:       CodeRateLimited,
+	ErrNotOnboarded:      CodeNotOnboarded,
+	ErrOnboardingPending: CodeOnboardingPending,
+	ErrNetwork:           CodeNetwork,
+	ErrServer:            CodeServer,
+}
+
+// Error is a failed Cloud Code API call
+type Error struct {
+	// Code is the machine-readable error code (one of the Code* constants)
+	Code string `json:"code"`
+	// HTTPStatus is the HTTP status code, 0 if no response was received
+	HTTPStatus int `json:"http_status,omitempty"`
+	// Status is the google.rpc status code, e.g. "PERMISSION_DENIED"
+	Status string `json:"status,omitempty"`
+	// Message is the error message returned by the server or describing the failure
+	Message string `json:"message"`
+	// Details are the google.rpc error details, if any
+	Details []map[string]interface{} `json:"details,omitempty"`
+	// Retryable reports whether the call may succeed if tried again later
+	Retryable bool `json:"retryable"`
+	// Err is the underlying error, e.g. a network error
+	Err error `json:"-"`
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.HTTPStatus != 0 && e.Status != "":
+		return fmt.Sprintf("API error %d (%s): %s", e.HTTPStatus, e.Status, e.Message)
+	case e.HTTPStatus != 0:
+		return fmt.Sprintf("API error %d: %s", e.HTTPStatus, e.Message)
+	case e.Err != nil:
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	default:
+		return e.Message
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches one of the sentinel errors
+func (e *Error) Is(target error) bool {
+	code, ok := sentinelCodes[target]
+	return ok && e.Code == code
+}
+
+// rpcStatus is the google.rpc.Status payload of an API error body
+type rpcStatus struct {
+	Code    int                      `json:"code"`
+	Message string                   `json:"message"`
+	Status  string                   `json:"status"`
+	Details []map[string]interface{} `json:"details"`
+}
+
+// parseRPCStatus extracts the google.rpc.Status from an error body
+func parseRPCStatus(body []byte) (rpcStatus, bool) {
+	var parsed struct {
+		Error rpcStatus `json:"error"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &parsed) != nil {
+		return rpcStatus{}, false
+	}
+	return parsed.Error, parsed.Error.Code != 0 || parsed.Error.Status != "" || parsed.Error.Message != ""
+}
+
+// newResponseError builds an Error from a non-2xx API response
+func newResponseError(statusCode int, body []byte) *Error {
+	apiErr := &Error{
+		HTTPStatus: statusCode,
+		Message:    http.StatusText(statusCode),
+	}
+
+	if status, ok := parseRPCStatus(body); ok {
+		apiErr.Status = status.Status
+		apiErr.Details = status.Details
+		if status.Message != "" {
+			apiErr.Message = status.Message
+		}
+	} else if len(body) > 0 {
+		apiErr.Message = string(body)
+	}
+
+	apiErr.Code, apiErr.Retryable = classify(statusCode, apiErr.Status)
+	return apiErr
+}
+
+// newNetworkError builds an Error for a request that got no response
+func newNetworkError(message string, err error) *Error {
+	return &Error{
+		Code:      CodeNetwork,
+		Message:   message,
+		Retryable: true,
+		Err:       err,
+	}
+}
+
+// classify maps an HTTP status and google.rpc status to an error code and retryability
+func classify(statusCode int, rpcStatus string) (string, bool) {
+	switch {
+	case statusCode == http.StatusUnauthorized || rpcStatus == "UNAUTHENTICATED":
+		return CodeUnauthenticated, false
+	case rpcStatus == "FAILED_PRECONDITION":
+		return CodeNotOnboarded, false
+	case statusCode == http.StatusForbidden || rpcStatus == "PERMISSION_DENIED":
+		return CodePermissionDenied, false
+	case statusCode == http.StatusTooManyRequests || rpcStatus == "RESOURCE_EXHAUSTED":
+		return CodeRateLimited, true
+	case statusCode >= 500:
+		return CodeServer, true
+	case statusCode == http.StatusNotFound:
+		return CodeNotFound, false
+	case statusCode >= 400:
+		return CodeInvalidRequest, false
+	default:
+		return CodeUnknown, false
+	}
+}
+
+// ErrorCode returns the machine-readable code for any error returned by the client
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return CodeCancelled
+	}
+
+	return CodeUnknown
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewResponseError(t *testing.T) {
+	body := []byte(`{
+		"error": {
+			"code": 403,
+			"message": "The caller does not have permission",
+			"status": "PERMISSION_DENIED",
+			"details": [{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "ACCESS_DENIED"}]
+		}
+	}`)
+
+	err := newResponseError(http.StatusForbidden, body)
+
+	if err.Code != CodePermissionDenied || err.Status != "PERMISSION_DENIED" {
+		t.Errorf("unexpected classification: %+v", err)
+	}
+	if err.Message != "The caller does not have permission" {
+		t.Errorf("unexpected message: %s", err.Message)
+	}
+	if len(err.Details) != 1 || err.Details[0]["reason"] != "ACCESS_DENIED" {
+		t.Errorf("unexpected details: %v", err.Details)
+	}
+	if err.Retryable {
+		t.Error("permission denied should not be retryable")
+	}
+}
+
+func TestErrorSentinels(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		sentinel error
+		code     string
+	}{
+		{"Unauthorized", newResponseError(http.StatusUnauthorized, nil), ErrUnauthenticated, CodeUnauthenticated},
+		{"Rate limited", newResponseError(http.StatusTooManyRequests, nil), ErrRateLimited, CodeRateLimited},
+		{"Resource exhausted", newResponseError(http.StatusBadRequest, []byte(`{"error": {"status": "RESOURCE_EXHAUSTED"}}`)), ErrRateLimited, CodeRateLimited},
+		{"Failed precondition", newResponseError(http.StatusBadRequest, []byte(`{"error": {"status": "FAILED_PRECONDITION"}}`)), ErrNotOnboarded, CodeNotOnboarded},
+		{"Server error", newResponseError(http.StatusBadGateway, nil), ErrServer, CodeServer},
+		{"Network error", newNetworkError("request failed", errors.New("connection refused")), ErrNetwork, CodeNetwork},
+		{"Wrapped", fmt.Errorf("loadCodeAssist failed: %w", newResponseError(http.StatusForbidden, nil)), ErrPermissionDenied, CodePermissionDenied},
+		{"Inside RetryError", &RetryError{Err: newResponseError(http.StatusServiceUnavailable, nil)}, ErrServer, CodeServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.sentinel) {
+				t.Errorf("expected errors.Is(%v, %v)", tt.err, tt.sentinel)
+			}
+			if got := ErrorCode(tt.err); got != tt.code {
+				t.Errorf("ErrorCode() = %s, want %s", got, tt.code)
+			}
+		})
+	}
+
+	if errors.Is(newResponseError(http.StatusForbidden, nil), ErrUnauthenticated) {
+		t.Error("permission denied should not match ErrUnauthenticated")
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	if got := ErrorCode(nil); got != "" {
+		t.Errorf("expected empty code for nil, got %s", got)
+	}
+	if got := ErrorCode(fmt.Errorf("request failed due to context: %w", context.Canceled)); got != CodeCancelled {
+		t.Errorf("expected %s, got %s", CodeCancelled, got)
+	}
+	if got := ErrorCode(errors.New("boom")); got != CodeUnknown {
+		t.Errorf("expected %s, got %s", CodeUnknown, got)
+	}
+}
+
+func TestClient_doRequestTypedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error": {"code": 401, "message": "Request had invalid authentication credentials.", "status": "UNAUTHENTICATED"}}`))
+	}))
+	defer server.Close()
+
+	client := &Client{
+		httpClient: server.Client(),
+		baseURL:    server.URL,
+		retryDelay: time.Millisecond,
+	}
+
+	_, err := client.doRequest(context.Background(), "POST", "/v1internal:loadCodeAssist", nil)
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *Error, got %T: %v", err, err)
+	}
+	if apiErr.HTTPStatus != http.StatusUnauthorized || apiErr.Status != "UNAUTHENTICATED" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+	if !errors.Is(err, ErrUnauthenticated) {
+		t.Error("expected error to match ErrUnauthenticated")
+	}
+}
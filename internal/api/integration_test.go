@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/auth"
+	"github.com/gundamkid/anti-gravity-quota/internal/fakeserver"
+	"github.com/gundamkid/anti-gravity-quota/internal/models"
+)
+
+// setupFakeServer starts a fake server, points the auth package at it and
+// isolates the config directory
+func setupFakeServer(t *testing.T) *fakeserver.Server {
+	t.Helper()
+
+	srv := fakeserver.New()
+	t.Cleanup(srv.Close)
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	endpoints := auth.Endpoints
+	auth.Endpoints = auth.OAuthEndpoints{AuthURL: srv.AuthURL(), TokenURL: srv.TokenURL(), UserInfoURL: srv.UserInfoURL(), RevokeURL: srv.RevokeURL()}
+	t.Cleanup(func() { auth.Endpoints = endpoints })
+
+	return srv
+}
+
+// saveAccount stores a token for an account registered on the fake server
+func saveAccount(t *testing.T, srv *fakeserver.Server, acc fakeserver.Account, expired bool) {
+	t.Helper()
+
+	accessToken := srv.AddAccount(acc)
+	expiry := time.Now().Add(time.Hour)
+	if expired {
+		accessToken = "expired-token"
+		expiry = time.Now().Add(-time.Hour)
+	}
+
+	err := auth.SaveTokenForAccount(acc.Email, &auth.TokenData{
+		AccessToken:  accessToken,
+		RefreshToken: srv.RefreshToken(acc.Email),
+		TokenType:    "Bearer",
+		Expiry:       expiry,
+	})
+	if err != nil {
+		t.Fatalf("SaveTokenForAccount failed: %v", err)
+	}
+}
+
+func TestIntegration_QuotaForOnboardedAccount(t *testing.T) {
+	srv := setupFakeServer(t)
+	saveAccount(t, srv, fakeserver.OnboardedAccount("user@example.com"), true)
+
+	client := NewClient(WithBaseURL(srv.URL()))
+	summary, err := client.GetQuotaInfoForAccount(context.Background(), "user@example.com")
+	if err != nil {
+		t.Fatalf("GetQuotaInfoForAccount failed: %v", err)
+	}
+
+	if summary.ProjectID != "project-user@example.com" {
+		t.Errorf("unexpected project: %s", summary.ProjectID)
+	}
+	if len(summary.Models) != len(fakeserver.DefaultModels()) {
+		t.Errorf("expected %d models, got %d", len(fakeserver.DefaultModels()), len(summary.Models))
+	}
+	if srv.Calls(fakeserver.MethodToken) != 1 {
+		t.Errorf("expected the expired token to be refreshed once, got %d refreshes", srv.Calls(fakeserver.MethodToken))
+	}
+	if srv.Calls(fakeserver.MethodOnboardUser) != 0 {
+		t.Error("onboarded account should not be onboarded again")
+	}
+}
+
+func TestIntegration_Onboarding(t *testing.T) {
+	srv := setupFakeServer(t)
+	saveAccount(t, srv, fakeserver.NewUserAccount("new@example.com", 1), false)
+
+	client := NewClient(WithBaseURL(srv.URL()), WithOnboardingBackoff(10*time.Millisecond, 10*time.Millisecond))
+	summary, err := client.GetQuotaInfoForAccount(context.Background(), "new@example.com")
+	if err != nil {
+		t.Fatalf("GetQuotaInfoForAccount failed: %v", err)
+	}
+
+	if summary.ProjectID != "onboarded-new@example.com" {
+		t.Errorf("expected onboarded project, got %s", summary.ProjectID)
+	}
+	if srv.Calls(fakeserver.MethodOnboardUser) != 2 {
+		t.Errorf("expected one pending poll and one done poll, got %d calls", srv.Calls(fakeserver.MethodOnboardUser))
+	}
+}
+
+func TestIntegration_RateLimitBurst(t *testing.T) {
+	srv := setupFakeServer(t)
+	saveAccount(t, srv, fakeserver.OnboardedAccount("busy@example.com"), false)
+	srv.InjectFaults(fakeserver.MethodFetchAvailableModels, fakeserver.RateLimitBurst(2, "0")...)
+
+	client := NewClient(WithBaseURL(srv.URL()))
+	summary, err := client.GetQuotaInfoForAccount(context.Background(), "busy@example.com")
+	if err != nil {
+		t.Fatalf("GetQuotaInfoForAccount failed: %v", err)
+	}
+
+	if len(summary.Models) == 0 {
+		t.Error("expected models after the burst")
+	}
+	if srv.Calls(fakeserver.MethodFetchAvailableModels) != 3 {
+		t.Errorf("expected 3 fetch attempts, got %d", srv.Calls(fakeserver.MethodFetchAvailableModels))
+	}
+}
+
+func TestIntegration_RevokedToken(t *testing.T) {
+	srv := setupFakeServer(t)
+	saveAccount(t, srv, fakeserver.OnboardedAccount("revoked@example.com"), false)
+	srv.RevokeAccessTokens("revoked@example.com")
+
+	client := NewClient(WithBaseURL(srv.URL()))
+	if _, err := client.GetQuotaInfoForAccount(context.Background(), "revoked@example.com"); err != nil {
+		t.Fatalf("GetQuotaInfoForAccount failed: %v", err)
+	}
+
+	if srv.Calls(fakeserver.MethodToken) != 1 {
+		t.Errorf("expected a forced refresh after 401, got %d refreshes", srv.Calls(fakeserver.MethodToken))
+	}
+}
+
+func TestIntegration_SlowResponse(t *testing.T) {
+	srv := setupFakeServer(t)
+	saveAccount(t, srv, fakeserver.OnboardedAccount("slow@example.com"), false)
+	srv.InjectFaults(fakeserver.MethodLoadCodeAssist, fakeserver.Slow(time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	client := NewClient(WithBaseURL(srv.URL()))
+	_, err := client.GetQuotaInfoForAccount(ctx, "slow@example.com")
+	if ErrorCode(err) != CodeCancelled {
+		t.Errorf("expected %s, got %v", CodeCancelled, err)
+	}
+}
+
+func TestIntegration_ProjectCache(t *testing.T) {
+	srv := setupFakeServer(t)
+	saveAccount(t, srv, fakeserver.OnboardedAccount("cached@example.com"), false)
+
+	for i := 0; i < 2; i++ {
+		client := NewClient(WithBaseURL(srv.URL()))
+		summary, err := client.GetQuotaInfoForAccount(context.Background(), "cached@example.com")
+		if err != nil {
+			t.Fatalf("GetQuotaInfoForAccount failed: %v", err)
+		}
+		if summary.ProjectID != "project-cached@example.com" {
+			t.Errorf("unexpected project on fetch %d: %s", i+1, summary.ProjectID)
+		}
+	}
+
+	if srv.Calls(fakeserver.MethodLoadCodeAssist) != 1 {
+		t.Errorf("expected the project to be resolved once, got %d loadCodeAssist calls", srv.Calls(fakeserver.MethodLoadCodeAssist))
+	}
+
+	token, err := auth.LoadTokenForAccount("cached@example.com")
+	if err != nil {
+		t.Fatalf("LoadTokenForAccount failed: %v", err)
+	}
+	if token.ProjectID != "project-cached@example.com" || token.TierID != fakeserver.TierFree || token.ProjectResolvedAt.IsZero() {
+		t.Errorf("project metadata not persisted: %+v", token)
+	}
+}
+
+func TestIntegration_ProjectCacheInvalidated(t *testing.T) {
+	srv := setupFakeServer(t)
+	saveAccount(t, srv, fakeserver.OnboardedAccount("moved@example.com"), false)
+
+	if _, err := NewClient(WithBaseURL(srv.URL())).GetQuotaInfoForAccount(context.Background(), "moved@example.com"); err != nil {
+		t.Fatalf("GetQuotaInfoForAccount failed: %v", err)
+	}
+
+	// The cached project is now rejected, the client must resolve it again
+	srv.SetProject("moved@example.com", "new-project")
+
+	summary, err := NewClient(WithBaseURL(srv.URL())).GetQuotaInfoForAccount(context.Background(), "moved@example.com")
+	if err != nil {
+		t.Fatalf("GetQuotaInfoForAccount failed: %v", err)
+	}
+	if summary.ProjectID != "new-project" {
+		t.Errorf("expected new-project, got %s", summary.ProjectID)
+	}
+	if srv.Calls(fakeserver.MethodLoadCodeAssist) != 2 {
+		t.Errorf("expected the project to be resolved again, got %d loadCodeAssist calls", srv.Calls(fakeserver.MethodLoadCodeAssist))
+	}
+
+	token, err := auth.LoadTokenForAccount("moved@example.com")
+	if err != nil {
+		t.Fatalf("LoadTokenForAccount failed: %v", err)
+	}
+	if token.ProjectID != "new-project" {
+		t.Errorf("expected the new project to be cached, got %s", token.ProjectID)
+	}
+}
+
+func TestIntegration_RefreshProjectMetadata(t *testing.T) {
+	srv := setupFakeServer(t)
+	saveAccount(t, srv, fakeserver.OnboardedAccount("meta@example.com"), false)
+
+	client := NewClient(WithBaseURL(srv.URL()))
+	for i := 0; i < 2; i++ {
+		projectID, tierID, err := client.RefreshProjectMetadata(context.Background(), "meta@example.com")
+		if err != nil {
+			t.Fatalf("RefreshProjectMetadata failed: %v", err)
+		}
+		if projectID != "project-meta@example.com" || tierID != fakeserver.TierFree {
+			t.Errorf("unexpected metadata: %s, %s", projectID, tierID)
+		}
+	}
+
+	if srv.Calls(fakeserver.MethodLoadCodeAssist) != 2 {
+		t.Errorf("expected every refresh to call loadCodeAssist, got %d calls", srv.Calls(fakeserver.MethodLoadCodeAssist))
+	}
+}
+
+func TestIntegration_PaidTierMetadata(t *testing.T) {
+	srv := setupFakeServer(t)
+	seat := models.Tier{ID: "enterprise-seat-tier", Name: "Enterprise Seat", Description: "Paid seat"}
+	saveAccount(t, srv, fakeserver.PaidAccount("paid@example.com", seat), false)
+
+	// The second fetch uses the cached tier ID and must still show the server's name
+	for i := 0; i < 2; i++ {
+		summary, err := NewClient(WithBaseURL(srv.URL())).GetQuotaInfoForAccount(context.Background(), "paid@example.com")
+		if err != nil {
+			t.Fatalf("GetQuotaInfoForAccount failed: %v", err)
+		}
+
+		if summary.TierName != "Enterprise Seat" {
+			t.Errorf("fetch %d: expected the server's tier name, got %s", i+1, summary.TierName)
+		}
+		if summary.Tier == nil || summary.Tier.ID != seat.ID || summary.Tier.Description != "Paid seat" {
+			t.Errorf("fetch %d: tier metadata missing: %+v", i+1, summary.Tier)
+		}
+	}
+
+	if srv.Calls(fakeserver.MethodLoadCodeAssist) != 1 {
+		t.Errorf("expected the tier to be resolved once, got %d loadCodeAssist calls", srv.Calls(fakeserver.MethodLoadCodeAssist))
+	}
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/models"
+)
+
+const (
+	// DefaultOnboardingTimeout is how long OnboardUser polls in a single run
+	DefaultOnboardingTimeout = 2 * time.Minute
+
+	// DefaultOnboardingInterval is the initial delay between onboarding polls
+	DefaultOnboardingInterval = 2 * time.Second
+
+	// DefaultOnboardingMaxInterval caps the delay between onboarding polls
+	DefaultOnboardingMaxInterval = 15 * time.Second
+)
+
+// OnboardingProgress describes an onboarding in progress, reported after each poll
+type OnboardingProgress struct {
+	TierID string
+	// Attempt is the number of polls made in this run
+	Attempt int
+	// Elapsed is the time since onboarding started, including earlier runs if it was resumed
+	Elapsed time.Duration
+	// NextPoll is the delay before the next poll, zero once done
+	NextPoll time.Duration
+	// Resumed reports whether the onboarding was started by an earlier run
+	Resumed bool
+	Done    bool
+}
+
+// onboardingState identifies an onboarding that has been started
+type onboardingState struct {
+	TierID    string
+	StartedAt time.Time
+}
+
+// ResumeOnboarding tells the client that an earlier run started onboarding with
+// the given tier, so polling continues with that tier and reports the total elapsed time
+func (c *Client) ResumeOnboarding(tierID string, startedAt time.Time) {
+	c.onboarding = onboardingState{TierID: tierID, StartedAt: startedAt}
+}
+
+// PendingOnboarding returns the tier and start time of an onboarding that was
+// started but has not completed. ok is false if there is none.
+func (c *Client) PendingOnboarding() (tierID string, startedAt time.Time, ok bool) {
+	if c.onboarding.TierID == "" {
+		return "", time.Time{}, false
+	}
+	return c.onboarding.TierID, c.onboarding.StartedAt, true
+}
+
+// OnboardUser onboards the user to the given tier and polls until the project is provisioned.
+// Polling backs off between attempts and stops at the onboarding deadline with an
+// ErrOnboardingPending error, or as soon as ctx is done. The onboarding can then be resumed by a later call.
+func (c *Client) OnboardUser(ctx context.Context, tierID string) (string, error) {
+	request := models.OnboardUserRequest{
+		TierID:   tierID,
+		Metadata: c.getMetadata(),
+	}
+
+	resumed := c.onboarding.TierID == tierID && !c.onboarding.StartedAt.IsZero()
+	if !resumed {
+		c.onboarding = onboardingState{TierID: tierID, StartedAt: time.Now()}
+	}
+
+	deadline := time.Now().Add(c.getOnboardingTimeout())
+	interval := c.getOnboardingInterval()
+
+	for attempt := 1; ; attempt++ {
+		responseData, err := c.doRequest(ctx, "POST", "/v1internal:onboardUser", request)
+		if err != nil {
+			return "", fmt.Errorf("onboardUser failed: %w", err)
+		}
+
+		var response models.OnboardUserResponse
+		if err := json.Unmarshal(responseData, &response); err != nil {
+			return "", fmt.Errorf("failed to parse onboard response: %w", err)
+		}
+
+		progress := OnboardingProgress{
+			TierID:  tierID,
+			Attempt: attempt,
+			Elapsed: time.Since(c.onboarding.StartedAt),
+			Resumed: resumed,
+		}
+
+		if response.Done {
+			c.onboarding = onboardingState{}
+			progress.Done = true
+			c.reportOnboarding(progress)
+			// The project ID may be empty if the server did not return one
+			return extractProjectId(response.Response.CloudAICompanionProject), nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return "", newOnboardingPendingError(tierID, attempt, nil)
+		}
+
+		wait := min(interval, remaining)
+		progress.NextPoll = wait
+		c.reportOnboarding(progress)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", newOnboardingPendingError(tierID, attempt, ctx.Err())
+		case <-timer.C:
+		}
+
+		interval = min(interval*2, c.getOnboardingMaxInterval())
+	}
+}
+
+// newOnboardingPendingError reports an onboarding that has not completed after the given number of polls
+func newOnboardingPendingError(tierID string, polls int, err error) *Error {
+	return &Error{
+		Code:      CodeOnboardingPending,
+		Message:   fmt.Sprintf("onboarding to %s still pending after %d polls", tierID, polls),
+		Retryable: true,
+		Err:       err,
+	}
+}
+
+func (c *Client) reportOnboarding(progress OnboardingProgress) {
+	if c.onboardingProgress != nil {
+		c.onboardingProgress(progress)
+	}
+}
+
+func (c *Client) getOnboardingTimeout() time.Duration {
+	if c.onboardingTimeout > 0 {
+		return c.onboardingTimeout
+	}
+	return DefaultOnboardingTimeout
+}
+
+func (c *Client) getOnboardingInterval() time.Duration {
+	if c.onboardingInterval > 0 {
+		return c.onboardingInterval
+	}
+	return DefaultOnboardingInterval
+}
+
+func (c *Client) getOnboardingMaxInterval() time.Duration {
+	if c.onboardingMaxInterval > 0 {
+		return max(c.onboardingMaxInterval, c.getOnboardingInterval())
+	}
+	return max(DefaultOnboardingMaxInterval, c.getOnboardingInterval())
+}
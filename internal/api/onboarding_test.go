@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/auth"
+	"github.com/gundamkid/anti-gravity-quota/internal/fakeserver"
+)
+
+func TestOnboardUser_Progress(t *testing.T) {
+	srv := setupFakeServer(t)
+	saveAccount(t, srv, fakeserver.NewUserAccount("progress@example.com", 3), false)
+
+	var updates []OnboardingProgress
+	client := NewClient(
+		WithBaseURL(srv.URL()),
+		WithOnboardingBackoff(5*time.Millisecond, 20*time.Millisecond),
+		WithOnboardingProgress(func(p OnboardingProgress) { updates = append(updates, p) }),
+	)
+
+	if _, err := client.GetQuotaInfoForAccount(context.Background(), "progress@example.com"); err != nil {
+		t.Fatalf("GetQuotaInfoForAccount failed: %v", err)
+	}
+
+	if len(updates) != 4 {
+		t.Fatalf("expected 3 pending updates and 1 done update, got %d", len(updates))
+	}
+	for i, p := range updates[:3] {
+		if p.Done || p.Attempt != i+1 || p.TierID != fakeserver.TierFree {
+			t.Errorf("unexpected update %d: %+v", i, p)
+		}
+	}
+	if updates[0].NextPoll != 5*time.Millisecond || updates[1].NextPoll != 10*time.Millisecond {
+		t.Errorf("expected the poll interval to double, got %v then %v", updates[0].NextPoll, updates[1].NextPoll)
+	}
+	if !updates[3].Done {
+		t.Errorf("expected the last update to be done: %+v", updates[3])
+	}
+}
+
+func TestOnboardUser_PendingAndResume(t *testing.T) {
+	srv := setupFakeServer(t)
+	saveAccount(t, srv, fakeserver.NewUserAccount("slowboard@example.com", 50), false)
+
+	client := NewClient(
+		WithBaseURL(srv.URL()),
+		WithOnboardingTimeout(30*time.Millisecond),
+		WithOnboardingBackoff(5*time.Millisecond, 5*time.Millisecond),
+	)
+	_, _, err := client.RefreshProjectMetadata(context.Background(), "slowboard@example.com")
+	if !errors.Is(err, ErrOnboardingPending) {
+		t.Fatalf("expected ErrOnboardingPending, got %v", err)
+	}
+	if ErrorCode(err) != CodeOnboardingPending {
+		t.Errorf("expected %s, got %s", CodeOnboardingPending, ErrorCode(err))
+	}
+
+	token, err := auth.LoadTokenForAccount("slowboard@example.com")
+	if err != nil {
+		t.Fatalf("LoadTokenForAccount failed: %v", err)
+	}
+	if token.OnboardingTierID != fakeserver.TierFree || token.OnboardingStartedAt.IsZero() {
+		t.Fatalf("pending onboarding not recorded: %+v", token)
+	}
+
+	// A later run resumes the pending onboarding and completes it
+	var resumed bool
+	client = NewClient(
+		WithBaseURL(srv.URL()),
+		WithOnboardingBackoff(time.Millisecond, time.Millisecond),
+		WithOnboardingProgress(func(p OnboardingProgress) { resumed = p.Resumed }),
+	)
+	projectID, _, err := client.RefreshProjectMetadata(context.Background(), "slowboard@example.com")
+	if err != nil {
+		t.Fatalf("RefreshProjectMetadata failed: %v", err)
+	}
+	if projectID != "onboarded-slowboard@example.com" {
+		t.Errorf("unexpected project: %s", projectID)
+	}
+	if !resumed {
+		t.Error("expected the onboarding to be reported as resumed")
+	}
+
+	token, err = auth.LoadTokenForAccount("slowboard@example.com")
+	if err != nil {
+		t.Fatalf("LoadTokenForAccount failed: %v", err)
+	}
+	if token.OnboardingTierID != "" || token.ProjectID != projectID {
+		t.Errorf("expected the pending onboarding to be replaced by the project: %+v", token)
+	}
+}
+
+func TestOnboardUser_ContextCancelled(t *testing.T) {
+	srv := setupFakeServer(t)
+	saveAccount(t, srv, fakeserver.NewUserAccount("cancel@example.com", 10), false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewClient(
+		WithBaseURL(srv.URL()),
+		WithOnboardingBackoff(time.Hour, time.Hour),
+		WithOnboardingProgress(func(OnboardingProgress) { cancel() }),
+		WithTokenSource(auth.NewAccountTokenSource("cancel@example.com", auth.GetOAuthConfig())),
+	)
+
+	start := time.Now()
+	_, err := client.OnboardUser(ctx, fakeserver.TierFree)
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("poller did not stop on cancellation")
+	}
+	if !errors.Is(err, ErrOnboardingPending) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a pending error caused by cancellation, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/models"
+)
+
+// DefaultTimeout is the HTTP timeout used when none is configured
+const DefaultTimeout = 30 * time.Second
+
+// Option configures a Client created with NewClient
+type Option func(*Client)
+
+// WithBaseURL sends all requests to the given endpoint instead of the
+// default Cloud Code endpoints. Failover to the backup endpoint is disabled.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient uses the given HTTP client for all requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTransport uses the given RoundTripper for all requests, e.g. a proxy-aware or recording transport
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
+// WithTimeout sets the timeout of each HTTP request
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithUserAgent overrides the User-Agent header sent with each request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithMetadata overrides the client metadata sent to loadCodeAssist and onboardUser
+func WithMetadata(metadata models.Metadata) Option {
+	return func(c *Client) {
+		c.metadata = &metadata
+	}
+}
+
+// WithTokenSource binds the client to a token source
+func WithTokenSource(ts TokenSource) Option {
+	return func(c *Client) {
+		c.tokenSource = ts
+	}
+}
+
+// WithOnboardingTimeout sets how long OnboardUser polls before giving up with ErrOnboardingPending
+func WithOnboardingTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.onboardingTimeout = timeout
+	}
+}
+
+// WithOnboardingBackoff sets the first interval between onboarding polls and the
+// cap the interval doubles up to
+func WithOnboardingBackoff(initial, max time.Duration) Option {
+	return func(c *Client) {
+		c.onboardingInterval = initial
+		c.onboardingMaxInterval = max
+	}
+}
+
+// WithOnboardingProgress reports the progress of onboarding polls to fn, e.g. to show it in the UI
+func WithOnboardingProgress(fn func(OnboardingProgress)) Option {
+	return func(c *Client) {
+		c.onboardingProgress = fn
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/models"
+)
+
+// recordingTransport records requests before passing them on
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewClient_Options(t *testing.T) {
+	var gotUserAgent string
+	var gotRequest models.LoadCodeAssistRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserAgent = r.Header.Get("User-Agent")
+		_ = json.NewDecoder(r.Body).Decode(&gotRequest)
+		_, _ = w.Write([]byte(`{"projectId": "local-project"}`))
+	}))
+	defer server.Close()
+
+	transport := &recordingTransport{}
+	metadata := models.Metadata{IDEType: "TEST", Platform: "LINUX", PluginType: "GEMINI"}
+
+	client := NewClient(
+		WithBaseURL(server.URL+"/"),
+		WithTransport(transport),
+		WithTimeout(5*time.Second),
+		WithUserAgent("ag-quota-test"),
+		WithMetadata(metadata),
+		WithTokenSource(&fakeTokenSource{token: "fake-token"}),
+	)
+
+	resp, err := client.LoadCodeAssist(context.Background())
+	if err != nil {
+		t.Fatalf("LoadCodeAssist failed: %v", err)
+	}
+
+	if resp.ProjectID != "local-project" {
+		t.Errorf("expected local-project, got %s", resp.ProjectID)
+	}
+	if len(transport.requests) != 1 {
+		t.Errorf("expected request through custom transport, got %d", len(transport.requests))
+	}
+	if gotUserAgent != "ag-quota-test" {
+		t.Errorf("expected custom User-Agent, got %s", gotUserAgent)
+	}
+	if gotRequest.Metadata != metadata {
+		t.Errorf("expected custom metadata, got %+v", gotRequest.Metadata)
+	}
+	if client.Endpoint() != server.URL {
+		t.Errorf("expected endpoint %s, got %s", server.URL, client.Endpoint())
+	}
+	if client.httpClient.Timeout != 5*time.Second {
+		t.Errorf("expected 5s timeout, got %v", client.httpClient.Timeout)
+	}
+}
+
+func TestNewClient_HTTPClientNotMutated(t *testing.T) {
+	httpClient := &http.Client{Timeout: time.Minute}
+
+	client := NewClient(WithHTTPClient(httpClient), WithTimeout(time.Second))
+
+	if httpClient.Timeout != time.Minute {
+		t.Errorf("caller's http.Client was modified")
+	}
+	if client.httpClient.Timeout != time.Second {
+		t.Errorf("expected 1s timeout, got %v", client.httpClient.Timeout)
+	}
+}
+
+func TestNewClient_Defaults(t *testing.T) {
+	client := NewClient()
+
+	if client.baseURL != BaseURL {
+		t.Errorf("expected %s, got %s", BaseURL, client.baseURL)
+	}
+	if client.httpClient.Timeout != DefaultTimeout {
+		t.Errorf("expected default timeout, got %v", client.httpClient.Timeout)
+	}
+	if client.getUserAgent() != UserAgent {
+		t.Errorf("expected default User-Agent, got %s", client.getUserAgent())
+	}
+}
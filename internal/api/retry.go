@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxRetryBudget caps the total time a single call may spend waiting between retries
+	MaxRetryBudget = 30 * time.Second
+
+	// retryInfoType is the type URL of the google.rpc.RetryInfo error detail
+	retryInfoType = "type.googleapis.com/google.rpc.RetryInfo"
+)
+
+// RetryError is returned when a request keeps failing with a retryable error.
+// It reports how long the client waited (or would have to wait) before the next attempt.
+type RetryError struct {
+	// Endpoint is the base URL the request was sent to
+	Endpoint string
+	// StatusCode is the HTTP status of the last attempt, 0 for network errors
+	StatusCode int
+	// Attempts is the number of attempts made
+	Attempts int
+	// Delay is the last delay chosen before retrying. When the budget ran out,
+	// it is the delay that would have been needed for the next attempt.
+	Delay time.Duration
+	// FromServer reports whether Delay came from a Retry-After header or RetryInfo detail
+	FromServer bool
+	// BudgetExceeded reports whether the client gave up because of MaxRetryBudget
+	BudgetExceeded bool
+	// Err is the error of the last attempt
+	Err error
+}
+
+func (e *RetryError) Error() string {
+	reason := fmt.Sprintf("failed after %d attempts", e.Attempts)
+	if e.BudgetExceeded {
+		reason = fmt.Sprintf("gave up after %d attempts, retry budget exhausted", e.Attempts)
+	}
+	msg := fmt.Sprintf("request to %s %s: %v", e.Endpoint, reason, e.Err)
+	if e.Delay > 0 {
+		msg += fmt.Sprintf(" (retry after %s)", e.Delay.Round(time.Millisecond))
+	}
+	return msg
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// backoffDelay returns the exponential backoff delay for a retry attempt (1-based) with equal jitter
+func backoffDelay(base time.Duration, attempt int) time.Duration {
+	delay := base * time.Duration(1<<uint(attempt-1))
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half+1)
+}
+
+// withJitter adds up to 10% on top of a server-provided delay so that
+// parallel clients don't all retry at the same instant
+func withJitter(delay time.Duration) time.Duration {
+	spread := delay / 10
+	if spread <= 0 {
+		return delay
+	}
+	return delay + rand.N(spread+1)
+}
+
+// serverRetryDelay extracts a retry hint from the Retry-After header or from
+// the google.rpc.RetryInfo detail in the error body
+func serverRetryDelay(header http.Header, body []byte, now time.Time) (time.Duration, bool) {
+	if delay, ok := parseRetryAfter(header.Get("Retry-After"), now); ok {
+		return delay, true
+	}
+	return parseRetryInfo(body)
+}
+
+// parseRetryAfter parses a Retry-After header value in seconds or HTTP date form
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// parseRetryInfo extracts the retryDelay of a google.rpc.RetryInfo detail
+func parseRetryInfo(body []byte) (time.Duration, bool) {
+	status, ok := parseRPCStatus(body)
+	if !ok {
+		return 0, false
+	}
+
+	for _, detail := range status.Details {
+		if detail["@type"] != retryInfoType {
+			continue
+		}
+		retryDelay, _ := detail["retryDelay"].(string)
+		if retryDelay == "" {
+			continue
+		}
+		// Durations are encoded as decimal seconds with an "s" suffix, e.g. "3.5s"
+		delay, err := time.ParseDuration(retryDelay)
+		if err != nil || delay < 0 {
+			continue
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
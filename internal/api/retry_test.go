@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"Seconds", "120", 2 * time.Minute, true},
+		{"HTTP date", now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{"Date in the past", now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"Empty", "", 0, false},
+		{"Negative", "-5", 0, false},
+		{"Garbage", "soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if ok != tt.ok || got != tt.expected {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseRetryInfo(t *testing.T) {
+	body := []byte(`{
+		"error": {
+			"code": 429,
+			"status": "RESOURCE_EXHAUSTED",
+			"details": [
+				{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "RATE_LIMIT_EXCEEDED"},
+				{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "3.5s"}
+			]
+		}
+	}`)
+
+	got, ok := parseRetryInfo(body)
+	if !ok || got != 3500*time.Millisecond {
+		t.Errorf("expected 3.5s, got %v (ok=%v)", got, ok)
+	}
+
+	if _, ok := parseRetryInfo([]byte(`{"error": {"code": 429}}`)); ok {
+		t.Error("expected no retry info")
+	}
+	if _, ok := parseRetryInfo([]byte(`not json`)); ok {
+		t.Error("expected no retry info for invalid body")
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	for attempt := 1; attempt <= 3; attempt++ {
+		full := time.Second * time.Duration(1<<uint(attempt-1))
+		for i := 0; i < 20; i++ {
+			got := backoffDelay(time.Second, attempt)
+			if got < full/2 || got > full {
+				t.Fatalf("attempt %d: delay %v outside [%v, %v]", attempt, got, full/2, full)
+			}
+		}
+	}
+}
+
+func TestClient_doRequestRetryAfterBudget(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &Client{
+		httpClient: server.Client(),
+		baseURL:    server.URL,
+	}
+
+	_, err := client.doRequest(context.Background(), "GET", "/test", nil)
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("expected RetryError, got %v", err)
+	}
+	if !retryErr.BudgetExceeded || !retryErr.FromServer {
+		t.Errorf("expected server-driven budget exhaustion, got %+v", retryErr)
+	}
+	if retryErr.Delay < 60*time.Second {
+		t.Errorf("expected delay of at least 60s, got %v", retryErr.Delay)
+	}
+	if retryErr.StatusCode != http.StatusTooManyRequests || hits != 1 {
+		t.Errorf("expected a single 429 attempt, got status %d after %d hits", retryErr.StatusCode, hits)
+	}
+}
+
+func TestClient_doRequestRetryInfo(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error": {"code": 429, "details": [{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "0.01s"}]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer server.Close()
+
+	client := &Client{
+		httpClient: server.Client(),
+		baseURL:    server.URL,
+		retryDelay: time.Hour, // would exceed the budget if the hint were ignored
+	}
+
+	data, err := client.doRequest(context.Background(), "GET", "/test", nil)
+	if err != nil {
+		t.Fatalf("doRequest failed: %v", err)
+	}
+	if string(data) != `{"status": "ok"}` || hits != 2 {
+		t.Errorf("unexpected response %s after %d hits", string(data), hits)
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+	"github.com/gundamkid/anti-gravity-quota/internal/models"
+)
+
+var (
+	tierRegistryOnce sync.Once
+	tierRegistry     *models.TierRegistry
+	// tierRegistryMu serializes writes of the registry file
+	tierRegistryMu sync.Mutex
+)
+
+// sharedTierRegistry returns the registry of tiers seen in loadCodeAssist responses.
+// It is persisted so cached tier IDs can still be shown with the server's metadata.
+func sharedTierRegistry() *models.TierRegistry {
+	tierRegistryOnce.Do(func() {
+		tierRegistry = models.NewTierRegistry(loadTiers()...)
+	})
+	return tierRegistry
+}
+
+// LookupTier returns the display information of a tier ID
+func LookupTier(tierID string) models.TierInfo {
+	return sharedTierRegistry().Lookup(tierID)
+}
+
+// registerTiers records the tiers of a loadCodeAssist response and persists the registry if it changed
+func registerTiers(resp *models.LoadCodeAssistResponse) {
+	registry := sharedTierRegistry()
+
+	changed := registry.Register(resp.PaidTier)
+	changed = registry.Register(resp.CurrentTier) || changed
+	for i := range resp.AllowedTiers {
+		changed = registry.Register(&resp.AllowedTiers[i]) || changed
+	}
+
+	if changed {
+		saveTiers(registry.Tiers())
+	}
+}
+
+// loadTiers reads the persisted tiers. Errors are ignored, the registry simply starts empty.
+func loadTiers() []models.Tier {
+	path, err := config.GetTierRegistryPath()
+	if err != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var tiers []models.Tier
+	if err := json.Unmarshal(data, &tiers); err != nil {
+		return nil
+	}
+	return tiers
+}
+
+// saveTiers persists the tiers. Errors are ignored, the registry is only a cache.
+func saveTiers(tiers []models.Tier) {
+	tierRegistryMu.Lock()
+	defer tierRegistryMu.Unlock()
+
+	path, err := config.GetTierRegistryPath()
+	if err != nil {
+		return
+	}
+	if _, err := config.EnsureConfigDir(); err != nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(tiers, "", "  ")
+	if err != nil {
+		return
+	}
+
+	_ = config.AtomicWrite(path, data, 0600)
+}
@@ -14,6 +14,16 @@ import (
 var (
 	// ErrAccountNotFound is returned when an account cannot be found
 	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrNotLoggedIn is returned when no account is saved
+	ErrNotLoggedIn = errors.New("not logged in")
+
+	// ErrNoDefaultAccount is returned when several accounts are saved but none is the default
+	ErrNoDefaultAccount = errors.New("no default account set")
+
+	// ErrDefaultNotPromoted is returned by RemoveAccount when the account was removed
+	// but no other account could be made the default
+	ErrDefaultNotPromoted = errors.New("no new default account was set")
 )
 
 // AccountInfo represents metadata about a saved account
@@ -23,12 +33,24 @@ type AccountInfo struct {
 	IsDefault  bool      `json:"is_default"`
 	LastUsed   time.Time `json:"last_used"`
 	TokenValid bool      `json:"token_valid"`
+	Alias      string    `json:"alias,omitempty"`
+	Labels     []string  `json:"labels,omitempty"`
+	Notes      string    `json:"notes,omitempty"`
+	Color      string    `json:"color,omitempty"`
+
+	LastSuccess   time.Time `json:"last_success"`
+	LastError     time.Time `json:"last_error"`
+	LastErrorText string    `json:"last_error_text,omitempty"`
 }
 
 // AccountManager handles all account-related operations
 type AccountManager struct {
 	accountsDir string
 	configPath  string
+
+	// ChooseDefault picks the new default account when the default is removed
+	// and several accounts could replace it. Nil picks the first candidate.
+	ChooseDefault func(candidates []string) (string, error)
 }
 
 // NewAccountManager creates a new instance of AccountManager
@@ -51,9 +73,9 @@ func NewAccountManager() (*AccountManager, error) {
 
 // ListAccounts returns a list of all saved accounts
 func (m *AccountManager) ListAccounts() ([]AccountInfo, error) {
-	entries, err := os.ReadDir(m.accountsDir)
+	emails, err := listAccountEmails(m.accountsDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read accounts directory: %w", err)
+		return nil, err
 	}
 
 	appCfg, err := m.LoadConfig()
@@ -61,28 +83,37 @@ func (m *AccountManager) ListAccounts() ([]AccountInfo, error) {
 		return nil, err
 	}
 
-	var accounts []AccountInfo
-	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
-			continue
-		}
+	defaultID, _ := config.CanonicalAccountID(appCfg.DefaultAccount)
 
-		email := entry.Name()[:len(entry.Name())-len(".json")]
+	activity, err := LoadAccountActivity()
+	if err != nil {
+		return nil, err
+	}
 
+	var accounts []AccountInfo
+	for _, email := range emails {
 		// Load token to check validity and get tier
 		token, err := LoadTokenForAccount(email)
-		valid := err == nil && token.IsValid()
-		tier := ""
+		info := AccountInfo{
+			Email:      email,
+			IsDefault:  email == defaultID,
+			TokenValid: err == nil && token.IsValid(),
+		}
 		if err == nil {
-			tier = token.TierName
+			info.TierName = token.TierName
+			info.Alias = token.Metadata.Alias
+			info.Labels = token.Metadata.Labels
+			info.Notes = token.Metadata.Notes
+			info.Color = token.Metadata.Color
 		}
 
-		accounts = append(accounts, AccountInfo{
-			Email:      email,
-			TierName:   tier,
-			IsDefault:  email == appCfg.DefaultAccount,
-			TokenValid: valid,
-		})
+		recorded := activity[email]
+		info.LastUsed = recorded.LastUsed
+		info.LastSuccess = recorded.LastSuccess
+		info.LastError = recorded.LastError
+		info.LastErrorText = recorded.LastErrorText
+
+		accounts = append(accounts, info)
 	}
 
 	// Sort: default first, then alphabetical
@@ -99,6 +130,64 @@ func (m *AccountManager) ListAccounts() ([]AccountInfo, error) {
 	return accounts, nil
 }
 
+// listAccountEmails returns the account IDs of the account files in dir. Names
+// are mapped back through the account index, falling back to decoding the file
+// name; files that do not belong to a canonical account ID are ignored until
+// MigrateAccountIDsIfNeeded renames them.
+func listAccountEmails(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accounts directory: %w", err)
+	}
+
+	index, err := config.LoadAccountIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		id, ok := index.Accounts[entry.Name()]
+		if !ok {
+			if id, err = config.AccountIDFromFileName(entry.Name()); err != nil {
+				continue
+			}
+		}
+		if canonical, err := config.CanonicalAccountID(id); err != nil || canonical != id || config.AccountFileName(id) != entry.Name() {
+			continue
+		}
+		emails = append(emails, id)
+	}
+	return emails, nil
+}
+
+// indexAccount records the file name of an account in the account index
+func indexAccount(id string) error {
+	name := config.AccountFileName(id)
+	return config.UpdateAccountIndex(func(index *config.AccountIndex) bool {
+		if index.Accounts[name] == id {
+			return false
+		}
+		index.Accounts[name] = id
+		return true
+	})
+}
+
+// unindexAccount removes an account from the account index
+func unindexAccount(id string) error {
+	name := config.AccountFileName(id)
+	return config.UpdateAccountIndex(func(index *config.AccountIndex) bool {
+		if _, ok := index.Accounts[name]; !ok {
+			return false
+		}
+		delete(index.Accounts, name)
+		return true
+	})
+}
+
 // LoadConfig loads the application config
 func (m *AccountManager) LoadConfig() (*config.Config, error) {
 	return config.LoadConfig()
@@ -109,8 +198,29 @@ func (m *AccountManager) SaveConfig(cfg *config.Config) error {
 	return config.SaveConfig(cfg)
 }
 
-// SetDefaultAccount sets the default account email
+// UpdateConfig changes the application config under the config lock
+func (m *AccountManager) UpdateConfig(fn func(cfg *config.Config) error) error {
+	return config.UpdateConfig(fn)
+}
+
+// HasAccount reports whether a token file exists for the account. Invalid emails
+// have no account.
+func (m *AccountManager) HasAccount(email string) bool {
+	path, err := config.GetAccountPath(email)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// SetDefaultAccount sets the default account, stored as its canonical ID
 func (m *AccountManager) SetDefaultAccount(email string) error {
+	email, err := config.CanonicalAccountID(email)
+	if err != nil {
+		return err
+	}
+
 	// Verify account exists
 	path, err := config.GetAccountPath(email)
 	if err != nil {
@@ -123,39 +233,141 @@ func (m *AccountManager) SetDefaultAccount(email string) error {
 		return errStat
 	}
 
+	return m.UpdateConfig(func(cfg *config.Config) error {
+		cfg.DefaultAccount = email
+		return nil
+	})
+}
+
+// DefaultAccount returns the email of the default account. A default that
+// points to a removed account counts as unset; when no default is set and only
+// one account is saved, that account becomes the default.
+func (m *AccountManager) DefaultAccount() (string, error) {
 	cfg, err := m.LoadConfig()
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	if id, err := config.CanonicalAccountID(cfg.DefaultAccount); err == nil && m.HasAccount(id) {
+		return id, nil
+	}
+
+	emails, err := listAccountEmails(m.accountsDir)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(emails) {
+	case 0:
+		return "", ErrNotLoggedIn
+	case 1:
+		if err := m.SetDefaultAccount(emails[0]); err != nil {
+			return "", err
+		}
+		return emails[0], nil
+	default:
+		return "", fmt.Errorf("%w: %d accounts are saved, choose one with 'ag-quota accounts default <email>'", ErrNoDefaultAccount, len(emails))
+	}
+}
+
+// PromoteDefaultAccount makes another account the default if the current default
+// is unset or was removed. Accounts whose token can still be used or refreshed are
+// preferred; ChooseDefault picks among several. It returns the new default, or ""
+// if no account is left.
+func (m *AccountManager) PromoteDefaultAccount() (string, error) {
+	cfg, err := m.LoadConfig()
+	if err != nil {
+		return "", err
+	}
+	if id, err := config.CanonicalAccountID(cfg.DefaultAccount); err == nil && m.HasAccount(id) {
+		return id, nil
 	}
 
-	cfg.DefaultAccount = email
-	return m.SaveConfig(cfg)
+	emails, err := listAccountEmails(m.accountsDir)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []string
+	for _, email := range emails {
+		if token, errLoad := LoadTokenForAccount(email); errLoad == nil && (token.IsValid() || token.RefreshToken != "") {
+			candidates = append(candidates, email)
+		}
+	}
+	if len(candidates) == 0 {
+		// No usable token left, fall back to any saved account
+		candidates = emails
+	}
+	sort.Strings(candidates)
+
+	next := ""
+	switch {
+	case len(candidates) == 0:
+	case len(candidates) == 1 || m.ChooseDefault == nil:
+		next = candidates[0]
+	default:
+		if next, err = m.ChooseDefault(candidates); err != nil {
+			return "", err
+		}
+	}
+
+	err = m.UpdateConfig(func(cfg *config.Config) error {
+		// Another process may have chosen a default in the meantime
+		if id, err := config.CanonicalAccountID(cfg.DefaultAccount); err == nil && m.HasAccount(id) {
+			next = id
+			return nil
+		}
+		cfg.DefaultAccount = next
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return next, nil
 }
 
-// RemoveAccount deletes an account's token and clears it from config if it was default
+// RemoveAccount deletes an account's token. If it was the default account,
+// another account is promoted with PromoteDefaultAccount. An error wrapping
+// ErrDefaultNotPromoted means the account is gone but no default is set.
 func (m *AccountManager) RemoveAccount(email string) error {
-	path, err := config.GetAccountPath(email)
+	email, err := config.CanonicalAccountID(email)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockAccount(email)
 	if err != nil {
 		return err
 	}
+	errRemove := removeTokenFile(email)
+	unlock()
 
 	// Remove token file
-	if errRemove := os.Remove(path); errRemove != nil {
+	if errRemove != nil {
 		if os.IsNotExist(errRemove) {
 			return fmt.Errorf("%w: %s", ErrAccountNotFound, email)
 		}
 		return fmt.Errorf("failed to delete account token: %w", errRemove)
 	}
+	_ = forgetAccountActivity(email)
 
 	// Update config if it was the default account
-	cfg, err := m.LoadConfig()
+	wasDefault := false
+	err = m.UpdateConfig(func(cfg *config.Config) error {
+		if defaultID, _ := config.CanonicalAccountID(cfg.DefaultAccount); defaultID == email {
+			cfg.DefaultAccount = ""
+			wasDefault = true
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	if cfg.DefaultAccount == email {
-		cfg.DefaultAccount = ""
-		return m.SaveConfig(cfg)
+	if wasDefault {
+		if _, err := m.PromoteDefaultAccount(); err != nil {
+			return fmt.Errorf("%w: %w", ErrDefaultNotPromoted, err)
+		}
 	}
 
 	return nil
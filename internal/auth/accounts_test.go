@@ -1,8 +1,10 @@
 package auth
 
 import (
+	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gundamkid/anti-gravity-quota/internal/config"
 )
@@ -69,3 +71,185 @@ func TestAccountManager(t *testing.T) {
 		}
 	})
 }
+
+func TestDefaultAccount_NoDefault(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	mgr, err := NewAccountManager()
+	if err != nil {
+		t.Fatalf("Failed to create AccountManager: %v", err)
+	}
+
+	if _, err := mgr.DefaultAccount(); !errors.Is(err, ErrNotLoggedIn) {
+		t.Errorf("expected ErrNotLoggedIn without accounts, got %v", err)
+	}
+
+	// A single account is used as the default even if none is configured
+	if err := SaveTokenForAccount("only@example.com", &TokenData{AccessToken: "a"}); err != nil {
+		t.Fatalf("SaveTokenForAccount failed: %v", err)
+	}
+	email, err := mgr.DefaultAccount()
+	if err != nil || email != "only@example.com" {
+		t.Errorf("expected only@example.com, got %q (%v)", email, err)
+	}
+
+	// With several accounts and a stale default, the user has to choose
+	if err := SaveTokenForAccount("other@example.com", &TokenData{AccessToken: "b"}); err != nil {
+		t.Fatalf("SaveTokenForAccount failed: %v", err)
+	}
+	cfg, _ := mgr.LoadConfig()
+	cfg.DefaultAccount = "gone@example.com"
+	if err := mgr.SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	if _, err := LoadToken(); !errors.Is(err, ErrNoDefaultAccount) {
+		t.Errorf("expected ErrNoDefaultAccount, got %v", err)
+	}
+}
+
+func TestRemoveAccount_PromotesDefault(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	mgr, err := NewAccountManager()
+	if err != nil {
+		t.Fatalf("Failed to create AccountManager: %v", err)
+	}
+
+	valid := &TokenData{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if err := SaveTokenForAccount(email, valid); err != nil {
+			t.Fatalf("SaveTokenForAccount failed: %v", err)
+		}
+	}
+	// An account that can no longer be refreshed is not promoted
+	if err := SaveTokenForAccount("b@example.com", &TokenData{AccessToken: "x", Expiry: time.Now().Add(-time.Hour)}); err != nil {
+		t.Fatalf("SaveTokenForAccount failed: %v", err)
+	}
+	if err := mgr.SetDefaultAccount("a@example.com"); err != nil {
+		t.Fatalf("SetDefaultAccount failed: %v", err)
+	}
+
+	var offered []string
+	mgr.ChooseDefault = func(candidates []string) (string, error) {
+		offered = candidates
+		return candidates[len(candidates)-1], nil
+	}
+
+	if err := mgr.RemoveAccount("a@example.com"); err != nil {
+		t.Fatalf("RemoveAccount failed: %v", err)
+	}
+
+	// Only c is usable, so it is promoted without asking
+	if offered != nil {
+		t.Errorf("expected no prompt with a single candidate, got %v", offered)
+	}
+	cfg, _ := mgr.LoadConfig()
+	if cfg.DefaultAccount != "c@example.com" {
+		t.Errorf("expected c@example.com to become the default, got %q", cfg.DefaultAccount)
+	}
+
+	// Removing the rest leaves no default behind
+	for _, email := range []string{"c@example.com", "b@example.com"} {
+		if err := mgr.RemoveAccount(email); err != nil {
+			t.Fatalf("RemoveAccount failed: %v", err)
+		}
+	}
+	cfg, _ = mgr.LoadConfig()
+	if cfg.DefaultAccount != "" {
+		t.Errorf("expected no default account, got %q", cfg.DefaultAccount)
+	}
+}
+
+func TestRemoveAccount_PromotionFails(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	mgr, err := NewAccountManager()
+	if err != nil {
+		t.Fatalf("Failed to create AccountManager: %v", err)
+	}
+
+	valid := &TokenData{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if err := SaveTokenForAccount(email, valid); err != nil {
+			t.Fatalf("SaveTokenForAccount failed: %v", err)
+		}
+	}
+	if err := mgr.SetDefaultAccount("a@example.com"); err != nil {
+		t.Fatalf("SetDefaultAccount failed: %v", err)
+	}
+
+	mgr.ChooseDefault = func(candidates []string) (string, error) {
+		return "", errors.New("invalid choice")
+	}
+
+	err = mgr.RemoveAccount("a@example.com")
+	if !errors.Is(err, ErrDefaultNotPromoted) {
+		t.Fatalf("expected ErrDefaultNotPromoted, got %v", err)
+	}
+	if mgr.HasAccount("a@example.com") {
+		t.Error("the account must be removed even though no default was set")
+	}
+	cfg, _ := mgr.LoadConfig()
+	if cfg.DefaultAccount != "" {
+		t.Errorf("expected no default account, got %q", cfg.DefaultAccount)
+	}
+}
+
+func TestAccountManager_CanonicalIDs(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	if err := SaveTokenForAccount(" Carol@Example.COM", &TokenData{AccessToken: "a", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("SaveTokenForAccount failed: %v", err)
+	}
+
+	mgr, err := NewAccountManager()
+	if err != nil {
+		t.Fatalf("NewAccountManager failed: %v", err)
+	}
+
+	// Every spelling of the email names the same account
+	if !mgr.HasAccount("carol@example.com") || !mgr.HasAccount("CAROL@example.com") {
+		t.Error("expected the account to be found under any case")
+	}
+	if err := mgr.SetDefaultAccount("Carol@Example.com"); err != nil {
+		t.Fatalf("SetDefaultAccount failed: %v", err)
+	}
+	if email, err := mgr.ResolveAccount("CAROL@EXAMPLE.COM"); err != nil || email != "carol@example.com" {
+		t.Errorf("ResolveAccount = %q, %v; want carol@example.com", email, err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.DefaultAccount != "carol@example.com" {
+		t.Errorf("expected the canonical default, got %q", cfg.DefaultAccount)
+	}
+
+	accounts, err := mgr.ListAccounts()
+	if err != nil {
+		t.Fatalf("ListAccounts failed: %v", err)
+	}
+	if len(accounts) != 1 || accounts[0].Email != "carol@example.com" || !accounts[0].IsDefault {
+		t.Errorf("unexpected accounts: %+v", accounts)
+	}
+
+	// Crafted identities never reach the file system
+	if err := SaveTokenForAccount("../../x@example.com", &TokenData{}); !errors.Is(err, config.ErrInvalidAccountID) {
+		t.Errorf("expected ErrInvalidAccountID, got %v", err)
+	}
+	if mgr.HasAccount("../carol@example.com") {
+		t.Error("a path must not resolve to an account")
+	}
+
+	if err := mgr.RemoveAccount("CAROL@example.com"); err != nil {
+		t.Fatalf("RemoveAccount failed: %v", err)
+	}
+	index, err := config.LoadAccountIndex()
+	if err != nil {
+		t.Fatalf("LoadAccountIndex failed: %v", err)
+	}
+	if len(index.Accounts) != 0 {
+		t.Errorf("expected the account to be removed from the index, got %v", index.Accounts)
+	}
+}
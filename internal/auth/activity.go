@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+)
+
+const (
+	// maxErrorTextLength bounds the error text kept per account
+	maxErrorTextLength = 500
+
+	// ActivityWriteInterval is how long a repeated outcome of using an account
+	// is not written again, so that frequent fetches in watch mode do not
+	// rewrite the activity file every time
+	ActivityWriteInterval = time.Minute
+)
+
+// activityMu serializes updates of the activity file within the process; the
+// file lock covers other processes
+var activityMu sync.Mutex
+
+// AccountActivity records when an account was last used by a quota fetch,
+// token refresh or login, and how that went
+type AccountActivity struct {
+	LastUsed      time.Time `json:"last_used,omitzero"`
+	LastSuccess   time.Time `json:"last_success,omitzero"`
+	LastError     time.Time `json:"last_error,omitzero"`
+	LastErrorText string    `json:"last_error_text,omitempty"`
+}
+
+// Record notes a use of the account at now, successful if err is nil
+func (a *AccountActivity) Record(now time.Time, err error) {
+	a.LastUsed = now
+	if err == nil {
+		a.LastSuccess = now
+		return
+	}
+
+	a.LastError = now
+	a.LastErrorText = err.Error()
+	if text := []rune(a.LastErrorText); len(text) > maxErrorTextLength {
+		a.LastErrorText = string(text[:maxErrorTextLength]) + "…"
+	}
+}
+
+// Failing reports whether the last use of the account failed
+func (a AccountActivity) Failing() bool {
+	return !a.LastError.IsZero() && !a.LastError.Before(a.LastSuccess)
+}
+
+// RecordAccountActivity saves the outcome of using an account, e.g. a quota fetch.
+// Activity is kept in its own plaintext file rather than the token file, so
+// recording it neither rewrites the token nor waits for the account lock.
+func RecordAccountActivity(email string, err error) error {
+	return recordAccountActivity(email, time.Now(), err)
+}
+
+func recordAccountActivity(email string, now time.Time, err error) error {
+	id, errID := config.CanonicalAccountID(email)
+	if errID != nil {
+		return errID
+	}
+
+	path, errPath := config.GetAccountPath(id)
+	if errPath != nil {
+		return errPath
+	}
+	if _, errStat := os.Stat(path); errStat != nil {
+		if os.IsNotExist(errStat) {
+			return fmt.Errorf("%w: %s", ErrAccountNotFound, id)
+		}
+		return errStat
+	}
+
+	activityMu.Lock()
+	defer activityMu.Unlock()
+
+	// Most fetches repeat the last outcome; skip those without taking the lock
+	if activity, errLoad := loadActivity(); errLoad == nil && !activityChanged(activity[id], now, err) {
+		return nil
+	}
+
+	lock, errLock := config.LockFile(config.ActivityFileName, config.ConfigLockTimeout)
+	if errLock != nil {
+		return errLock
+	}
+	defer lock.Release()
+
+	activity, errLoad := loadActivity()
+	if errLoad != nil {
+		return errLoad
+	}
+	if !activityChanged(activity[id], now, err) {
+		return nil
+	}
+
+	entry := activity[id]
+	entry.Record(now, err)
+	activity[id] = entry
+	return saveActivity(activity)
+}
+
+// activityChanged reports whether recording err at now changes what is known
+// about the account by more than a repeat within ActivityWriteInterval
+func activityChanged(prev AccountActivity, now time.Time, err error) bool {
+	if prev.LastUsed.IsZero() || now.Sub(prev.LastUsed) >= ActivityWriteInterval {
+		return true
+	}
+
+	next := prev
+	next.Record(now, err)
+	return next.Failing() != prev.Failing() || next.LastErrorText != prev.LastErrorText
+}
+
+// LoadAccountActivity returns the recorded activity by account ID
+func LoadAccountActivity() (map[string]AccountActivity, error) {
+	activityMu.Lock()
+	defer activityMu.Unlock()
+
+	return loadActivity()
+}
+
+// forgetAccountActivity drops the recorded activity of a removed account
+func forgetAccountActivity(id string) error {
+	activityMu.Lock()
+	defer activityMu.Unlock()
+
+	lock, err := config.LockFile(config.ActivityFileName, config.ConfigLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	activity, err := loadActivity()
+	if err != nil {
+		return err
+	}
+	if _, ok := activity[id]; !ok {
+		return nil
+	}
+	delete(activity, id)
+	return saveActivity(activity)
+}
+
+func loadActivity() (map[string]AccountActivity, error) {
+	activity := make(map[string]AccountActivity)
+
+	path, err := config.GetActivityPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return activity, nil
+		}
+		return nil, fmt.Errorf("failed to read activity file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &activity); err != nil {
+		return nil, fmt.Errorf("failed to parse activity file: %w", err)
+	}
+	if activity == nil {
+		activity = make(map[string]AccountActivity)
+	}
+	return activity, nil
+}
+
+func saveActivity(activity map[string]AccountActivity) error {
+	path, err := config.GetActivityPath()
+	if err != nil {
+		return err
+	}
+	if _, err := config.EnsureConfigDir(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(activity, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal activity: %w", err)
+	}
+	return config.AtomicWrite(path, data, 0600)
+}
+
+// UnusedAccounts returns the accounts not used since before now minus unusedFor.
+// Accounts without recorded activity, e.g. saved by an older version, are judged
+// by when their token file was last written.
+func (m *AccountManager) UnusedAccounts(unusedFor time.Duration, now time.Time) ([]AccountInfo, error) {
+	accounts, err := m.ListAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := now.Add(-unusedFor)
+	var unused []AccountInfo
+	for _, acc := range accounts {
+		lastUsed := acc.LastUsed
+		if lastUsed.IsZero() {
+			path, err := config.GetAccountPath(acc.Email)
+			if err != nil {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			lastUsed = info.ModTime()
+		}
+		if lastUsed.Before(cutoff) {
+			unused = append(unused, acc)
+		}
+	}
+	return unused, nil
+}
+
+// Failing reports whether the last use of the account failed
+func (a AccountInfo) Failing() bool {
+	return AccountActivity{LastSuccess: a.LastSuccess, LastError: a.LastError}.Failing()
+}
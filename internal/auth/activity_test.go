@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountActivity_Record(t *testing.T) {
+	var activity AccountActivity
+	assert.False(t, activity.Failing())
+
+	start := time.Now()
+	activity.Record(start, errors.New("quota fetch failed"))
+	assert.Equal(t, start, activity.LastUsed)
+	assert.Equal(t, "quota fetch failed", activity.LastErrorText)
+	assert.True(t, activity.Failing())
+
+	// A later success clears the failing state but keeps the error for reference
+	later := start.Add(time.Minute)
+	activity.Record(later, nil)
+	assert.Equal(t, later, activity.LastSuccess)
+	assert.Equal(t, "quota fetch failed", activity.LastErrorText)
+	assert.False(t, activity.Failing())
+
+	activity.Record(later.Add(time.Minute), errors.New(strings.Repeat("x", 2*maxErrorTextLength)))
+	assert.Len(t, []rune(activity.LastErrorText), maxErrorTextLength+1)
+}
+
+func TestRecordAccountActivity(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	email := "active@example.com"
+	require.NoError(t, SaveTokenForAccount(email, testToken(email)))
+
+	require.NoError(t, RecordAccountActivity(email, errors.New("permission denied")))
+
+	mgr, err := NewAccountManager()
+	require.NoError(t, err)
+	accounts, err := mgr.ListAccounts()
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+
+	acc := accounts[0]
+	assert.False(t, acc.LastUsed.IsZero())
+	assert.True(t, acc.LastSuccess.IsZero())
+	assert.Equal(t, "permission denied", acc.LastErrorText)
+	assert.True(t, acc.Failing())
+
+	// The token file is not rewritten
+	path, err := config.GetAccountPath(email)
+	require.NoError(t, err)
+	before, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, RecordAccountActivity(email, nil))
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	assert.ErrorIs(t, RecordAccountActivity("missing@example.com", nil), ErrAccountNotFound)
+
+	// Removing the account forgets its activity
+	require.NoError(t, mgr.RemoveAccount(email))
+	activity, err := LoadAccountActivity()
+	require.NoError(t, err)
+	assert.NotContains(t, activity, email)
+}
+
+func TestRecordAccountActivity_SkipsRepeats(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	email := "watched@example.com"
+	require.NoError(t, SaveTokenForAccount(email, testToken(email)))
+
+	start := time.Now()
+	require.NoError(t, recordAccountActivity(email, start, nil))
+
+	// A repeated success within the interval is not written
+	require.NoError(t, recordAccountActivity(email, start.Add(10*time.Second), nil))
+	activity, err := LoadAccountActivity()
+	require.NoError(t, err)
+	assert.True(t, activity[email].LastUsed.Equal(start))
+
+	// A different outcome is written at once
+	failedAt := start.Add(20 * time.Second)
+	require.NoError(t, recordAccountActivity(email, failedAt, errors.New("quota fetch failed")))
+	activity, err = LoadAccountActivity()
+	require.NoError(t, err)
+	assert.True(t, activity[email].LastError.Equal(failedAt))
+	assert.True(t, activity[email].Failing())
+
+	// So is a repeat once the interval has passed
+	later := failedAt.Add(ActivityWriteInterval)
+	require.NoError(t, recordAccountActivity(email, later, errors.New("quota fetch failed")))
+	activity, err = LoadAccountActivity()
+	require.NoError(t, err)
+	assert.True(t, activity[email].LastUsed.Equal(later))
+}
+
+func TestUnusedAccounts(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	now := time.Now()
+	for email, lastUsed := range map[string]time.Time{
+		"recent@example.com": now.Add(-time.Hour),
+		"stale@example.com":  now.Add(-45 * 24 * time.Hour),
+		"legacy@example.com": {},
+	} {
+		require.NoError(t, SaveTokenForAccount(email, testToken(email)))
+		if !lastUsed.IsZero() {
+			require.NoError(t, recordAccountActivity(email, lastUsed, nil))
+		}
+	}
+
+	// Without recorded activity the file's modification time is used
+	path, err := config.GetAccountPath("legacy@example.com")
+	require.NoError(t, err)
+	old := now.Add(-60 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(path, old, old))
+
+	mgr, err := NewAccountManager()
+	require.NoError(t, err)
+
+	unused, err := mgr.UnusedAccounts(30*24*time.Hour, now)
+	require.NoError(t, err)
+
+	var emails []string
+	for _, acc := range unused {
+		emails = append(emails, acc.Email)
+	}
+	assert.ElementsMatch(t, []string{"stale@example.com", "legacy@example.com"}, emails)
+}
@@ -0,0 +1,334 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+)
+
+// EnvBundlePassphrase supplies the passphrase of export bundles without prompting, e.g. in CI
+const EnvBundlePassphrase = "AG_QUOTA_BUNDLE_PASSPHRASE"
+
+const (
+	bundleFormat  = "ag-quota-bundle"
+	bundleVersion = 1
+)
+
+// Conflict policies for importing an account that already exists locally
+const (
+	// ConflictSkip keeps the local account
+	ConflictSkip = "skip"
+	// ConflictOverwrite replaces the local account with the imported one
+	ConflictOverwrite = "overwrite"
+	// ConflictNewest keeps whichever token was issued last
+	ConflictNewest = "newest"
+)
+
+// ErrBundleDecrypt is returned when a bundle cannot be decrypted with the given passphrase
+var ErrBundleDecrypt = errors.New("wrong bundle passphrase or corrupted bundle")
+
+// ExportOptions selects what goes into an export bundle
+type ExportOptions struct {
+	// Emails limits the export to these accounts; empty means all accounts
+	Emails []string
+	// Label limits the export to accounts carrying the label
+	Label string
+	// IncludeNotifications adds the notification settings to the bundle
+	IncludeNotifications bool
+}
+
+// ImportOptions controls how a bundle is applied
+type ImportOptions struct {
+	// Conflict is ConflictSkip (default), ConflictOverwrite or ConflictNewest
+	Conflict string
+	// IncludeNotifications applies the notification settings carried by the bundle
+	IncludeNotifications bool
+}
+
+// ImportResult reports what an import did with each account
+type ImportResult struct {
+	Imported    []string
+	Overwritten []string
+	Skipped     []string
+	// DefaultAccount is set when the import changed the default account
+	DefaultAccount string
+	// NotificationsApplied reports whether notification settings were replaced
+	NotificationsApplied bool
+	Warnings             []string
+}
+
+// bundleFile is the on-disk form of a bundle; everything but the header is encrypted
+type bundleFile struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// bundleContents is the decrypted payload of a bundle
+type bundleContents struct {
+	CreatedAt      time.Time                    `json:"created_at"`
+	DefaultAccount string                       `json:"default_account,omitempty"`
+	Accounts       []*TokenData                 `json:"accounts"`
+	Notifications  *config.NotificationSettings `json:"notifications,omitempty"`
+}
+
+// ExportBundle encrypts the selected accounts, the default account and optionally
+// the notification settings with the passphrase. It returns the bundle and the
+// emails it contains.
+func ExportBundle(passphrase string, opts ExportOptions) ([]byte, []string, error) {
+	if passphrase == "" {
+		return nil, nil, fmt.Errorf("bundle passphrase must not be empty")
+	}
+
+	mgr, err := NewAccountManager()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	emails, err := exportSelection(mgr, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(emails) == 0 {
+		return nil, nil, fmt.Errorf("no accounts match the selection")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contents := bundleContents{CreatedAt: time.Now().UTC()}
+	for _, email := range emails {
+		token, err := LoadTokenForAccount(email)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load %s: %w", email, err)
+		}
+		token.Email = email
+		contents.Accounts = append(contents.Accounts, token)
+	}
+	if slices.Contains(emails, cfg.DefaultAccount) {
+		contents.DefaultAccount = cfg.DefaultAccount
+	}
+	if opts.IncludeNotifications {
+		notifications := cfg.Notifications
+		contents.Notifications = &notifications
+	}
+
+	plaintext, err := json.Marshal(contents)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+
+	bundle, err := sealBundle(passphrase, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bundle, emails, nil
+}
+
+// exportSelection resolves the accounts named by the options, sorted
+func exportSelection(mgr *AccountManager, opts ExportOptions) ([]string, error) {
+	var emails []string
+	if len(opts.Emails) > 0 {
+		for _, name := range opts.Emails {
+			email, err := mgr.ResolveAccount(name)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(emails, email) {
+				emails = append(emails, email)
+			}
+		}
+	} else {
+		all, err := listAccountEmails(mgr.accountsDir)
+		if err != nil {
+			return nil, err
+		}
+		emails = all
+	}
+
+	if opts.Label != "" {
+		accounts, err := mgr.ListAccounts()
+		if err != nil {
+			return nil, err
+		}
+		var labelled []string
+		for _, acc := range FilterByLabel(accounts, opts.Label) {
+			if slices.Contains(emails, acc.Email) {
+				labelled = append(labelled, acc.Email)
+			}
+		}
+		emails = labelled
+	}
+
+	slices.Sort(emails)
+	return emails, nil
+}
+
+// ImportBundle decrypts a bundle and saves its accounts with the local vault backend
+func ImportBundle(data []byte, passphrase string, opts ImportOptions) (*ImportResult, error) {
+	switch opts.Conflict {
+	case "":
+		opts.Conflict = ConflictSkip
+	case ConflictSkip, ConflictOverwrite, ConflictNewest:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q (expected %s, %s or %s)", opts.Conflict, ConflictSkip, ConflictOverwrite, ConflictNewest)
+	}
+
+	plaintext, err := openBundle(passphrase, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var contents bundleContents
+	if err := json.Unmarshal(plaintext, &contents); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle contents: %w", err)
+	}
+
+	mgr, err := NewAccountManager()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	for _, token := range contents.Accounts {
+		if token == nil || token.Email == "" {
+			result.Warnings = append(result.Warnings, "skipped an account without an email")
+			continue
+		}
+		if err := importAccount(mgr, token, opts.Conflict, result); err != nil {
+			return result, fmt.Errorf("failed to import %s: %w", token.Email, err)
+		}
+	}
+
+	if contents.DefaultAccount != "" && mgr.HasAccount(contents.DefaultAccount) {
+		if _, err := mgr.DefaultAccount(); err != nil {
+			if err := mgr.SetDefaultAccount(contents.DefaultAccount); err != nil {
+				return result, err
+			}
+			result.DefaultAccount = contents.DefaultAccount
+		}
+	}
+
+	if opts.IncludeNotifications {
+		if contents.Notifications == nil {
+			result.Warnings = append(result.Warnings, "the bundle does not contain notification settings")
+		} else {
+			err := config.UpdateConfig(func(cfg *config.Config) error {
+				cfg.Notifications = *contents.Notifications
+				return nil
+			})
+			if err != nil {
+				return result, fmt.Errorf("failed to save notification settings: %w", err)
+			}
+			result.NotificationsApplied = true
+		}
+	}
+
+	return result, nil
+}
+
+// importAccount saves one account from a bundle according to the conflict policy
+func importAccount(mgr *AccountManager, token *TokenData, conflict string, result *ImportResult) error {
+	email := token.Email
+
+	exists := mgr.HasAccount(email)
+	if exists {
+		replace := conflict == ConflictOverwrite
+		if conflict == ConflictNewest {
+			local, err := LoadTokenForAccount(email)
+			replace = err != nil || token.Expiry.After(local.Expiry)
+		}
+		if !replace {
+			result.Skipped = append(result.Skipped, email)
+			return nil
+		}
+	}
+
+	// An alias already taken by another local account is dropped rather than duplicated
+	if alias := token.Metadata.Alias; alias != "" {
+		if owner, err := mgr.ResolveAccount(alias); err == nil && owner != email {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("alias %q of %s is already used by %s and was dropped", alias, email, owner))
+			token.Metadata.Alias = ""
+		}
+	}
+
+	if err := SaveTokenForAccount(email, token); err != nil {
+		return err
+	}
+
+	if exists {
+		result.Overwritten = append(result.Overwritten, email)
+	} else {
+		result.Imported = append(result.Imported, email)
+	}
+	return nil
+}
+
+// sealBundle encrypts a bundle payload with a key derived from the passphrase
+func sealBundle(passphrase string, plaintext []byte) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, salt, pbkdf2Iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return json.MarshalIndent(bundleFile{
+		Format:     bundleFormat,
+		Version:    bundleVersion,
+		KDF:        vaultKDF,
+		Iterations: pbkdf2Iterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, []byte(bundleFormat)),
+	}, "", "  ")
+}
+
+// openBundle decrypts a bundle created by sealBundle
+func openBundle(passphrase string, data []byte) ([]byte, error) {
+	var file bundleFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Format != bundleFormat {
+		return nil, fmt.Errorf("not an ag-quota bundle")
+	}
+	if file.Version != bundleVersion || file.KDF != vaultKDF {
+		return nil, fmt.Errorf("unsupported bundle (version %d, kdf %q)", file.Version, file.KDF)
+	}
+	// The header is not authenticated, so a crafted bundle must not be able to
+	// make the key derivation arbitrarily slow
+	if file.Iterations < pbkdf2Iterations || file.Iterations > 10*pbkdf2Iterations {
+		return nil, fmt.Errorf("unsupported bundle (%d key derivation iterations)", file.Iterations)
+	}
+
+	gcm, err := newGCM(passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce in bundle")
+	}
+
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, []byte(bundleFormat))
+	if err != nil {
+		return nil, ErrBundleDecrypt
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportFrom builds a bundle in one config dir so it can be imported into another
+func exportFrom(t *testing.T, opts ExportOptions, tokens ...*TokenData) []byte {
+	t.Helper()
+
+	setupVault(t, config.VaultSettings{})
+	for _, token := range tokens {
+		require.NoError(t, SaveTokenForAccount(token.Email, token))
+	}
+	require.NoError(t, config.SaveConfig(&config.Config{
+		DefaultAccount: tokens[0].Email,
+		Notifications: config.NotificationSettings{
+			Enabled:  true,
+			Telegram: config.TelegramSettings{BotToken: "bot", ChatID: "42"},
+		},
+	}))
+
+	bundle, _, err := ExportBundle("moving day", opts)
+	require.NoError(t, err)
+	return bundle
+}
+
+func bundleToken(email, alias string, labels ...string) *TokenData {
+	token := testToken(email)
+	token.Email = email
+	token.Metadata = AccountMetadata{Alias: alias, Labels: labels}
+	return token
+}
+
+func TestBundle_RoundTrip(t *testing.T) {
+	bundle := exportFrom(t, ExportOptions{IncludeNotifications: true},
+		bundleToken("alice@example.com", "work", "ci"),
+		bundleToken("bob@example.com", ""),
+	)
+	assert.NotContains(t, string(bundle), "refresh-alice@example.com", "tokens must be encrypted")
+
+	// A fresh machine with an encrypted vault
+	setupVault(t, config.VaultSettings{Backend: VaultEncrypted})
+	t.Setenv(EnvVaultPassphrase, "local vault")
+
+	_, err := ImportBundle(bundle, "wrong", ImportOptions{})
+	assert.True(t, errors.Is(err, ErrBundleDecrypt), "expected ErrBundleDecrypt, got %v", err)
+
+	result, err := ImportBundle(bundle, "moving day", ImportOptions{IncludeNotifications: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, result.Imported)
+	assert.Equal(t, "alice@example.com", result.DefaultAccount)
+	assert.True(t, result.NotificationsApplied)
+
+	loaded, err := LoadTokenForAccount("alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "refresh-alice@example.com", loaded.RefreshToken)
+	assert.Equal(t, "work", loaded.Metadata.Alias)
+	assert.Contains(t, string(readAccountFile(t, "alice@example.com")), `"vault": "encrypted"`,
+		"imported tokens use the local vault backend")
+
+	cfg, err := config.LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", cfg.DefaultAccount)
+	assert.Equal(t, "42", cfg.Notifications.Telegram.ChatID)
+	assert.Equal(t, VaultEncrypted, cfg.Vault.Backend, "importing must not touch the vault settings")
+}
+
+func TestBundle_SelectiveExport(t *testing.T) {
+	tokens := []*TokenData{
+		bundleToken("alice@example.com", "work", "ci"),
+		bundleToken("bob@example.com", "", "ci"),
+		bundleToken("carol@example.com", "home"),
+	}
+
+	setupVault(t, config.VaultSettings{})
+	for _, token := range tokens {
+		require.NoError(t, SaveTokenForAccount(token.Email, token))
+	}
+
+	_, emails, err := ExportBundle("pw", ExportOptions{Label: "CI"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, emails)
+
+	_, emails, err = ExportBundle("pw", ExportOptions{Emails: []string{"home", "bob@example.com"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob@example.com", "carol@example.com"}, emails)
+
+	_, _, err = ExportBundle("pw", ExportOptions{Emails: []string{"home"}, Label: "ci"})
+	assert.Error(t, err, "an empty selection is an error")
+
+	_, _, err = ExportBundle("pw", ExportOptions{Emails: []string{"nobody"}})
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+
+	bundle, _, err := ExportBundle("pw", ExportOptions{})
+	require.NoError(t, err)
+	_, err = ImportBundle(bundle, "pw", ImportOptions{IncludeNotifications: true})
+	require.NoError(t, err)
+}
+
+func TestBundle_Conflicts(t *testing.T) {
+	older := bundleToken("alice@example.com", "")
+	older.Expiry = time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	older.AccessToken = "bundled"
+	bundle := exportFrom(t, ExportOptions{}, older, bundleToken("bob@example.com", "shared"))
+
+	setup := func() {
+		setupVault(t, config.VaultSettings{})
+		local := bundleToken("alice@example.com", "")
+		local.AccessToken = "local"
+		require.NoError(t, SaveTokenForAccount(local.Email, local))
+		require.NoError(t, SaveTokenForAccount("carol@example.com", bundleToken("carol@example.com", "shared")))
+	}
+	accessToken := func() string {
+		token, err := LoadTokenForAccount("alice@example.com")
+		require.NoError(t, err)
+		return token.AccessToken
+	}
+
+	setup()
+	result, err := ImportBundle(bundle, "moving day", ImportOptions{Conflict: ConflictSkip})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice@example.com"}, result.Skipped)
+	assert.Equal(t, "local", accessToken())
+
+	// bob's alias is taken by carol, so it is dropped
+	require.Len(t, result.Warnings, 1)
+	bob, err := LoadTokenForAccount("bob@example.com")
+	require.NoError(t, err)
+	assert.Empty(t, bob.Metadata.Alias)
+
+	// The local token expires later, so it is the newest
+	result, err = ImportBundle(bundle, "moving day", ImportOptions{Conflict: ConflictNewest})
+	require.NoError(t, err)
+	assert.Contains(t, result.Skipped, "alice@example.com")
+	assert.Equal(t, "local", accessToken())
+
+	result, err = ImportBundle(bundle, "moving day", ImportOptions{Conflict: ConflictOverwrite})
+	require.NoError(t, err)
+	assert.Contains(t, result.Overwritten, "alice@example.com")
+	assert.Equal(t, "bundled", accessToken())
+
+	_, err = ImportBundle(bundle, "moving day", ImportOptions{Conflict: "merge"})
+	assert.Error(t, err)
+}
+
+func TestBundle_Invalid(t *testing.T) {
+	setupVault(t, config.VaultSettings{})
+
+	_, err := ImportBundle([]byte(`{"access_token": "x"}`), "pw", ImportOptions{})
+	assert.Error(t, err)
+
+	data, err := os.ReadFile("bundle_test.go")
+	require.NoError(t, err)
+	_, err = ImportBundle(data, "pw", ImportOptions{})
+	assert.Error(t, err)
+}
+
+func TestBundle_TamperedIterations(t *testing.T) {
+	bundle := exportFrom(t, ExportOptions{}, bundleToken("alice@example.com", ""))
+
+	for _, iterations := range []int{1, pbkdf2Iterations - 1, 10*pbkdf2Iterations + 1, 1 << 31} {
+		var file map[string]any
+		require.NoError(t, json.Unmarshal(bundle, &file))
+		file["iterations"] = iterations
+		tampered, err := json.Marshal(file)
+		require.NoError(t, err)
+
+		_, err = ImportBundle(tampered, "moving day", ImportOptions{})
+		require.Error(t, err, "iterations %d", iterations)
+		assert.Contains(t, err.Error(), "key derivation iterations")
+	}
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+)
+
+// AccountColors are the colors an account can be marked with
+var AccountColors = []string{"red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// AccountMetadata is what the user records about an account. It is kept in the
+// account's token file so it moves and is removed together with the token.
+type AccountMetadata struct {
+	// Alias is a short name accepted wherever an account email is
+	Alias  string   `json:"alias,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+	Notes  string   `json:"notes,omitempty"`
+	Color  string   `json:"color,omitempty"`
+}
+
+// HasLabel reports whether the account carries the label, ignoring case
+func (m AccountMetadata) HasLabel(label string) bool {
+	return slices.ContainsFunc(m.Labels, func(l string) bool {
+		return strings.EqualFold(l, label)
+	})
+}
+
+// Validate checks the alias format and color
+func (m AccountMetadata) Validate() error {
+	if m.Alias != "" && !aliasPattern.MatchString(m.Alias) {
+		return fmt.Errorf("invalid alias %q: use letters, digits, '.', '_' or '-'", m.Alias)
+	}
+	if m.Color != "" && !slices.Contains(AccountColors, m.Color) {
+		return fmt.Errorf("invalid color %q (expected one of %s)", m.Color, strings.Join(AccountColors, ", "))
+	}
+	for _, label := range m.Labels {
+		if strings.TrimSpace(label) == "" || strings.Contains(label, ",") {
+			return fmt.Errorf("invalid label %q", label)
+		}
+	}
+	return nil
+}
+
+// NormalizeLabels trims, deduplicates (ignoring case) and sorts labels
+func NormalizeLabels(labels []string) []string {
+	var out []string
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+		if !slices.ContainsFunc(out, func(l string) bool { return strings.EqualFold(l, label) }) {
+			out = append(out, label)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ResolveAccount returns the email of the account named by an email or alias
+func (m *AccountManager) ResolveAccount(name string) (string, error) {
+	if id, err := config.CanonicalAccountID(name); err == nil && m.HasAccount(id) {
+		return id, nil
+	}
+
+	emails, err := listAccountEmails(m.accountsDir)
+	if err != nil {
+		return "", err
+	}
+	for _, email := range emails {
+		token, err := LoadTokenForAccount(email)
+		if err != nil {
+			continue
+		}
+		if token.Metadata.Alias != "" && strings.EqualFold(token.Metadata.Alias, name) {
+			return email, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrAccountNotFound, name)
+}
+
+// SetAccountMetadata replaces the metadata of an account. Aliases must be unique
+// and must not be the email of another account.
+func (m *AccountManager) SetAccountMetadata(email string, meta AccountMetadata) error {
+	meta.Labels = NormalizeLabels(meta.Labels)
+	if err := meta.Validate(); err != nil {
+		return err
+	}
+
+	email, err := config.CanonicalAccountID(email)
+	if err != nil {
+		return err
+	}
+	if !m.HasAccount(email) {
+		return fmt.Errorf("%w: %s", ErrAccountNotFound, email)
+	}
+
+	if meta.Alias != "" {
+		owner, err := m.ResolveAccount(meta.Alias)
+		if err == nil && owner != email {
+			return fmt.Errorf("alias %q is already used by %s", meta.Alias, owner)
+		}
+	}
+
+	return UpdateTokenForAccount(email, func(token *TokenData) {
+		token.Metadata = meta
+	})
+}
+
+// FilterByLabel returns the accounts that carry the label, ignoring case
+func FilterByLabel(accounts []AccountInfo, label string) []AccountInfo {
+	var filtered []AccountInfo
+	for _, acc := range accounts {
+		if (AccountMetadata{Labels: acc.Labels}).HasLabel(label) {
+			filtered = append(filtered, acc)
+		}
+	}
+	return filtered
+}
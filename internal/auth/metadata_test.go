@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountMetadata(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	mgr, err := NewAccountManager()
+	require.NoError(t, err)
+
+	work := "someone.long.name+work@gmail.com"
+	for _, email := range []string{work, "personal@gmail.com"} {
+		require.NoError(t, SaveTokenForAccount(email, &TokenData{AccessToken: "a", RefreshToken: "r"}))
+	}
+
+	require.NoError(t, mgr.SetAccountMetadata(work, AccountMetadata{
+		Alias:  "work",
+		Labels: []string{" team-A", "ci", "CI"},
+		Notes:  "build runners",
+		Color:  "cyan",
+	}))
+
+	token, err := LoadTokenForAccount(work)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci", "team-A"}, token.Metadata.Labels, "labels are trimmed, deduplicated and sorted")
+	assert.Equal(t, "r", token.RefreshToken, "updating metadata keeps the token")
+
+	t.Run("ResolveAlias", func(t *testing.T) {
+		email, err := mgr.ResolveAccount("WORK")
+		require.NoError(t, err)
+		assert.Equal(t, work, email)
+
+		email, err = mgr.ResolveAccount("personal@gmail.com")
+		require.NoError(t, err)
+		assert.Equal(t, "personal@gmail.com", email)
+
+		_, err = mgr.ResolveAccount("nobody")
+		assert.True(t, errors.Is(err, ErrAccountNotFound))
+	})
+
+	t.Run("AliasMustBeUnique", func(t *testing.T) {
+		err := mgr.SetAccountMetadata("personal@gmail.com", AccountMetadata{Alias: "work"})
+		assert.ErrorContains(t, err, "already used")
+
+		err = mgr.SetAccountMetadata("personal@gmail.com", AccountMetadata{Alias: work})
+		assert.Error(t, err, "an email is not a valid alias")
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		assert.Error(t, mgr.SetAccountMetadata(work, AccountMetadata{Color: "purple"}))
+		assert.Error(t, mgr.SetAccountMetadata(work, AccountMetadata{Alias: "../x"}))
+		assert.True(t, errors.Is(mgr.SetAccountMetadata("missing@gmail.com", AccountMetadata{}), ErrAccountNotFound))
+	})
+
+	t.Run("ListAndFilter", func(t *testing.T) {
+		accounts, err := mgr.ListAccounts()
+		require.NoError(t, err)
+		require.Len(t, accounts, 2)
+
+		filtered := FilterByLabel(accounts, "Team-a")
+		require.Len(t, filtered, 1)
+		assert.Equal(t, work, filtered[0].Email)
+		assert.Equal(t, "work", filtered[0].Alias)
+		assert.Equal(t, "cyan", filtered[0].Color)
+		assert.Equal(t, "build runners", filtered[0].Notes)
+	})
+}
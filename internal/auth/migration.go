@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 
@@ -88,3 +89,325 @@ func MigrateIfNeeded() error {
 
 	return nil
 }
+
+// MigrateVaultIfNeeded moves account tokens into the configured vault backend,
+// e.g. encrypting existing plaintext files after the encrypted backend was chosen.
+// Files that already use the configured backend are left untouched. With a backend
+// other than plaintext, the plaintext token.json.bak left by MigrateIfNeeded is
+// removed as well.
+func MigrateVaultIfNeeded() (err error) {
+	target, err := ConfiguredSecretStore()
+	if err != nil {
+		return err
+	}
+	if target.Name() != VaultPlaintext {
+		defer func() {
+			if err == nil {
+				err = removeLegacyTokenBackup()
+			}
+		}()
+	}
+
+	accountsDir, err := config.GetAccountsDir()
+	if err != nil {
+		return nil
+	}
+
+	emails, err := listAccountEmails(accountsDir)
+	if err != nil {
+		return nil // No accounts yet
+	}
+
+	var pending []string
+	for _, email := range emails {
+		path, errPath := config.GetAccountPath(email)
+		if errPath != nil {
+			continue
+		}
+		data, errRead := os.ReadFile(path)
+		if errRead != nil {
+			continue
+		}
+		if store, errStore := storeOf(data); errStore == nil && store.Name() == target.Name() {
+			continue
+		}
+		pending = append(pending, email)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(os.Stderr, color.CyanString("🔐 Moving %d account token(s) to the %s vault...", len(pending), target.Name()))
+
+	for _, email := range pending {
+		if err := migrateAccountVault(email); err != nil {
+			return fmt.Errorf("failed to migrate token of %s: %w", email, err)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, color.GreenString("✅ Vault migration complete!"))
+	fmt.Fprintln(os.Stderr)
+
+	return nil
+}
+
+// removeLegacyTokenBackup deletes token.json.bak, the plaintext copy of the token
+// kept by MigrateIfNeeded, once its account is stored in the vault. A backup that
+// cannot be matched to an account is kept and reported.
+func removeLegacyTokenBackup() error {
+	tokenPath, err := config.GetTokenPath()
+	if err != nil {
+		return nil
+	}
+	backupPath := tokenPath + ".bak"
+
+	data, err := os.ReadFile(backupPath)
+	if err != nil {
+		return nil // No backup
+	}
+
+	var token TokenData
+	if json.Unmarshal(data, &token) == nil {
+		if path, errPath := config.GetAccountPath(token.Email); errPath == nil {
+			if _, errStat := os.Stat(path); errStat == nil {
+				if err := os.Remove(backupPath); err != nil {
+					return fmt.Errorf("failed to remove plaintext token backup: %w", err)
+				}
+				fmt.Fprintln(os.Stderr, color.New(color.Faint).Sprintf("   Removed the plaintext token backup %s", filepath.Base(backupPath)))
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%s holds a plaintext token outside the vault, delete it once you no longer need it", backupPath)
+}
+
+// migrateAccountVault re-saves one account with the configured store and removes
+// what its previous store kept elsewhere
+func migrateAccountVault(email string) error {
+	unlock, err := lockAccount(email)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path, err := config.GetAccountPath(email)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	previous, err := storeOf(data)
+	if err != nil {
+		return err
+	}
+
+	token, err := loadTokenForAccount(email)
+	if err != nil {
+		return err
+	}
+
+	if err := saveTokenForAccount(email, token); err != nil {
+		return err
+	}
+
+	return previous.Delete(email)
+}
+
+// MigrateAccountIDsIfNeeded renames account files written before accounts were
+// stored under their canonical ID, e.g. accounts/User@Example.com.json, and
+// records every account in the account index. When two files belong to the same
+// account, the token that expires last is kept.
+func MigrateAccountIDsIfNeeded() error {
+	accountsDir, err := config.GetAccountsDir()
+	if err != nil {
+		return nil
+	}
+
+	entries, err := os.ReadDir(accountsDir)
+	if err != nil {
+		return nil // No accounts yet
+	}
+
+	pending := make(map[string]string) // file name -> raw email
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		raw, errName := config.AccountIDFromFileName(entry.Name())
+		if errName != nil {
+			continue
+		}
+		id, errID := config.CanonicalAccountID(raw)
+		if errID != nil {
+			continue // Not an account file
+		}
+		if config.AccountFileName(id) != entry.Name() {
+			pending[entry.Name()] = raw
+		}
+	}
+
+	if len(pending) > 0 {
+		fmt.Fprintln(os.Stderr, color.CyanString("🔄 Renaming %d account file(s) to canonical account IDs...", len(pending)))
+
+		for name, raw := range pending {
+			if err := migrateAccountID(filepath.Join(accountsDir, name), raw); err != nil {
+				return fmt.Errorf("failed to migrate account file %s: %w", name, err)
+			}
+		}
+
+		fmt.Fprintln(os.Stderr, color.GreenString("✅ Account files migrated!"))
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if err := canonicalizeDefaultAccount(); err != nil {
+		return err
+	}
+	return rebuildAccountIndex(accountsDir)
+}
+
+// migrateAccountID moves the account file at path, written for the raw email,
+// to the canonical ID of that email
+func migrateAccountID(path, raw string) error {
+	id, err := config.CanonicalAccountID(raw)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockAccount(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	target, err := config.GetAccountPath(id)
+	if err != nil {
+		return err
+	}
+
+	// On a case-insensitive filesystem, e.g. on macOS, User@Example.com.json already
+	// is the account's file. It is renamed through a staging name so its directory
+	// entry takes the canonical name, and must not be removed afterwards.
+	renamed := false
+	if sameFile(path, target) {
+		staging := filepath.Join(filepath.Dir(path), stagingFileName(raw))
+		if err := os.Rename(path, staging); err != nil {
+			return err
+		}
+		if err := os.Rename(staging, target); err != nil {
+			return err
+		}
+		path, renamed = target, true
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Files were sealed with the email they were named after
+	previous, err := storeOf(data)
+	if err != nil {
+		return err
+	}
+	plain, err := previous.Open(raw, data)
+	if err != nil {
+		return err
+	}
+
+	var token TokenData
+	if err := json.Unmarshal(plain, &token); err != nil {
+		return fmt.Errorf("failed to parse token file: %w", err)
+	}
+
+	// A renamed file is the only copy, it is re-saved so it is sealed with the canonical ID
+	if renamed {
+		if err := saveTokenForAccount(id, &token); err != nil {
+			return err
+		}
+	} else if existing, errLoad := loadTokenForAccount(id); errLoad != nil || token.Expiry.After(existing.Expiry) {
+		if err := saveTokenForAccount(id, &token); err != nil {
+			return err
+		}
+	}
+
+	if raw != id {
+		if err := previous.Delete(raw); err != nil {
+			return fmt.Errorf("failed to delete %s secret: %w", previous.Name(), err)
+		}
+	}
+	if renamed {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// sameFile reports whether both paths name the same existing file, replaced in tests
+var sameFile = func(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+// stagingFileName returns a file name for raw that differs from the canonical one
+// in more than case but still decodes to raw, so an interrupted rename is picked
+// up again by the next migration
+func stagingFileName(raw string) string {
+	return fmt.Sprintf("%%%02X", raw[0]) + config.AccountFileName(raw[1:])
+}
+
+// canonicalizeDefaultAccount stores the default account under its canonical ID
+func canonicalizeDefaultAccount() error {
+	// This runs on every invocation, so the config is only locked and rewritten
+	// when the default account actually needs to change
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if id, err := config.CanonicalAccountID(cfg.DefaultAccount); err != nil || id == cfg.DefaultAccount {
+		return nil
+	}
+
+	return config.UpdateConfig(func(cfg *config.Config) error {
+		if id, err := config.CanonicalAccountID(cfg.DefaultAccount); err == nil {
+			cfg.DefaultAccount = id
+		}
+		return nil
+	})
+}
+
+// rebuildAccountIndex makes the account index list exactly the account files in dir
+func rebuildAccountIndex(dir string) error {
+	emails, err := listAccountEmails(dir)
+	if err != nil {
+		return err
+	}
+
+	want := make(map[string]string, len(emails))
+	for _, email := range emails {
+		want[config.AccountFileName(email)] = email
+	}
+	// Like the default account, the index is only locked when it is out of date
+	if index, err := config.LoadAccountIndex(); err == nil && maps.Equal(index.Accounts, want) {
+		return nil
+	}
+
+	return config.UpdateAccountIndex(func(index *config.AccountIndex) bool {
+		if maps.Equal(index.Accounts, want) {
+			return false
+		}
+		index.Accounts = want
+		return true
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -72,3 +73,132 @@ func TestMigrateIfNeeded(t *testing.T) {
 	_, err = os.Stat(backupPath)
 	assert.NoError(t, err, "Backup file should exist")
 }
+
+func TestMigrateAccountIDsIfNeeded(t *testing.T) {
+	setupVault(t, config.VaultSettings{Backend: VaultEncrypted})
+	t.Setenv(EnvVaultPassphrase, "ids")
+
+	accountsDir, err := config.EnsureAccountsDir()
+	require.NoError(t, err)
+
+	writeLegacy := func(name, email string, token *TokenData) {
+		data, err := json.Marshal(token)
+		require.NoError(t, err)
+		sealed, err := (&encryptedStore{}).Seal(email, data)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(accountsDir, name), sealed, 0600))
+	}
+
+	// Two files for the same account; the later expiry wins. Their names differ in
+	// more than case so both can exist on a case-insensitive filesystem.
+	newer := testToken(" Alice@Example.com")
+	newer.AccessToken = "newer"
+	newer.Expiry = time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	older := testToken("alice@example.com")
+	older.AccessToken = "older"
+	writeLegacy("%20Alice@Example.com.json", " Alice@Example.com", newer)
+	writeLegacy("alice@example.com.json", "alice@example.com", older)
+	writeLegacy("Bob@Example.com.json", "Bob@Example.com", testToken("Bob@Example.com"))
+
+	// Files that are not accounts are left alone
+	require.NoError(t, os.WriteFile(filepath.Join(accountsDir, "notes.json"), []byte("{}"), 0600))
+
+	require.NoError(t, config.SaveConfig(&config.Config{
+		DefaultAccount: "Bob@Example.com",
+		Vault:          config.VaultSettings{Backend: VaultEncrypted},
+	}))
+
+	require.NoError(t, MigrateAccountIDsIfNeeded())
+
+	entries, err := os.ReadDir(accountsDir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{"alice@example.com.json", "bob@example.com.json", "notes.json"}, names)
+
+	alice, err := LoadTokenForAccount("ALICE@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "newer", alice.AccessToken)
+	assert.Equal(t, "alice@example.com", alice.Email)
+
+	cfg, err := config.LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", cfg.DefaultAccount)
+
+	index, err := config.LoadAccountIndex()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"alice@example.com.json": "alice@example.com",
+		"bob@example.com.json":   "bob@example.com",
+	}, index.Accounts)
+
+	mgr, err := NewAccountManager()
+	require.NoError(t, err)
+	accounts, err := mgr.ListAccounts()
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	assert.Equal(t, "bob@example.com", accounts[0].Email)
+	assert.True(t, accounts[0].IsDefault)
+
+	// Nothing is left to do on the next run, and the config is not rewritten
+	configPath, err := config.GetConfigPath()
+	require.NoError(t, err)
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(configPath, past, past))
+
+	require.NoError(t, MigrateAccountIDsIfNeeded())
+
+	info, err := os.Stat(configPath)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(past), "config.json must not be rewritten when nothing changes")
+}
+
+func TestMigrateAccountIDs_CaseOnlyRename(t *testing.T) {
+	for _, backend := range []string{VaultPlaintext, VaultEncrypted} {
+		t.Run(backend, func(t *testing.T) {
+			setupVault(t, config.VaultSettings{Backend: backend})
+			t.Setenv(EnvVaultPassphrase, "ids")
+
+			// Act like a case-insensitive filesystem, where Carol@Example.com.json
+			// and carol@example.com.json are the same file
+			original := sameFile
+			sameFile = func(a, b string) bool { return strings.EqualFold(a, b) }
+			t.Cleanup(func() { sameFile = original })
+
+			accountsDir, err := config.EnsureAccountsDir()
+			require.NoError(t, err)
+
+			store, err := secretStoreByName(backend, config.VaultSettings{Backend: backend})
+			require.NoError(t, err)
+			for name, email := range map[string]string{
+				"Carol@Example.com.json":            "Carol@Example.com",
+				stagingFileName("Dave@Example.com"): "Dave@Example.com", // left over by an interrupted rename
+			} {
+				data, err := json.Marshal(testToken(email))
+				require.NoError(t, err)
+				sealed, err := store.Seal(email, data)
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(filepath.Join(accountsDir, name), sealed, 0600))
+			}
+
+			require.NoError(t, MigrateAccountIDsIfNeeded())
+
+			entries, err := os.ReadDir(accountsDir)
+			require.NoError(t, err)
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			assert.ElementsMatch(t, []string{"carol@example.com.json", "dave@example.com.json"}, names)
+
+			for _, email := range []string{"carol@example.com", "dave@example.com"} {
+				token, err := LoadTokenForAccount(email)
+				require.NoError(t, err, "the only copy of %s must survive the rename", email)
+				assert.Equal(t, email, token.Email)
+				assert.NotEmpty(t, token.RefreshToken)
+			}
+		})
+	}
+}
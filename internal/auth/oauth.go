@@ -1,15 +1,20 @@
 package auth
 
 import (
+	"bufio"
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os/exec"
 	"runtime"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -21,8 +26,10 @@ var callbackHTML string
 
 const (
 	// Google OAuth2 endpoints
-	GoogleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
-	GoogleTokenURL = "https://oauth2.googleapis.com/token"
+	GoogleAuthURL     = "https://accounts.google.com/o/oauth2/v2/auth"
+	GoogleTokenURL    = "https://oauth2.googleapis.com/token"
+	GoogleUserInfoURL = "https://www.googleapis.com/oauth2/v2/userinfo"
+	GoogleRevokeURL   = "https://oauth2.googleapis.com/revoke"
 
 	// Anti-Gravity OAuth client ID & Secret
 	ClientID     = "1071006060591-tmhssin2h21lcre235vtolojh4g403ep.apps.googleusercontent.com"
@@ -36,12 +43,33 @@ const (
 	Scopes = "https://www.googleapis.com/auth/cloud-platform https://www.googleapis.com/auth/userinfo.email"
 )
 
+// OAuthEndpoints are the endpoints used to log in, refresh, identify and revoke tokens
+type OAuthEndpoints struct {
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	RevokeURL   string
+}
+
+// Endpoints are the Google endpoints in use. Only tests change them, to run
+// against a fake server; the client secret and refresh tokens are sent there.
+var Endpoints = OAuthEndpoints{
+	AuthURL:     GoogleAuthURL,
+	TokenURL:    GoogleTokenURL,
+	UserInfoURL: GoogleUserInfoURL,
+	RevokeURL:   GoogleRevokeURL,
+}
+
 // GetOAuthConfig returns the OAuth2 configuration
 func GetOAuthConfig() *oauth2.Config {
+	endpoint := google.Endpoint
+	endpoint.AuthURL = Endpoints.AuthURL
+	endpoint.TokenURL = Endpoints.TokenURL
+
 	return &oauth2.Config{
 		ClientID:     ClientID,
 		ClientSecret: ClientSecret,
-		Endpoint:     google.Endpoint,
+		Endpoint:     endpoint,
 		Scopes:       []string{"https://www.googleapis.com/auth/cloud-platform", "https://www.googleapis.com/auth/userinfo.email"},
 		RedirectURL:  RedirectURI,
 	}
@@ -64,6 +92,12 @@ func Login() error {
 		return fmt.Errorf("failed to generate state: %w", err)
 	}
 
+	// Generate PKCE verifier, only its S256 challenge is sent with the authorization request
+	verifier, err := GenerateCodeVerifier()
+	if err != nil {
+		return fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+
 	// Create a new ServeMux for this login session
 	mux := http.NewServeMux()
 
@@ -72,7 +106,7 @@ func Login() error {
 
 	// Handle OAuth2 callback
 	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		handleCallback(w, r, oauthConfig, state, "", resultChan)
+		handleCallback(w, r, oauthConfig, state, verifier, resultChan)
 	})
 
 	// Start local HTTP server for callback on a random port
@@ -104,11 +138,7 @@ func Login() error {
 	}()
 
 	// Build authorization URL
-	authURL := oauthConfig.AuthCodeURL(
-		state,
-		oauth2.SetAuthURLParam("access_type", "offline"),
-		oauth2.SetAuthURLParam("prompt", "consent"),
-	)
+	authURL := buildAuthURL(oauthConfig, state, verifier)
 
 	// Open browser
 	fmt.Println("Opening browser for authentication...")
@@ -148,72 +178,105 @@ func Login() error {
 	return nil
 }
 
-// handleCallback processes the OAuth2 callback
-func handleCallback(w http.ResponseWriter, r *http.Request, config *oauth2.Config, expectedState, verifier string, resultChan chan LoginResult) {
+// buildAuthURL returns the authorization URL for an offline, PKCE-protected (S256) login
+func buildAuthURL(oauthConfig *oauth2.Config, state, verifier string) string {
+	return oauthConfig.AuthCodeURL(
+		state,
+		oauth2.SetAuthURLParam("access_type", "offline"),
+		oauth2.SetAuthURLParam("prompt", "consent"),
+		oauth2.SetAuthURLParam("code_challenge", GenerateCodeChallenge(verifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+}
+
+// callbackError is a failed authorization response, with the status and
+// message shown on the callback page
+type callbackError struct {
+	status  int
+	message string
+	err     error
+}
+
+func (e *callbackError) Error() string {
+	return e.err.Error()
+}
+
+func (e *callbackError) Unwrap() error {
+	return e.err
+}
+
+// completeLogin checks an authorization response (the callback query), exchanges
+// its code using the PKCE verifier and saves the token as the default account.
+// It is shared by the browser callback and the headless flow.
+func completeLogin(ctx context.Context, config *oauth2.Config, query url.Values, expectedState, verifier string) (*LoginResult, error) {
 	// Check for error in callback
-	if errMsg := r.URL.Query().Get("error"); errMsg != "" {
-		errDesc := r.URL.Query().Get("error_description")
-		http.Error(w, "Authentication failed", http.StatusBadRequest)
-		resultChan <- LoginResult{Error: fmt.Errorf("authentication failed: %s - %s", errMsg, errDesc)}
-		return
+	if errMsg := query.Get("error"); errMsg != "" {
+		errDesc := query.Get("error_description")
+		return nil, &callbackError{http.StatusBadRequest, "Authentication failed", fmt.Errorf("authentication failed: %s - %s", errMsg, errDesc)}
 	}
 
 	// Verify state parameter (CSRF protection)
-	state := r.URL.Query().Get("state")
-	if state != expectedState {
-		http.Error(w, "Invalid state parameter", http.StatusBadRequest)
-		resultChan <- LoginResult{Error: fmt.Errorf("invalid state parameter")}
-		return
+	if query.Get("state") != expectedState {
+		return nil, &callbackError{http.StatusBadRequest, "Invalid state parameter", fmt.Errorf("invalid state parameter")}
 	}
 
 	// Get authorization code
-	code := r.URL.Query().Get("code")
+	code := query.Get("code")
 	if code == "" {
-		http.Error(w, "No authorization code received", http.StatusBadRequest)
-		resultChan <- LoginResult{Error: fmt.Errorf("no authorization code received")}
-		return
+		return nil, &callbackError{http.StatusBadRequest, "No authorization code received", fmt.Errorf("no authorization code received")}
 	}
 
 	// Exchange code for token
-	ctx := context.Background()
-	token, err := config.Exchange(ctx, code)
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
 	if err != nil {
-		// Log detailed error for debugging
-		errMsg := fmt.Sprintf("Failed to exchange token: %v", err)
-		fmt.Printf("DEBUG: %s\n", errMsg)
-		http.Error(w, "Failed to exchange token", http.StatusInternalServerError)
-		resultChan <- LoginResult{Error: fmt.Errorf("failed to exchange token: %w", err)}
-		return
+		return nil, &callbackError{http.StatusInternalServerError, "Failed to exchange token", fmt.Errorf("failed to exchange token: %w", err)}
 	}
 
 	// Fetch user email from Google UserInfo API
 	email, err := fetchUserEmail(ctx, token.AccessToken)
 	if err != nil {
-		http.Error(w, "Failed to fetch user email", http.StatusInternalServerError)
-		resultChan <- LoginResult{Error: fmt.Errorf("failed to fetch user email: %w", err)}
-		return
+		return nil, &callbackError{http.StatusInternalServerError, "Failed to fetch user email", fmt.Errorf("failed to fetch user email: %w", err)}
 	}
 
 	// Create token data
 	tokenData := FromOAuth2Token(token, email)
 
+	// Logging in again keeps what the user recorded about the account
+	if existing, errLoad := LoadTokenForAccount(email); errLoad == nil {
+		tokenData.Metadata = existing.Metadata
+	}
+
 	// Save token
-	err = SaveToken(tokenData)
-	if err != nil {
-		http.Error(w, "Failed to save token", http.StatusInternalServerError)
-		resultChan <- LoginResult{Error: fmt.Errorf("failed to save token: %w", err)}
-		return
+	if err := SaveToken(tokenData); err != nil {
+		return nil, &callbackError{http.StatusInternalServerError, "Failed to save token", fmt.Errorf("failed to save token: %w", err)}
 	}
+	_ = RecordAccountActivity(email, nil)
 
 	// Set the logged-in account as default
 	if mgr, mErr := NewAccountManager(); mErr == nil {
 		_ = mgr.SetDefaultAccount(email)
 	}
 
+	return &LoginResult{Token: tokenData, Email: tokenData.Email}, nil
+}
+
+// handleCallback processes the OAuth2 callback
+func handleCallback(w http.ResponseWriter, r *http.Request, config *oauth2.Config, expectedState, verifier string, resultChan chan LoginResult) {
+	result, err := completeLogin(context.Background(), config, r.URL.Query(), expectedState, verifier)
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Authentication failed"
+		var cbErr *callbackError
+		if errors.As(err, &cbErr) {
+			status, message = cbErr.status, cbErr.message
+		}
+		http.Error(w, message, status)
+		resultChan <- LoginResult{Error: err}
+		return
+	}
+
 	// Send success response
 	w.Header().Set("Content-Type", "text/html")
-	var tmpl *template.Template
-	tmpl, err = template.New("callback").Parse(callbackHTML)
+	tmpl, err := template.New("callback").Parse(callbackHTML)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		resultChan <- LoginResult{Error: fmt.Errorf("failed to parse callback template: %w", err)}
@@ -223,7 +286,7 @@ func handleCallback(w http.ResponseWriter, r *http.Request, config *oauth2.Confi
 	data := struct {
 		Email string
 	}{
-		Email: email,
+		Email: result.Email,
 	}
 
 	err = tmpl.Execute(w, data)
@@ -232,15 +295,86 @@ func handleCallback(w http.ResponseWriter, r *http.Request, config *oauth2.Confi
 	}
 
 	// Send result
-	resultChan <- LoginResult{
-		Token: tokenData,
-		Email: email,
-		Error: nil,
+	resultChan <- *result
+}
+
+// LoginNoBrowser runs the login flow without a browser or callback server, e.g. over SSH.
+// It prints the authorization URL to out and reads the redirect URL, or just the code, from in.
+func LoginNoBrowser(in io.Reader, out io.Writer) error {
+	oauthConfig := GetOAuthConfig()
+
+	state, err := GenerateState()
+	if err != nil {
+		return fmt.Errorf("failed to generate state: %w", err)
+	}
+
+	verifier, err := GenerateCodeVerifier()
+	if err != nil {
+		return fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+
+	authURL := buildAuthURL(oauthConfig, state, verifier)
+
+	fmt.Fprintln(out, "Open this URL in a browser on any machine and sign in:")
+	fmt.Fprintln(out, authURL)
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Afterwards the browser is redirected to a 127.0.0.1 page that fails to load.")
+	fmt.Fprintln(out, "Copy the full URL from its address bar (or only the code parameter) and paste it here.")
+	fmt.Fprint(out, "Redirect URL or code: ")
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && (err != io.EOF || strings.TrimSpace(line) == "") {
+		return fmt.Errorf("failed to read authorization response: %w", err)
+	}
+
+	query, err := parseAuthResponse(strings.TrimSpace(line), state)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	result, err := completeLogin(ctx, oauthConfig, query, state, verifier)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "\nLogin successful!")
+	fmt.Fprintf(out, "Logged in as: %s\n", result.Email)
+	return nil
+}
+
+// parseAuthResponse extracts the callback parameters from a pasted redirect URL,
+// query string or bare authorization code
+func parseAuthResponse(input, state string) (url.Values, error) {
+	if input == "" {
+		return nil, fmt.Errorf("no authorization response entered")
+	}
+
+	if !strings.Contains(input, "code=") && !strings.Contains(input, "error=") {
+		// A bare code carries no state. The PKCE verifier still ties it to this login.
+		return url.Values{"code": {input}, "state": {state}}, nil
+	}
+
+	raw := input
+	if i := strings.Index(raw, "?"); i >= 0 {
+		raw = raw[i+1:]
+	}
+	if i := strings.Index(raw, "#"); i >= 0 {
+		raw = raw[:i]
+	}
+
+	query, err := url.ParseQuery(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect URL: %w", err)
 	}
+	return query, nil
 }
 
-// openBrowser opens the default browser to the specified URL
-func openBrowser(url string) error {
+// openBrowser opens the default browser to the specified URL.
+// It is a variable so tests can follow the URL instead.
+var openBrowser = func(url string) error {
 	var cmd string
 	var args []string
 
@@ -266,7 +400,7 @@ type userInfo struct {
 
 // fetchUserEmail retrieves the user's email address using the access token
 func fetchUserEmail(ctx context.Context, accessToken string) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://www.googleapis.com/oauth2/v2/userinfo", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", Endpoints.UserInfoURL, nil)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/fakeserver"
+	"golang.org/x/oauth2"
+)
+
+// useFakeEndpoints points the OAuth endpoints at the fake server for the duration of the test
+func useFakeEndpoints(t *testing.T, srv *fakeserver.Server) {
+	t.Helper()
+
+	endpoints := Endpoints
+	Endpoints = OAuthEndpoints{AuthURL: srv.AuthURL(), TokenURL: srv.TokenURL(), UserInfoURL: srv.UserInfoURL(), RevokeURL: srv.RevokeURL()}
+	t.Cleanup(func() { Endpoints = endpoints })
+}
+
+// authorize follows an authorization URL on the fake server and returns the issued code
+func authorize(t *testing.T, authURL string) string {
+	t.Helper()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorization request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+	code := location.Query().Get("code")
+	if code == "" {
+		t.Fatalf("no code in redirect: %s", location)
+	}
+	return code
+}
+
+func TestPKCE_CodeRequiresMatchingVerifier(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	srv.AddAccount(fakeserver.Account{Email: "pkce@example.com"})
+	srv.Authorize("pkce@example.com")
+
+	oauthConfig := &oauth2.Config{
+		ClientID:    ClientID,
+		Endpoint:    srv.OAuthEndpoint(),
+		RedirectURL: "http://127.0.0.1:1/callback",
+	}
+
+	verifier, err := GenerateCodeVerifier()
+	if err != nil {
+		t.Fatalf("GenerateCodeVerifier failed: %v", err)
+	}
+	otherVerifier, err := GenerateCodeVerifier()
+	if err != nil {
+		t.Fatalf("GenerateCodeVerifier failed: %v", err)
+	}
+
+	ctx := context.Background()
+
+	code := authorize(t, buildAuthURL(oauthConfig, "state", verifier))
+	if _, err := oauthConfig.Exchange(ctx, code); err == nil {
+		t.Error("expected a code exchanged without verifier to be rejected")
+	}
+
+	code = authorize(t, buildAuthURL(oauthConfig, "state", verifier))
+	if _, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(otherVerifier)); err == nil {
+		t.Error("expected a code exchanged with another verifier to be rejected")
+	}
+
+	code = authorize(t, buildAuthURL(oauthConfig, "state", verifier))
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		t.Fatalf("expected the matching verifier to be accepted: %v", err)
+	}
+	if token.RefreshToken != srv.RefreshToken("pkce@example.com") {
+		t.Errorf("unexpected refresh token: %s", token.RefreshToken)
+	}
+}
+
+func TestLogin_UsesPKCE(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	useFakeEndpoints(t, srv)
+
+	srv.AddAccount(fakeserver.Account{Email: "login@example.com"})
+	srv.Authorize("login@example.com")
+
+	// Instead of opening a browser, follow the consent redirect back to the callback server
+	original := openBrowser
+	openBrowser = func(authURL string) error {
+		go func() {
+			resp, err := http.Get(authURL)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+	defer func() { openBrowser = original }()
+
+	if err := Login(); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	auth := srv.LastAuthorization()
+	if auth.Get("code_challenge_method") != "S256" || auth.Get("code_challenge") == "" {
+		t.Errorf("authorization request without S256 challenge: %v", auth)
+	}
+
+	token, err := LoadTokenForAccount("login@example.com")
+	if err != nil {
+		t.Fatalf("LoadTokenForAccount failed: %v", err)
+	}
+	if token.RefreshToken != srv.RefreshToken("login@example.com") {
+		t.Errorf("unexpected refresh token: %s", token.RefreshToken)
+	}
+}
+
+// headlessLogin runs LoginNoBrowser, answering the prompt with respond(authURL)
+func headlessLogin(t *testing.T, respond func(authURL string) string) error {
+	t.Helper()
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- LoginNoBrowser(inR, outW)
+		outW.Close()
+	}()
+
+	// The authorization URL is the first line starting with http. The output is
+	// drained in the background so the prompt never blocks on the pipe.
+	urls := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, "http") {
+				urls <- line
+			}
+		}
+	}()
+
+	select {
+	case authURL := <-urls:
+		_, _ = io.WriteString(inW, respond(authURL)+"\n")
+	case err := <-done:
+		return err
+	}
+
+	return <-done
+}
+
+func setupHeadlessLogin(t *testing.T, email string) *fakeserver.Server {
+	t.Helper()
+
+	srv := fakeserver.New()
+	t.Cleanup(srv.Close)
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	useFakeEndpoints(t, srv)
+
+	srv.AddAccount(fakeserver.Account{Email: email})
+	srv.Authorize(email)
+	return srv
+}
+
+// redirectURL follows the consent screen and returns the URL the browser would be redirected to
+func redirectURL(t *testing.T, authURL string) string {
+	t.Helper()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Errorf("authorization request failed: %v", err)
+		return ""
+	}
+	defer resp.Body.Close()
+	return resp.Header.Get("Location")
+}
+
+func TestLoginNoBrowser_PastedRedirectURL(t *testing.T) {
+	srv := setupHeadlessLogin(t, "remote@example.com")
+
+	err := headlessLogin(t, func(authURL string) string { return redirectURL(t, authURL) })
+	if err != nil {
+		t.Fatalf("LoginNoBrowser failed: %v", err)
+	}
+
+	token, err := LoadToken()
+	if err != nil {
+		t.Fatalf("expected the account to be saved as default: %v", err)
+	}
+	if token.Email != "remote@example.com" || token.RefreshToken != srv.RefreshToken("remote@example.com") {
+		t.Errorf("unexpected token: %+v", token)
+	}
+}
+
+func TestLoginNoBrowser_PastedCode(t *testing.T) {
+	setupHeadlessLogin(t, "code@example.com")
+
+	err := headlessLogin(t, func(authURL string) string {
+		location, err := url.Parse(redirectURL(t, authURL))
+		if err != nil {
+			t.Errorf("invalid redirect: %v", err)
+			return ""
+		}
+		return location.Query().Get("code")
+	})
+	if err != nil {
+		t.Fatalf("LoginNoBrowser failed: %v", err)
+	}
+
+	if _, err := LoadTokenForAccount("code@example.com"); err != nil {
+		t.Errorf("token not saved: %v", err)
+	}
+}
+
+func TestLoginNoBrowser_StateMismatch(t *testing.T) {
+	setupHeadlessLogin(t, "csrf@example.com")
+
+	err := headlessLogin(t, func(authURL string) string {
+		return strings.Replace(redirectURL(t, authURL), "state=", "state=forged", 1)
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid state") {
+		t.Fatalf("expected an invalid state error, got %v", err)
+	}
+
+	if _, err := LoadTokenForAccount("csrf@example.com"); err == nil {
+		t.Error("no token must be saved after a state mismatch")
+	}
+}
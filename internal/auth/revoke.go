@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrTokenAlreadyInvalid is returned when Google reports that the token being
+// revoked had already expired or been revoked
+var ErrTokenAlreadyInvalid = errors.New("token was already expired or revoked")
+
+// RevokeToken revokes an OAuth token at Google. Revoking a refresh token also
+// revokes every access token issued from it.
+func RevokeToken(ctx context.Context, token string) error {
+	if token == "" {
+		return fmt.Errorf("no token to revoke")
+	}
+
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, Endpoints.RevokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach revocation endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var oauthErr struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	_ = json.Unmarshal(body, &oauthErr)
+
+	if oauthErr.Error == "invalid_token" {
+		return ErrTokenAlreadyInvalid
+	}
+	if oauthErr.ErrorDescription != "" {
+		return fmt.Errorf("revocation failed: status %d: %s (%s)", resp.StatusCode, oauthErr.Error, oauthErr.ErrorDescription)
+	}
+	if oauthErr.Error != "" {
+		return fmt.Errorf("revocation failed: status %d: %s", resp.StatusCode, oauthErr.Error)
+	}
+	return fmt.Errorf("revocation failed: status %d", resp.StatusCode)
+}
+
+// RevokeAccount revokes the stored grant of an account at Google. The local
+// token is left in place so the caller can decide whether to remove it.
+func RevokeAccount(ctx context.Context, email string) error {
+	token, err := LoadTokenForAccount(email)
+	if err != nil {
+		return err
+	}
+
+	// The refresh token covers the whole grant; fall back to the access token
+	// for tokens saved without one
+	if token.RefreshToken != "" {
+		return RevokeToken(ctx, token.RefreshToken)
+	}
+	return RevokeToken(ctx, token.AccessToken)
+}
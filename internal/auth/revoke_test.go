@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/fakeserver"
+)
+
+func setupRevoke(t *testing.T, email string) *fakeserver.Server {
+	t.Helper()
+
+	srv := fakeserver.New()
+	t.Cleanup(srv.Close)
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	useFakeEndpoints(t, srv)
+
+	accessToken := srv.AddAccount(fakeserver.Account{Email: email})
+	err := SaveTokenForAccount(email, &TokenData{
+		AccessToken:  accessToken,
+		RefreshToken: srv.RefreshToken(email),
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("SaveTokenForAccount failed: %v", err)
+	}
+	return srv
+}
+
+func TestRevokeAccount(t *testing.T) {
+	email := "leaver@example.com"
+	srv := setupRevoke(t, email)
+
+	if err := RevokeAccount(context.Background(), email); err != nil {
+		t.Fatalf("RevokeAccount failed: %v", err)
+	}
+	if !srv.Revoked(email) {
+		t.Error("expected the grant to be revoked at the server")
+	}
+
+	// The local token is left for the caller to remove
+	token, err := LoadTokenForAccount(email)
+	if err != nil {
+		t.Fatalf("expected local token to remain: %v", err)
+	}
+
+	// The refresh token no longer works
+	if _, err := ForceRefreshTokenForAccount(context.Background(), email, token.AccessToken, GetOAuthConfig()); err == nil {
+		t.Error("expected refresh with a revoked token to fail")
+	}
+
+	// Revoking again reports the token as already invalid
+	err = RevokeAccount(context.Background(), email)
+	if !errors.Is(err, ErrTokenAlreadyInvalid) {
+		t.Errorf("expected ErrTokenAlreadyInvalid, got %v", err)
+	}
+}
+
+func TestRevokeToken_ServerError(t *testing.T) {
+	email := "flaky@example.com"
+	srv := setupRevoke(t, email)
+	srv.InjectFaults(fakeserver.MethodRevoke, fakeserver.ServerError(http.StatusServiceUnavailable))
+
+	err := RevokeAccount(context.Background(), email)
+	if err == nil || errors.Is(err, ErrTokenAlreadyInvalid) {
+		t.Fatalf("expected a revocation failure, got %v", err)
+	}
+	if srv.Revoked(email) {
+		t.Error("grant must not be revoked when the server failed")
+	}
+}
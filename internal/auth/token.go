@@ -3,12 +3,14 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/gundamkid/anti-gravity-quota/internal/config"
+	"github.com/gundamkid/anti-gravity-quota/internal/filelock"
 	"golang.org/x/oauth2"
 )
 
@@ -17,12 +19,35 @@ var (
 	locks sync.Map
 )
 
+// AccountLockTimeout bounds how long a process waits for another one that is
+// refreshing or updating the same account
+var AccountLockTimeout = filelock.DefaultTimeout
+
 func getLock(email string) *sync.Mutex {
 	val, _ := locks.LoadOrStore(email, &sync.Mutex{})
 	lock, _ := val.(*sync.Mutex)
 	return lock
 }
 
+// lockAccount takes the in-process lock of an account and the lock file shared
+// with other ag-quota processes. It must be held around every read-modify-write
+// of the account file, e.g. a token refresh. The returned function releases both.
+func lockAccount(email string) (func(), error) {
+	mu := getLock(email)
+	mu.Lock()
+
+	fileLock, err := config.LockFile(config.AccountFileName(email), AccountLockTimeout)
+	if err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("account %s is busy: %w", email, err)
+	}
+
+	return func() {
+		_ = fileLock.Release()
+		mu.Unlock()
+	}, nil
+}
+
 // TokenData represents stored OAuth2 token information
 type TokenData struct {
 	AccessToken  string    `json:"access_token"`
@@ -31,6 +56,17 @@ type TokenData struct {
 	Expiry       time.Time `json:"expiry"`
 	Email        string    `json:"email,omitempty"`
 	TierName     string    `json:"tier_name,omitempty"`
+
+	// Project metadata resolved through loadCodeAssist, cached to skip that call on later fetches
+	ProjectID         string    `json:"project_id,omitempty"`
+	TierID            string    `json:"tier_id,omitempty"`
+	ProjectResolvedAt time.Time `json:"project_resolved_at,omitzero"`
+
+	// Onboarding that was started but had not completed when the last run gave up
+	OnboardingTierID    string    `json:"onboarding_tier_id,omitempty"`
+	OnboardingStartedAt time.Time `json:"onboarding_started_at,omitzero"`
+
+	Metadata AccountMetadata `json:"metadata,omitzero"`
 }
 
 // SaveToken saves the token data to the current default account
@@ -60,36 +96,41 @@ func SaveToken(token *TokenData) error {
 	return SaveTokenForAccount(email, token)
 }
 
-// LoadToken loads the token data from the current default account
+// LoadToken loads the token data from the current default account.
+// It returns ErrNotLoggedIn or ErrNoDefaultAccount if there is no default account.
 func LoadToken() (*TokenData, error) {
 	mgr, err := NewAccountManager()
 	if err != nil {
 		return nil, err
 	}
 
-	cfg, err := mgr.LoadConfig()
+	email, err := mgr.DefaultAccount()
 	if err != nil {
 		return nil, err
 	}
 
-	if cfg.DefaultAccount == "" {
-		return nil, fmt.Errorf("no default account set. please login first")
-	}
-
-	return LoadTokenForAccount(cfg.DefaultAccount)
+	return LoadTokenForAccount(email)
 }
 
-// SaveTokenForAccount saves token data for a specific account
+// SaveTokenForAccount saves token data for a specific account. Like every function
+// taking an account email, it stores the account under its canonical ID.
 func SaveTokenForAccount(email string, token *TokenData) error {
-	lock := getLock(email)
-	lock.Lock()
-	defer lock.Unlock()
+	email, err := config.CanonicalAccountID(email)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockAccount(email)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	return saveTokenForAccount(email, token)
 }
 
 // saveTokenForAccount is the internal version of SaveTokenForAccount that doesn't acquire locks.
-// It must be called while holding the lock for the account.
+// It must be called with the canonical account ID while holding the lock for the account.
 func saveTokenForAccount(email string, token *TokenData) error {
 	if _, err := config.EnsureAccountsDir(); err != nil {
 		return err
@@ -108,11 +149,53 @@ func saveTokenForAccount(email string, token *TokenData) error {
 		return fmt.Errorf("failed to marshal token: %w", err)
 	}
 
-	return config.AtomicWrite(tokenPath, data, 0600)
+	store, err := ConfiguredSecretStore()
+	if err != nil {
+		return err
+	}
+
+	sealed, err := store.Seal(email, data)
+	if err != nil {
+		return err
+	}
+
+	if err := config.AtomicWrite(tokenPath, sealed, 0600); err != nil {
+		return err
+	}
+
+	return indexAccount(email)
+}
+
+// UpdateTokenForAccount loads the token of an account, applies fn to it and saves it
+// while holding the account lock, so concurrent refreshes are not overwritten
+func UpdateTokenForAccount(email string, fn func(token *TokenData)) error {
+	email, err := config.CanonicalAccountID(email)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockAccount(email)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	token, err := loadTokenForAccount(email)
+	if err != nil {
+		return err
+	}
+
+	fn(token)
+	return saveTokenForAccount(email, token)
 }
 
 // LoadTokenForAccount loads token data for a specific account
 func LoadTokenForAccount(email string) (*TokenData, error) {
+	email, err := config.CanonicalAccountID(email)
+	if err != nil {
+		return nil, err
+	}
+
 	lock := getLock(email)
 	lock.Lock()
 	defer lock.Unlock()
@@ -121,7 +204,7 @@ func LoadTokenForAccount(email string) (*TokenData, error) {
 }
 
 // loadTokenForAccount is the internal version of LoadTokenForAccount that doesn't acquire locks.
-// It must be called while holding the lock for the account.
+// It must be called with the canonical account ID while holding the lock for the account.
 func loadTokenForAccount(email string) (*TokenData, error) {
 	tokenPath, err := config.GetAccountPath(email)
 	if err != nil {
@@ -136,6 +219,11 @@ func loadTokenForAccount(email string) (*TokenData, error) {
 		return nil, fmt.Errorf("failed to read token file: %w", err)
 	}
 
+	data, err = openTokenFile(email, data)
+	if err != nil {
+		return nil, err
+	}
+
 	var token TokenData
 	err = json.Unmarshal(data, &token)
 	if err != nil {
@@ -145,29 +233,23 @@ func loadTokenForAccount(email string) (*TokenData, error) {
 	return &token, nil
 }
 
-// DeleteToken removes the stored token file for the current default account
+// DeleteToken removes the stored token of the current default account and
+// promotes another account to default
 func DeleteToken() error {
 	mgr, err := NewAccountManager()
 	if err != nil {
 		return err
 	}
 
-	cfg, err := mgr.LoadConfig()
+	email, err := mgr.DefaultAccount()
 	if err != nil {
+		if errors.Is(err, ErrNotLoggedIn) || errors.Is(err, ErrNoDefaultAccount) {
+			return nil // Nothing to delete
+		}
 		return err
 	}
 
-	if cfg.DefaultAccount == "" {
-		return nil // Nothing to delete
-	}
-
-	tokenPath, err := config.GetAccountPath(cfg.DefaultAccount)
-	if err != nil {
-		return fmt.Errorf("failed to get token path: %w", err)
-	}
-
-	err = os.Remove(tokenPath)
-	if err != nil && !os.IsNotExist(err) {
+	if err := mgr.RemoveAccount(email); err != nil && !errors.Is(err, ErrAccountNotFound) {
 		return fmt.Errorf("failed to delete token file: %w", err)
 	}
 
@@ -184,6 +266,18 @@ func (t *TokenData) IsValid() bool {
 	return t.AccessToken != "" && !t.IsExpired()
 }
 
+// HasFreshProject reports whether a project ID is cached and was resolved less than ttl ago
+func (t *TokenData) HasFreshProject(ttl time.Duration) bool {
+	return t.ProjectID != "" && time.Since(t.ProjectResolvedAt) < ttl
+}
+
+// ClearProject drops the cached project metadata so it is resolved again on the next fetch
+func (t *TokenData) ClearProject() {
+	t.ProjectID = ""
+	t.TierID = ""
+	t.ProjectResolvedAt = time.Time{}
+}
+
 // ToOAuth2Token converts TokenData to oauth2.Token
 func (t *TokenData) ToOAuth2Token() *oauth2.Token {
 	return &oauth2.Token{
@@ -205,32 +299,6 @@ func FromOAuth2Token(token *oauth2.Token, email string) *TokenData {
 	}
 }
 
-// RefreshToken refreshes an expired token using the refresh token
-func RefreshToken(token *TokenData, oauthConfig *oauth2.Config) (*TokenData, error) {
-	if token.RefreshToken == "" {
-		return nil, fmt.Errorf("no refresh token available")
-	}
-
-	// Create token source
-	tokenSource := oauthConfig.TokenSource(context.Background(), token.ToOAuth2Token())
-
-	// Get fresh token
-	newToken, err := tokenSource.Token()
-	if err != nil {
-		return nil, fmt.Errorf("failed to refresh token: %w", err)
-	}
-
-	// Create new TokenData with refreshed token
-	refreshedToken := FromOAuth2Token(newToken, token.Email)
-
-	// Save the refreshed token
-	if err := SaveToken(refreshedToken); err != nil {
-		return nil, fmt.Errorf("failed to save refreshed token: %w", err)
-	}
-
-	return refreshedToken, nil
-}
-
 // GetValidToken returns a valid access token for the default account, refreshing if necessary
 func GetValidToken(oauthConfig *oauth2.Config) (string, error) {
 	token, err := LoadToken()
@@ -243,14 +311,30 @@ func GetValidToken(oauthConfig *oauth2.Config) (string, error) {
 
 // GetValidTokenForAccount returns a valid access token for a specific account, refreshing if necessary
 func GetValidTokenForAccount(email string, oauthConfig *oauth2.Config) (string, error) {
-	// Use a lock for this account to prevent concurrent refreshes
-	lock := getLock(email)
-	lock.Lock()
-	defer lock.Unlock()
+	email, err := config.CanonicalAccountID(email)
+	if err != nil {
+		return "", err
+	}
+
+	// Most calls find a valid token and need no lock shared with other processes
+	token, err := LoadTokenForAccount(email)
+	if err != nil {
+		return "", err
+	}
+	if token.IsValid() {
+		return token.AccessToken, nil
+	}
+
+	// Use a lock for this account to prevent concurrent refreshes, also by other processes
+	unlock, err := lockAccount(email)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
 
 	// Load existing token for the account again while holding the lock
-	// to ensure we have the latest one (it might have been refreshed by another goroutine)
-	token, err := loadTokenForAccount(email)
+	// to ensure we have the latest one (it might have been refreshed by another goroutine or process)
+	token, err = loadTokenForAccount(email)
 	if err != nil {
 		return "", err
 	}
@@ -265,22 +349,108 @@ func GetValidTokenForAccount(email string, oauthConfig *oauth2.Config) (string,
 		return "", fmt.Errorf("token expired and no refresh token available for %s, please login again", email)
 	}
 
-	// Create token source
-	tokenSource := oauthConfig.TokenSource(context.Background(), token.ToOAuth2Token())
+	refreshedToken, err := refreshTokenForAccount(context.Background(), email, token, oauthConfig)
+	if err != nil {
+		return "", err
+	}
+
+	return refreshedToken.AccessToken, nil
+}
+
+// ForceRefreshTokenForAccount refreshes the access token of an account even if it has not expired yet,
+// e.g. after the API rejected it. If the stored token no longer matches staleToken, another
+// caller already refreshed it and the stored token is returned instead.
+func ForceRefreshTokenForAccount(ctx context.Context, email, staleToken string, oauthConfig *oauth2.Config) (string, error) {
+	email, err := config.CanonicalAccountID(email)
+	if err != nil {
+		return "", err
+	}
+
+	unlock, err := lockAccount(email)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	token, err := loadTokenForAccount(email)
+	if err != nil {
+		return "", err
+	}
+
+	if token.AccessToken != staleToken && token.IsValid() {
+		return token.AccessToken, nil
+	}
+
+	if token.RefreshToken == "" {
+		return "", fmt.Errorf("token rejected and no refresh token available for %s, please login again", email)
+	}
+
+	refreshedToken, err := refreshTokenForAccount(ctx, email, token, oauthConfig)
+	if err != nil {
+		return "", err
+	}
+
+	return refreshedToken.AccessToken, nil
+}
+
+// refreshTokenForAccount exchanges the refresh token for a new access token and saves it.
+// It must be called while holding lockAccount for the account.
+func refreshTokenForAccount(ctx context.Context, email string, token *TokenData, oauthConfig *oauth2.Config) (*TokenData, error) {
+	// Only pass the refresh token so the token source always performs a refresh
+	tokenSource := oauthConfig.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken})
 
 	// Get fresh token
 	newToken, err := tokenSource.Token()
 	if err != nil {
-		return "", fmt.Errorf("failed to refresh token for %s: %w", email, err)
+		err = fmt.Errorf("failed to refresh token for %s: %w", email, err)
+		_ = RecordAccountActivity(email, err)
+		return nil, err
 	}
 
-	// Create new TokenData with refreshed token
-	refreshedToken := FromOAuth2Token(newToken, email)
+	// Copy the stored token so its metadata is kept, then apply the refreshed credentials
+	refreshedToken := *token
+	refreshedToken.Email = email
+	refreshedToken.AccessToken = newToken.AccessToken
+	refreshedToken.TokenType = newToken.TokenType
+	refreshedToken.Expiry = newToken.Expiry
+	if newToken.RefreshToken != "" {
+		refreshedToken.RefreshToken = newToken.RefreshToken
+	}
 
 	// Save the refreshed token for this account
-	if err := saveTokenForAccount(email, refreshedToken); err != nil {
-		return "", fmt.Errorf("failed to save refreshed token for %s: %w", email, err)
+	if err := saveTokenForAccount(email, &refreshedToken); err != nil {
+		return nil, fmt.Errorf("failed to save refreshed token for %s: %w", email, err)
 	}
+	_ = RecordAccountActivity(email, nil)
 
-	return refreshedToken.AccessToken, nil
+	return &refreshedToken, nil
+}
+
+// AccountTokenSource provides access tokens for a single account, refreshing them when needed
+type AccountTokenSource struct {
+	email       string
+	oauthConfig *oauth2.Config
+}
+
+// NewAccountTokenSource creates a token source bound to the given account
+func NewAccountTokenSource(email string, oauthConfig *oauth2.Config) *AccountTokenSource {
+	return &AccountTokenSource{
+		email:       email,
+		oauthConfig: oauthConfig,
+	}
+}
+
+// Email returns the account the token source is bound to
+func (s *AccountTokenSource) Email() string {
+	return s.email
+}
+
+// Token returns a valid access token, refreshing it if it has expired
+func (s *AccountTokenSource) Token(ctx context.Context) (string, error) {
+	return GetValidTokenForAccount(s.email, s.oauthConfig)
+}
+
+// Refresh forces a refresh of the access token after staleToken was rejected
+func (s *AccountTokenSource) Refresh(ctx context.Context, staleToken string) (string, error) {
+	return ForceRefreshTokenForAccount(ctx, s.email, staleToken, s.oauthConfig)
 }
@@ -1,10 +1,19 @@
 package auth
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+	"golang.org/x/oauth2"
 )
 
 func TestTokenData_IsValid(t *testing.T) {
@@ -78,6 +87,21 @@ func TestSaveLoadTokenForAccount(t *testing.T) {
 	if loaded.Email != email {
 		t.Errorf("expected %s, got %s", email, loaded.Email)
 	}
+
+	// Unset timestamps are left out of the file
+	path, err := config.GetAccountPath(email)
+	if err != nil {
+		t.Fatalf("GetAccountPath failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read token file: %v", err)
+	}
+	for _, field := range []string{"project_resolved_at", "onboarding_started_at"} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("expected %s to be omitted:\n%s", field, data)
+		}
+	}
 }
 
 func TestSaveLoadTokenConcurrent(t *testing.T) {
@@ -106,3 +130,147 @@ func TestSaveLoadTokenConcurrent(t *testing.T) {
 		<-done
 	}
 }
+
+func TestForceRefreshTokenForAccount(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.Setenv("XDG_CONFIG_HOME", tmpDir)
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+
+	refreshes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refreshes++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token": "new-token-%d", "token_type": "Bearer", "expires_in": 3600}`, refreshes)
+	}))
+	defer server.Close()
+
+	oauthConfig := &oauth2.Config{
+		ClientID: "client",
+		Endpoint: oauth2.Endpoint{TokenURL: server.URL},
+	}
+
+	email := "refresh@example.com"
+	err := SaveTokenForAccount(email, &TokenData{
+		AccessToken:  "revoked-token",
+		RefreshToken: "refresh-token",
+		Expiry:       time.Now().Add(time.Hour),
+		TierName:     "Pro 💎",
+		ProjectID:    "project-1",
+	})
+	if err != nil {
+		t.Fatalf("SaveTokenForAccount failed: %v", err)
+	}
+
+	// A token that is still valid by expiry is refreshed anyway
+	token, err := ForceRefreshTokenForAccount(context.Background(), email, "revoked-token", oauthConfig)
+	if err != nil {
+		t.Fatalf("ForceRefreshTokenForAccount failed: %v", err)
+	}
+	if token != "new-token-1" {
+		t.Errorf("expected new-token-1, got %s", token)
+	}
+
+	saved, err := LoadTokenForAccount(email)
+	if err != nil {
+		t.Fatalf("LoadTokenForAccount failed: %v", err)
+	}
+	if saved.AccessToken != "new-token-1" || saved.RefreshToken != "refresh-token" || saved.TierName != "Pro 💎" || saved.ProjectID != "project-1" {
+		t.Errorf("refreshed token not saved correctly: %+v", saved)
+	}
+
+	// A caller holding the old token gets the already refreshed one without another refresh
+	token, err = ForceRefreshTokenForAccount(context.Background(), email, "revoked-token", oauthConfig)
+	if err != nil {
+		t.Fatalf("ForceRefreshTokenForAccount failed: %v", err)
+	}
+	if token != "new-token-1" || refreshes != 1 {
+		t.Errorf("expected reuse of new-token-1, got %s after %d refreshes", token, refreshes)
+	}
+}
+
+func TestTokenData_HasFreshProject(t *testing.T) {
+	token := &TokenData{ProjectID: "project-1", TierID: "free-tier", ProjectResolvedAt: time.Now().Add(-time.Hour)}
+
+	if !token.HasFreshProject(2 * time.Hour) {
+		t.Error("expected project resolved an hour ago to be fresh")
+	}
+	if token.HasFreshProject(30 * time.Minute) {
+		t.Error("expected project older than the TTL to be stale")
+	}
+
+	token.ClearProject()
+	if token.HasFreshProject(2*time.Hour) || token.TierID != "" {
+		t.Errorf("expected cleared project metadata, got %+v", token)
+	}
+}
+
+// envRefreshHelper makes the test binary act as one of the processes of
+// TestGetValidTokenForAccount_MultiProcess; it holds the token endpoint URL
+const envRefreshHelper = "AG_QUOTA_REFRESH_HELPER"
+
+func TestGetValidTokenForAccount_MultiProcess(t *testing.T) {
+	const processes = 4
+
+	var refreshes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := refreshes.Add(1)
+		time.Sleep(50 * time.Millisecond) // keep the refresh in flight while the others start
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token": "new-token-%d", "token_type": "Bearer", "expires_in": 3600}`, n)
+	}))
+	defer server.Close()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	err := SaveTokenForAccount("shared@example.com", &TokenData{
+		AccessToken:  "expired-token",
+		RefreshToken: "refresh-token",
+		Expiry:       time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("SaveTokenForAccount failed: %v", err)
+	}
+
+	cmds := make([]*exec.Cmd, processes)
+	outputs := make([]*strings.Builder, processes)
+	for i := range cmds {
+		outputs[i] = &strings.Builder{}
+		cmd := exec.Command(os.Args[0], "-test.run=^TestRefreshHelperProcess$")
+		cmd.Env = append(os.Environ(), envRefreshHelper+"="+server.URL)
+		cmd.Stdout = outputs[i]
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("failed to start process %d: %v", i, err)
+		}
+		cmds[i] = cmd
+	}
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("process %d failed: %v", i, err)
+		}
+	}
+
+	if n := refreshes.Load(); n != 1 {
+		t.Errorf("expected one refresh across all processes, got %d", n)
+	}
+	for i, out := range outputs {
+		if !strings.Contains(out.String(), "token=new-token-1\n") {
+			t.Errorf("process %d did not get the refreshed token:\n%s", i, out.String())
+		}
+	}
+}
+
+// TestRefreshHelperProcess fetches a valid token for
+// TestGetValidTokenForAccount_MultiProcess and prints it
+func TestRefreshHelperProcess(t *testing.T) {
+	tokenURL := os.Getenv(envRefreshHelper)
+	if tokenURL == "" {
+		return
+	}
+
+	oauthConfig := &oauth2.Config{ClientID: "client", Endpoint: oauth2.Endpoint{TokenURL: tokenURL}}
+	token, err := GetValidTokenForAccount("shared@example.com", oauthConfig)
+	if err != nil {
+		t.Fatalf("GetValidTokenForAccount failed: %v", err)
+	}
+	fmt.Printf("token=%s\n", token)
+}
@@ -0,0 +1,524 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+)
+
+// Vault backends that can be selected in config.VaultSettings.Backend
+const (
+	VaultPlaintext = "plaintext"
+	VaultEncrypted = "encrypted"
+	VaultKeyring   = "keyring"
+)
+
+const (
+	// EnvVaultPassphrase unlocks the encrypted vault without prompting
+	EnvVaultPassphrase = "AG_QUOTA_PASSPHRASE"
+	// EnvVaultKeyFile points to a key file that unlocks the encrypted vault
+	EnvVaultKeyFile = "AG_QUOTA_KEY_FILE"
+
+	vaultVersion = 1
+	vaultKDF     = "pbkdf2-sha256"
+	keyringLabel = "ag-quota"
+)
+
+var (
+	// ErrVaultLocked is returned when the encrypted vault is used without a passphrase or key file
+	ErrVaultLocked = errors.New("token vault is locked")
+	// ErrVaultDecrypt is returned when an account file cannot be decrypted with the given secret
+	ErrVaultDecrypt = errors.New("wrong vault passphrase or key file")
+
+	// PassphrasePrompt asks the user for the vault passphrase when neither
+	// AG_QUOTA_PASSPHRASE nor a key file is set. Nil disables prompting.
+	PassphrasePrompt func() (string, error)
+
+	// pbkdf2Iterations is the work factor for new encrypted files. Existing files
+	// keep the count they were sealed with.
+	pbkdf2Iterations = 600000
+
+	// keyringCommand runs secret-tool, replaced in tests
+	keyringCommand = runSecretTool
+
+	// writeAccountFile replaces an account file, replaced in tests to inject failures
+	writeAccountFile = config.AtomicWrite
+
+	passphraseMu sync.Mutex
+	passphrase   string
+
+	derivedKeys sync.Map // derivation inputs -> AES key
+	sealSalt    []byte
+	sealSaltMu  sync.Mutex
+)
+
+// SecretStore decides how the serialized token of an account is kept at rest.
+// The account file always exists so accounts can be listed: a store may keep
+// the token as is, encrypt it, or replace it with a reference to a keyring entry.
+type SecretStore interface {
+	// Name identifies the store in the config and inside sealed account files
+	Name() string
+	// Seal turns the serialized token into the contents of the account file
+	Seal(email string, data []byte) ([]byte, error)
+	// Open returns the serialized token from the contents of the account file
+	Open(email string, sealed []byte) ([]byte, error)
+	// Delete removes anything the store keeps outside the account file
+	Delete(email string) error
+}
+
+// sealedFile is the account file of every store except plaintext
+type sealedFile struct {
+	Vault      string `json:"vault"`
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt,omitempty"`
+	Nonce      []byte `json:"nonce,omitempty"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+}
+
+// UnlockVault sets the passphrase of the encrypted vault for this process
+func UnlockVault(secret string) {
+	passphraseMu.Lock()
+	defer passphraseMu.Unlock()
+	passphrase = secret
+}
+
+// ConfiguredSecretStore returns the store new tokens are saved with
+func ConfiguredSecretStore() (SecretStore, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return secretStoreByName(cfg.Vault.Backend, cfg.Vault)
+}
+
+func secretStoreByName(name string, settings config.VaultSettings) (SecretStore, error) {
+	switch name {
+	case "", VaultPlaintext:
+		return plaintextStore{}, nil
+	case VaultEncrypted:
+		return &encryptedStore{keyFile: settings.KeyFile}, nil
+	case VaultKeyring:
+		return keyringStore{}, nil
+	default:
+		return nil, fmt.Errorf("unknown vault backend %q (expected %s, %s or %s)", name, VaultPlaintext, VaultEncrypted, VaultKeyring)
+	}
+}
+
+// storeOf returns the store an account file was sealed with
+func storeOf(data []byte) (SecretStore, error) {
+	var header struct {
+		Vault string `json:"vault"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse token file: %w", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return secretStoreByName(header.Vault, cfg.Vault)
+}
+
+// openTokenFile returns the serialized token from the contents of an account file
+func openTokenFile(email string, data []byte) ([]byte, error) {
+	store, err := storeOf(data)
+	if err != nil {
+		return nil, err
+	}
+	return store.Open(email, data)
+}
+
+// removeTokenFile deletes the account file and whatever its store keeps elsewhere.
+// The error of os.Remove is returned as is so callers can check os.IsNotExist.
+func removeTokenFile(email string) error {
+	path, err := config.GetAccountPath(email)
+	if err != nil {
+		return err
+	}
+
+	if data, errRead := os.ReadFile(path); errRead == nil {
+		if store, errStore := storeOf(data); errStore == nil {
+			if errDel := store.Delete(email); errDel != nil {
+				return fmt.Errorf("failed to delete %s secret: %w", store.Name(), errDel)
+			}
+		}
+	}
+
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	return unindexAccount(email)
+}
+
+// plaintextStore keeps the token as JSON, protected only by file permissions
+type plaintextStore struct{}
+
+func (plaintextStore) Name() string { return VaultPlaintext }
+
+func (plaintextStore) Seal(_ string, data []byte) ([]byte, error) { return data, nil }
+
+func (plaintextStore) Open(_ string, sealed []byte) ([]byte, error) { return sealed, nil }
+
+func (plaintextStore) Delete(string) error { return nil }
+
+// encryptedStore encrypts the token with AES-256-GCM. The key is derived with
+// PBKDF2 from a passphrase or the contents of a key file, and the account email
+// is authenticated so files cannot be swapped between accounts.
+type encryptedStore struct {
+	keyFile string
+}
+
+func (s *encryptedStore) Name() string { return VaultEncrypted }
+
+func (s *encryptedStore) Seal(email string, data []byte) ([]byte, error) {
+	secret, err := s.secret()
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := currentSealSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(secret, salt, pbkdf2Iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return json.MarshalIndent(sealedFile{
+		Vault:      VaultEncrypted,
+		Version:    vaultVersion,
+		KDF:        vaultKDF,
+		Iterations: pbkdf2Iterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, data, []byte(email)),
+	}, "", "  ")
+}
+
+func (s *encryptedStore) Open(email string, sealed []byte) ([]byte, error) {
+	var file sealedFile
+	if err := json.Unmarshal(sealed, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted token file: %w", err)
+	}
+	if file.Version != vaultVersion || file.KDF != vaultKDF {
+		return nil, fmt.Errorf("unsupported encrypted token file (version %d, kdf %q)", file.Version, file.KDF)
+	}
+
+	secret, err := s.secret()
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(secret, file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce in encrypted token file")
+	}
+
+	data, err := gcm.Open(nil, file.Nonce, file.Ciphertext, []byte(email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token for %s: %w", email, ErrVaultDecrypt)
+	}
+	return data, nil
+}
+
+func (s *encryptedStore) Delete(string) error { return nil }
+
+// secret returns what the key is derived from: AG_QUOTA_PASSPHRASE, then the key
+// file (AG_QUOTA_KEY_FILE or the configured one), then the unlocked or prompted passphrase
+func (s *encryptedStore) secret() (string, error) {
+	if env := os.Getenv(EnvVaultPassphrase); env != "" {
+		return env, nil
+	}
+
+	keyFile := s.keyFile
+	if env := os.Getenv(EnvVaultKeyFile); env != "" {
+		keyFile = env
+	}
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read vault key file: %w", err)
+		}
+		key := strings.TrimSpace(string(data))
+		if key == "" {
+			return "", fmt.Errorf("vault key file %s is empty", keyFile)
+		}
+		return key, nil
+	}
+
+	passphraseMu.Lock()
+	defer passphraseMu.Unlock()
+
+	if passphrase == "" && PassphrasePrompt != nil {
+		entered, err := PassphrasePrompt()
+		if err != nil {
+			return "", fmt.Errorf("failed to read vault passphrase: %w", err)
+		}
+		passphrase = entered
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("%w: set %s or configure a key file", ErrVaultLocked, EnvVaultPassphrase)
+	}
+	return passphrase, nil
+}
+
+// GenerateVaultKeyFile writes a new random key file readable only by the owner
+func GenerateVaultKeyFile(path string) error {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(key) + "\n"
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return nil
+}
+
+// currentSealSalt returns the salt used for files sealed by this process, so the
+// expensive key derivation runs once per run rather than once per account
+func currentSealSalt() ([]byte, error) {
+	sealSaltMu.Lock()
+	defer sealSaltMu.Unlock()
+
+	if sealSalt == nil {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		sealSalt = salt
+	}
+	return sealSalt, nil
+}
+
+func newGCM(secret string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if len(salt) == 0 || iterations <= 0 {
+		return nil, fmt.Errorf("invalid key derivation parameters")
+	}
+
+	// Cache derived keys by a digest of their inputs rather than the secret itself
+	digest := sha256.Sum256(bytes.Join([][]byte{[]byte(secret), salt, fmt.Appendf(nil, "%d", iterations)}, []byte{0}))
+	cacheKey := hex.EncodeToString(digest[:])
+
+	var key []byte
+	if cached, ok := derivedKeys.Load(cacheKey); ok {
+		key = cached.([]byte)
+	} else {
+		derived, err := pbkdf2.Key(sha256.New, secret, salt, iterations, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive vault key: %w", err)
+		}
+		derivedKeys.Store(cacheKey, derived)
+		key = derived
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyringStore keeps the token in the Secret Service keyring (GNOME Keyring,
+// KWallet) through libsecret's secret-tool; the account file only marks it
+type keyringStore struct{}
+
+func (keyringStore) Name() string { return VaultKeyring }
+
+func (keyringStore) Seal(email string, data []byte) ([]byte, error) {
+	_, err := keyringCommand(data, "store", "--label", keyringLabel+" "+email, "service", keyringLabel, "account", email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store token in keyring: %w", err)
+	}
+
+	return json.MarshalIndent(sealedFile{Vault: VaultKeyring, Version: vaultVersion}, "", "  ")
+}
+
+func (keyringStore) Open(email string, _ []byte) ([]byte, error) {
+	data, err := keyringCommand(nil, "lookup", "service", keyringLabel, "account", email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token from keyring: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("token for account %s not found in keyring", email)
+	}
+	return data, nil
+}
+
+func (keyringStore) Delete(email string) error {
+	_, err := keyringCommand(nil, "clear", "service", keyringLabel, "account", email)
+	return err
+}
+
+func runSecretTool(stdin []byte, args ...string) ([]byte, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return nil, fmt.Errorf("secret-tool not found, install libsecret to use the keyring backend")
+	}
+
+	cmd := exec.Command(path, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("secret-tool %s: %s", args[0], msg)
+		}
+		// secret-tool lookup exits with 1 when nothing is stored
+		if args[0] == "lookup" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("secret-tool %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// ReconfigureVault switches the vault settings and re-saves every account with
+// them. Tokens are read with the current settings first, so this also changes the
+// key file of the encrypted backend, or its passphrase when called with unchanged
+// settings. newPassphrase unlocks the new vault when it is encrypted with a
+// passphrase; it may be empty otherwise.
+//
+// Every token is sealed before any file is replaced and the config is switched
+// last. If a step fails, the files already replaced are restored, so the vault is
+// never left half sealed with the old settings and half with the new ones.
+func ReconfigureVault(settings config.VaultSettings, newPassphrase string) (int, error) {
+	store, err := secretStoreByName(settings.Backend, settings)
+	if err != nil {
+		return 0, err
+	}
+
+	accountsDir, err := config.EnsureAccountsDir()
+	if err != nil {
+		return 0, err
+	}
+	emails, err := listAccountEmails(accountsDir)
+	if err != nil {
+		return 0, err
+	}
+
+	// Hold every account lock so no refresh saves with the old settings in between
+	for _, email := range emails {
+		unlock, err := lockAccount(email)
+		if err != nil {
+			return 0, err
+		}
+		defer unlock()
+	}
+
+	paths := make(map[string]string, len(emails))
+	original := make(map[string][]byte, len(emails))
+	tokens := make(map[string]*TokenData, len(emails))
+	previous := make(map[string]SecretStore, len(emails))
+	for _, email := range emails {
+		if paths[email], err = config.GetAccountPath(email); err != nil {
+			return 0, err
+		}
+		if original[email], err = os.ReadFile(paths[email]); err != nil {
+			return 0, fmt.Errorf("failed to read token of %s: %w", email, err)
+		}
+		if previous[email], err = storeOf(original[email]); err != nil {
+			return 0, err
+		}
+		if tokens[email], err = loadTokenForAccount(email); err != nil {
+			return 0, err
+		}
+	}
+
+	passphraseMu.Lock()
+	oldPassphrase := passphrase
+	passphraseMu.Unlock()
+	if newPassphrase != "" {
+		UnlockVault(newPassphrase)
+	}
+
+	// rollback restores the account files written so far and the old passphrase,
+	// and removes keyring entries only the new settings refer to
+	var written []string
+	rollback := func(cause error) error {
+		for _, email := range written {
+			if err := config.AtomicWrite(paths[email], original[email], 0600); err != nil {
+				cause = errors.Join(cause, fmt.Errorf("failed to restore token of %s: %w", email, err))
+			}
+		}
+		if store.Name() == VaultKeyring {
+			for _, email := range emails {
+				if previous[email].Name() != VaultKeyring {
+					_ = store.Delete(email)
+				}
+			}
+		}
+		UnlockVault(oldPassphrase)
+		return cause
+	}
+
+	sealed := make(map[string][]byte, len(emails))
+	for _, email := range emails {
+		token := *tokens[email]
+		token.Email = email
+		data, err := json.MarshalIndent(&token, "", "  ")
+		if err != nil {
+			return 0, rollback(fmt.Errorf("failed to marshal token of %s: %w", email, err))
+		}
+		if sealed[email], err = store.Seal(email, data); err != nil {
+			return 0, rollback(fmt.Errorf("failed to seal token of %s: %w", email, err))
+		}
+	}
+
+	for _, email := range emails {
+		if err := writeAccountFile(paths[email], sealed[email], 0600); err != nil {
+			return 0, rollback(fmt.Errorf("failed to save token of %s: %w", email, err))
+		}
+		written = append(written, email)
+	}
+
+	err = config.UpdateConfig(func(cfg *config.Config) error {
+		cfg.Vault = settings
+		return nil
+	})
+	if err != nil {
+		return 0, rollback(err)
+	}
+
+	for _, email := range emails {
+		if previous[email].Name() != settings.Backend && previous[email].Name() != VaultPlaintext {
+			if err := previous[email].Delete(email); err != nil {
+				return 0, fmt.Errorf("failed to remove old %s secret of %s: %w", previous[email].Name(), email, err)
+			}
+		}
+	}
+
+	return len(emails), nil
+}
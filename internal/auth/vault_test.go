@@ -0,0 +1,355 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupVault points the config at a temp dir with the given vault settings and
+// makes key derivation cheap for the duration of the test
+func setupVault(t *testing.T, settings config.VaultSettings) {
+	t.Helper()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(EnvVaultPassphrase, "")
+	t.Setenv(EnvVaultKeyFile, "")
+
+	iterations := pbkdf2Iterations
+	pbkdf2Iterations = 1000
+	UnlockVault("")
+	t.Cleanup(func() {
+		pbkdf2Iterations = iterations
+		UnlockVault("")
+	})
+
+	require.NoError(t, config.SaveConfig(&config.Config{Vault: settings}))
+}
+
+// fakeKeyring replaces secret-tool with an in-memory keyring
+func fakeKeyring(t *testing.T) map[string][]byte {
+	t.Helper()
+
+	var mu sync.Mutex
+	secrets := make(map[string][]byte)
+
+	original := keyringCommand
+	keyringCommand = func(stdin []byte, args ...string) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		account := args[len(args)-1]
+		switch args[0] {
+		case "store":
+			secrets[account] = bytes.Clone(stdin)
+		case "lookup":
+			return secrets[account], nil
+		case "clear":
+			delete(secrets, account)
+		}
+		return nil, nil
+	}
+	t.Cleanup(func() { keyringCommand = original })
+
+	return secrets
+}
+
+func testToken(email string) *TokenData {
+	return &TokenData{
+		AccessToken:  "access-" + email,
+		RefreshToken: "refresh-" + email,
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+}
+
+func readAccountFile(t *testing.T, email string) []byte {
+	t.Helper()
+	path, err := config.GetAccountPath(email)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func TestEncryptedVault_Passphrase(t *testing.T) {
+	setupVault(t, config.VaultSettings{Backend: VaultEncrypted})
+	t.Setenv(EnvVaultPassphrase, "correct horse")
+
+	email := "vault@example.com"
+	require.NoError(t, SaveTokenForAccount(email, testToken(email)))
+
+	data := readAccountFile(t, email)
+	assert.NotContains(t, string(data), "refresh-"+email, "refresh token must not be stored in plaintext")
+	assert.Contains(t, string(data), `"vault": "encrypted"`)
+
+	loaded, err := LoadTokenForAccount(email)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh-"+email, loaded.RefreshToken)
+	assert.Equal(t, email, loaded.Email)
+
+	t.Setenv(EnvVaultPassphrase, "wrong")
+	_, err = LoadTokenForAccount(email)
+	assert.True(t, errors.Is(err, ErrVaultDecrypt), "expected ErrVaultDecrypt, got %v", err)
+}
+
+func TestEncryptedVault_BoundToAccount(t *testing.T) {
+	setupVault(t, config.VaultSettings{Backend: VaultEncrypted})
+	t.Setenv(EnvVaultPassphrase, "correct horse")
+
+	require.NoError(t, SaveTokenForAccount("alice@example.com", testToken("alice@example.com")))
+
+	// Copying alice's file over bob's must not hand bob's account alice's token
+	path, err := config.GetAccountPath("bob@example.com")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, readAccountFile(t, "alice@example.com"), 0600))
+
+	_, err = LoadTokenForAccount("bob@example.com")
+	assert.True(t, errors.Is(err, ErrVaultDecrypt), "expected ErrVaultDecrypt, got %v", err)
+}
+
+func TestEncryptedVault_KeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "vault.key")
+	setupVault(t, config.VaultSettings{Backend: VaultEncrypted, KeyFile: keyFile})
+
+	email := "keyfile@example.com"
+	err := SaveTokenForAccount(email, testToken(email))
+	require.Error(t, err, "saving must fail while the key file is missing")
+
+	require.NoError(t, GenerateVaultKeyFile(keyFile))
+	info, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	require.NoError(t, SaveTokenForAccount(email, testToken(email)))
+	loaded, err := LoadTokenForAccount(email)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh-"+email, loaded.RefreshToken)
+}
+
+func TestEncryptedVault_Locked(t *testing.T) {
+	setupVault(t, config.VaultSettings{Backend: VaultEncrypted})
+
+	original := PassphrasePrompt
+	PassphrasePrompt = nil
+	t.Cleanup(func() { PassphrasePrompt = original })
+
+	err := SaveTokenForAccount("locked@example.com", testToken("locked@example.com"))
+	assert.True(t, errors.Is(err, ErrVaultLocked), "expected ErrVaultLocked, got %v", err)
+
+	prompts := 0
+	PassphrasePrompt = func() (string, error) {
+		prompts++
+		return "typed", nil
+	}
+
+	require.NoError(t, SaveTokenForAccount("locked@example.com", testToken("locked@example.com")))
+	_, err = LoadTokenForAccount("locked@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, prompts, "the passphrase should be asked once per run")
+}
+
+func TestKeyringVault(t *testing.T) {
+	setupVault(t, config.VaultSettings{Backend: VaultKeyring})
+	secrets := fakeKeyring(t)
+
+	email := "keyring@example.com"
+	require.NoError(t, SaveTokenForAccount(email, testToken(email)))
+
+	data := readAccountFile(t, email)
+	assert.NotContains(t, string(data), "refresh-"+email)
+	assert.Contains(t, string(secrets[email]), "refresh-"+email)
+
+	loaded, err := LoadTokenForAccount(email)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh-"+email, loaded.RefreshToken)
+
+	mgr, err := NewAccountManager()
+	require.NoError(t, err)
+	require.NoError(t, mgr.RemoveAccount(email))
+	assert.NotContains(t, secrets, email, "removing the account must clear the keyring entry")
+}
+
+func TestMigrateVaultIfNeeded(t *testing.T) {
+	setupVault(t, config.VaultSettings{})
+
+	emails := []string{"a@example.com", "b@example.com"}
+	for _, email := range emails {
+		require.NoError(t, SaveTokenForAccount(email, testToken(email)))
+		assert.Contains(t, string(readAccountFile(t, email)), "refresh-"+email)
+	}
+
+	// Selecting the encrypted backend by hand leaves the plaintext files behind
+	require.NoError(t, config.SaveConfig(&config.Config{Vault: config.VaultSettings{Backend: VaultEncrypted}}))
+	t.Setenv(EnvVaultPassphrase, "migrate me")
+
+	require.NoError(t, MigrateVaultIfNeeded())
+
+	for _, email := range emails {
+		data := readAccountFile(t, email)
+		assert.NotContains(t, string(data), "refresh-"+email)
+
+		loaded, err := LoadTokenForAccount(email)
+		require.NoError(t, err)
+		assert.Equal(t, "refresh-"+email, loaded.RefreshToken)
+	}
+
+	// A second run finds nothing to do
+	before := readAccountFile(t, emails[0])
+	require.NoError(t, MigrateVaultIfNeeded())
+	assert.Equal(t, before, readAccountFile(t, emails[0]))
+}
+
+func TestMigrateVaultIfNeeded_TokenBackup(t *testing.T) {
+	setupVault(t, config.VaultSettings{})
+	t.Setenv(EnvVaultPassphrase, "migrate me")
+	require.NoError(t, SaveTokenForAccount("a@example.com", testToken("a@example.com")))
+
+	tokenPath, err := config.GetTokenPath()
+	require.NoError(t, err)
+	backupPath := tokenPath + ".bak"
+	writeBackup := func(email string) {
+		token := testToken(email)
+		token.Email = email
+		data, err := json.Marshal(token)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(backupPath, data, 0600))
+	}
+
+	// The plaintext backend keeps the backup
+	writeBackup("a@example.com")
+	require.NoError(t, MigrateVaultIfNeeded())
+	assert.FileExists(t, backupPath)
+
+	require.NoError(t, config.SaveConfig(&config.Config{Vault: config.VaultSettings{Backend: VaultEncrypted}}))
+
+	// A backup of an account that is not saved is kept and reported
+	writeBackup("gone@example.com")
+	assert.ErrorContains(t, MigrateVaultIfNeeded(), "plaintext token")
+	assert.FileExists(t, backupPath)
+
+	// Once the account is in the vault, its plaintext backup is removed
+	writeBackup("a@example.com")
+	require.NoError(t, MigrateVaultIfNeeded())
+	assert.NoFileExists(t, backupPath)
+	assert.NotContains(t, string(readAccountFile(t, "a@example.com")), "refresh-a@example.com")
+}
+
+func TestReconfigureVault(t *testing.T) {
+	setupVault(t, config.VaultSettings{})
+	secrets := fakeKeyring(t)
+
+	email := "switch@example.com"
+	require.NoError(t, SaveTokenForAccount(email, testToken(email)))
+
+	// plaintext -> encrypted with a passphrase
+	count, err := ReconfigureVault(config.VaultSettings{Backend: VaultEncrypted}, "first")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NotContains(t, string(readAccountFile(t, email)), "refresh-"+email)
+
+	// same settings with a new passphrase
+	_, err = ReconfigureVault(config.VaultSettings{Backend: VaultEncrypted}, "second")
+	require.NoError(t, err)
+
+	UnlockVault("first")
+	_, err = LoadTokenForAccount(email)
+	assert.ErrorIs(t, err, ErrVaultDecrypt, "the old passphrase must no longer unlock the vault")
+	UnlockVault("second")
+
+	// encrypted with a passphrase -> encrypted with a key file
+	keyFile := filepath.Join(t.TempDir(), "vault.key")
+	require.NoError(t, GenerateVaultKeyFile(keyFile))
+	_, err = ReconfigureVault(config.VaultSettings{Backend: VaultEncrypted, KeyFile: keyFile}, "")
+	require.NoError(t, err)
+
+	UnlockVault("")
+	loaded, err := LoadTokenForAccount(email)
+	require.NoError(t, err, "the key file alone must unlock the vault")
+	assert.Equal(t, "refresh-"+email, loaded.RefreshToken)
+
+	// encrypted -> keyring -> plaintext
+	_, err = ReconfigureVault(config.VaultSettings{Backend: VaultKeyring}, "")
+	require.NoError(t, err)
+	assert.Contains(t, secrets, email)
+
+	_, err = ReconfigureVault(config.VaultSettings{Backend: VaultPlaintext}, "")
+	require.NoError(t, err)
+	assert.NotContains(t, secrets, email, "leaving the keyring must clear its entry")
+	assert.True(t, strings.Contains(string(readAccountFile(t, email)), "refresh-"+email))
+
+	_, err = ReconfigureVault(config.VaultSettings{Backend: "bogus"}, "")
+	assert.Error(t, err)
+}
+
+func TestReconfigureVault_FailureRollsBack(t *testing.T) {
+	setupVault(t, config.VaultSettings{Backend: VaultEncrypted})
+	UnlockVault("first")
+
+	emails := []string{"a@example.com", "b@example.com", "c@example.com"}
+	before := make(map[string][]byte)
+	for _, email := range emails {
+		require.NoError(t, SaveTokenForAccount(email, testToken(email)))
+		before[email] = readAccountFile(t, email)
+	}
+
+	// assertUnchanged checks that every account is still sealed with the first passphrase
+	assertUnchanged := func(t *testing.T) {
+		t.Helper()
+
+		cfg, err := config.LoadConfig()
+		require.NoError(t, err)
+		assert.Equal(t, config.VaultSettings{Backend: VaultEncrypted}, cfg.Vault)
+		for _, email := range emails {
+			assert.Equal(t, before[email], readAccountFile(t, email), "%s must be restored", email)
+			loaded, err := LoadTokenForAccount(email)
+			require.NoError(t, err, "the old passphrase must still unlock %s", email)
+			assert.Equal(t, "refresh-"+email, loaded.RefreshToken)
+		}
+	}
+
+	t.Run("Save Fails", func(t *testing.T) {
+		writes := 0
+		original := writeAccountFile
+		writeAccountFile = func(path string, data []byte, perm os.FileMode) error {
+			if writes++; writes == 2 {
+				return errors.New("no space left on device")
+			}
+			return original(path, data, perm)
+		}
+		t.Cleanup(func() { writeAccountFile = original })
+
+		_, err := ReconfigureVault(config.VaultSettings{Backend: VaultEncrypted}, "second")
+		require.ErrorContains(t, err, "no space left on device")
+		assertUnchanged(t)
+	})
+
+	t.Run("Seal Fails", func(t *testing.T) {
+		secrets := fakeKeyring(t)
+		stores := 0
+		fake := keyringCommand
+		keyringCommand = func(stdin []byte, args ...string) ([]byte, error) {
+			if args[0] == "store" {
+				if stores++; stores == 2 {
+					return nil, errors.New("keyring is locked")
+				}
+			}
+			return fake(stdin, args...)
+		}
+
+		_, err := ReconfigureVault(config.VaultSettings{Backend: VaultKeyring}, "")
+		require.ErrorContains(t, err, "keyring is locked")
+		assert.Empty(t, secrets, "keyring entries of the failed switch must be removed")
+		assertUnchanged(t)
+	})
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// AccountIndexFileName maps account file names back to account IDs
+const AccountIndexFileName = "accounts-index.json"
+
+// accountIndexVersion is increased when the index format changes
+const accountIndexVersion = 1
+
+// maxAccountIDLength is the longest address allowed by RFC 5321
+const maxAccountIDLength = 254
+
+// ErrInvalidAccountID is returned for identities that are not usable as an account ID
+var ErrInvalidAccountID = errors.New("invalid account email")
+
+// accountIndexMu serializes UpdateAccountIndex within the process; the file lock covers other processes
+var accountIndexMu sync.Mutex
+
+// AccountIndex is the reverse mapping from account file names to account IDs
+type AccountIndex struct {
+	Version int `json:"version"`
+	// Accounts maps a file name in the accounts directory to its account ID
+	Accounts map[string]string `json:"accounts"`
+}
+
+// CanonicalAccountID returns the identity an account is stored under: the email
+// trimmed and lowercased. Emails without exactly one '@', with whitespace,
+// control characters or path separators are rejected.
+func CanonicalAccountID(email string) (string, error) {
+	id := strings.ToLower(strings.TrimSpace(email))
+
+	local, domain, found := strings.Cut(id, "@")
+	if !found || local == "" || domain == "" || strings.Contains(domain, "@") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAccountID, email)
+	}
+	if len(id) > maxAccountIDLength {
+		return "", fmt.Errorf("%w: longer than %d characters", ErrInvalidAccountID, maxAccountIDLength)
+	}
+	if strings.ContainsFunc(id, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r) || r == '/' || r == '\\'
+	}) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAccountID, email)
+	}
+
+	return id, nil
+}
+
+// AccountFileName returns the file name an account ID is stored under. Ordinary
+// addresses are kept as they are; any other byte, and a leading dot, is written
+// as %XX so the name can never leave the accounts directory or be hidden.
+func AccountFileName(id string) string {
+	var b strings.Builder
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if isFileNameSafe(c) && (i > 0 || c != '.') {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String() + ".json"
+}
+
+// AccountIDFromFileName reverses AccountFileName. The result is not canonicalized:
+// files written before account IDs existed may be named after any email.
+func AccountIDFromFileName(name string) (string, error) {
+	stem, ok := strings.CutSuffix(name, ".json")
+	if !ok {
+		return "", fmt.Errorf("%w: %s is not an account file", ErrInvalidAccountID, name)
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(stem); i++ {
+		if stem[i] != '%' {
+			b.WriteByte(stem[i])
+			continue
+		}
+		if i+2 >= len(stem) {
+			return "", fmt.Errorf("%w: bad escape in %s", ErrInvalidAccountID, name)
+		}
+		c, err := strconv.ParseUint(stem[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("%w: bad escape in %s", ErrInvalidAccountID, name)
+		}
+		b.WriteByte(byte(c))
+		i += 2
+	}
+
+	return b.String(), nil
+}
+
+func isFileNameSafe(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("@._+-", c) >= 0
+}
+
+// GetAccountIndexPath returns the full path to the account index file
+func GetAccountIndexPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, AccountIndexFileName), nil
+}
+
+// LoadAccountIndex loads the account index. A missing index is empty.
+func LoadAccountIndex() (*AccountIndex, error) {
+	accountIndexMu.Lock()
+	defer accountIndexMu.Unlock()
+
+	return loadAccountIndex()
+}
+
+// UpdateAccountIndex applies fn to the account index and saves it if fn reports a change
+func UpdateAccountIndex(fn func(index *AccountIndex) bool) error {
+	accountIndexMu.Lock()
+	defer accountIndexMu.Unlock()
+
+	lock, err := LockFile(AccountIndexFileName, ConfigLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	index, err := loadAccountIndex()
+	if err != nil {
+		return err
+	}
+	if !fn(index) {
+		return nil
+	}
+
+	path, err := GetAccountIndexPath()
+	if err != nil {
+		return err
+	}
+	if _, err := EnsureConfigDir(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal account index: %w", err)
+	}
+	return AtomicWrite(path, data, 0600)
+}
+
+func loadAccountIndex() (*AccountIndex, error) {
+	index := &AccountIndex{Version: accountIndexVersion, Accounts: make(map[string]string)}
+
+	path, err := GetAccountIndexPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("failed to read account index: %w", err)
+	}
+
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse account index: %w", err)
+	}
+	if index.Accounts == nil {
+		index.Accounts = make(map[string]string)
+	}
+	return index, nil
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalAccountID(t *testing.T) {
+	valid := map[string]string{
+		"user@example.com":            "user@example.com",
+		"  User.Name@Example.COM \n":  "user.name@example.com",
+		"first+tag@sub.example.co.uk": "first+tag@sub.example.co.uk",
+		"..@example.com":              "..@example.com",
+	}
+	for input, want := range valid {
+		got, err := CanonicalAccountID(input)
+		if err != nil || got != want {
+			t.Errorf("CanonicalAccountID(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"no-at-sign",
+		"@example.com",
+		"user@",
+		"a@b@example.com",
+		"../../etc/passwd@example.com",
+		`..\evil@example.com`,
+		"user@exa mple.com",
+		"user\x00@example.com",
+		strings.Repeat("a", 250) + "@example.com",
+	}
+	for _, input := range invalid {
+		if _, err := CanonicalAccountID(input); !errors.Is(err, ErrInvalidAccountID) {
+			t.Errorf("CanonicalAccountID(%q) should fail with ErrInvalidAccountID, got %v", input, err)
+		}
+	}
+}
+
+func TestAccountFileName(t *testing.T) {
+	tests := map[string]string{
+		"user@example.com":     "user@example.com.json",
+		"first+tag@example.io": "first+tag@example.io.json",
+		"..@example.com":       "%2E.@example.com.json",
+		"o'neil@example.com":   "o%27neil@example.com.json",
+		"50%@example.com":      "50%25@example.com.json",
+		"ünï@example.com":      "%C3%BCn%C3%AF@example.com.json",
+	}
+	for id, want := range tests {
+		name := AccountFileName(id)
+		if name != want {
+			t.Errorf("AccountFileName(%q) = %q, want %q", id, name, want)
+		}
+		if filepath.Base(name) != name {
+			t.Errorf("AccountFileName(%q) = %q is not a plain file name", id, name)
+		}
+
+		back, err := AccountIDFromFileName(name)
+		if err != nil || back != id {
+			t.Errorf("AccountIDFromFileName(%q) = %q, %v; want %q", name, back, err, id)
+		}
+	}
+
+	for _, name := range []string{"user@example.com.txt", "bad%2@example.com.json", "bad%zz@example.com.json"} {
+		if _, err := AccountIDFromFileName(name); err == nil {
+			t.Errorf("AccountIDFromFileName(%q) should fail", name)
+		}
+	}
+}
+
+func TestAccountIndex(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	index, err := LoadAccountIndex()
+	if err != nil {
+		t.Fatalf("LoadAccountIndex failed: %v", err)
+	}
+	if len(index.Accounts) != 0 {
+		t.Errorf("expected an empty index, got %v", index.Accounts)
+	}
+
+	err = UpdateAccountIndex(func(index *AccountIndex) bool {
+		index.Accounts[AccountFileName("a@example.com")] = "a@example.com"
+		return true
+	})
+	if err != nil {
+		t.Fatalf("UpdateAccountIndex failed: %v", err)
+	}
+
+	index, err = LoadAccountIndex()
+	if err != nil {
+		t.Fatalf("LoadAccountIndex failed: %v", err)
+	}
+	if index.Accounts["a@example.com.json"] != "a@example.com" || index.Version != accountIndexVersion {
+		t.Errorf("unexpected index: %+v", index)
+	}
+}
+
+func TestGetAccountPath_Canonical(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	lower, err := GetAccountPath("user@example.com")
+	if err != nil {
+		t.Fatalf("GetAccountPath failed: %v", err)
+	}
+	upper, err := GetAccountPath("User@Example.com")
+	if err != nil {
+		t.Fatalf("GetAccountPath failed: %v", err)
+	}
+	if lower != upper {
+		t.Errorf("case variants map to different files: %s and %s", lower, upper)
+	}
+
+	if _, err := GetAccountPath("../../escape@example.com"); err == nil {
+		t.Error("expected a path separator to be rejected")
+	}
+}
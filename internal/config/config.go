@@ -1,22 +1,54 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/filelock"
 )
 
 // Config represents the application's configuration.
 type Config struct {
 	DefaultAccount string               `json:"default_account,omitempty"`
 	Notifications  NotificationSettings `json:"notifications,omitempty"`
+	API            APISettings          `json:"api,omitempty"`
+	Vault          VaultSettings        `json:"vault,omitempty"`
+}
+
+// VaultSettings selects how account tokens are kept at rest
+type VaultSettings struct {
+	// Backend is "plaintext" (default), "encrypted" or "keyring"
+	Backend string `json:"backend,omitempty"`
+	// KeyFile unlocks the encrypted backend instead of a passphrase
+	KeyFile string `json:"key_file,omitempty"`
+}
+
+// APISettings contains overrides for how the Cloud Code API is reached.
+// Empty values fall back to the built-in defaults.
+type APISettings struct {
+	Endpoint       string `json:"endpoint,omitempty"`
+	Proxy          string `json:"proxy,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+	UserAgent      string `json:"user_agent,omitempty"`
+	// OnboardingTimeoutSeconds limits how long a run waits for onboarding to complete
+	OnboardingTimeoutSeconds int `json:"onboarding_timeout_seconds,omitempty"`
 }
 
 // NotificationSettings contains settings for various notification channels.
 type NotificationSettings struct {
 	Enabled  bool             `json:"enabled"`
 	Telegram TelegramSettings `json:"telegram,omitempty"`
+	Webhook  WebhookSettings  `json:"webhook,omitzero"`
+	Slack    SlackSettings    `json:"slack,omitzero"`
+	Discord  DiscordSettings  `json:"discord,omitzero"`
+	Email    EmailSettings    `json:"email,omitzero"`
+	Ntfy     NtfySettings     `json:"ntfy,omitzero"`
+	Gotify   GotifySettings   `json:"gotify,omitzero"`
 }
 
 // TelegramSettings contains credentials for Telegram bot notifications.
@@ -25,6 +57,57 @@ type TelegramSettings struct {
 	ChatID   string `json:"chat_id"`
 }
 
+// SlackSettings contains the incoming webhook for Slack notifications.
+type SlackSettings struct {
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// DiscordSettings contains the channel webhook for Discord notifications.
+type DiscordSettings struct {
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// EmailSettings configures notifications sent by email over SMTP.
+// Empty values fall back to the built-in defaults.
+type EmailSettings struct {
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+	// Security is "starttls" (default), "tls" or "none"
+	Security string   `json:"security,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	// SubjectTemplate is a Go text/template rendering the subject line
+	SubjectTemplate string `json:"subject_template,omitempty"`
+}
+
+// NtfySettings contains the topic for ntfy push notifications.
+type NtfySettings struct {
+	TopicURL string `json:"topic_url,omitempty"`
+	// Token is an access token for protected topics
+	Token string `json:"token,omitempty"`
+}
+
+// GotifySettings contains the server and application token for Gotify push notifications.
+type GotifySettings struct {
+	URL   string `json:"url,omitempty"`
+	Token string `json:"token,omitempty"`
+}
+
+// WebhookSettings configures notifications sent as HTTP requests to any URL.
+// Empty values fall back to the built-in defaults.
+type WebhookSettings struct {
+	URL     string            `json:"url,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Secret signs each request body with HMAC-SHA256
+	Secret          string `json:"secret,omitempty"`
+	SignatureHeader string `json:"signature_header,omitempty"`
+	// BodyTemplate is a Go text/template rendering the request body
+	BodyTemplate string `json:"body_template,omitempty"`
+}
+
 // AtomicWrite writes data to a file atomically by writing to a temp file first and then renaming it.
 func AtomicWrite(path string, data []byte, perm os.FileMode) error {
 	dir := filepath.Dir(path)
@@ -62,8 +145,26 @@ const (
 	TokenFileName  = "token.json" // Deprecated: use accounts/{email}.json
 	ConfigFileName = "config.json"
 	AccountsDir    = "accounts"
+
+	// EndpointStateFileName stores which API endpoint is currently healthy
+	EndpointStateFileName = "endpoints.json"
+
+	// TierRegistryFileName stores the tier metadata returned by the API
+	TierRegistryFileName = "tiers.json"
+
+	// ActivityFileName stores when each account was last used and how that went
+	ActivityFileName = "activity.json"
+
+	// LocksDir holds the lock files shared by concurrent ag-quota processes
+	LocksDir = "locks"
 )
 
+// ConfigLockTimeout bounds how long UpdateConfig waits for another process
+const ConfigLockTimeout = 10 * time.Second
+
+// configMu serializes UpdateConfig within the process; the file lock covers other processes
+var configMu sync.Mutex
+
 // GetAccountsDir returns the directory where account tokens are stored
 func GetAccountsDir() (string, error) {
 	configDir, err := GetConfigDir()
@@ -89,13 +190,20 @@ func EnsureAccountsDir() (string, error) {
 	return accountsDir, nil
 }
 
-// GetAccountPath returns the full path to an account token file
+// GetAccountPath returns the full path to an account token file. The email is
+// canonicalized and encoded with AccountFileName, so it always names a file
+// inside the accounts directory.
 func GetAccountPath(email string) (string, error) {
+	id, err := CanonicalAccountID(email)
+	if err != nil {
+		return "", err
+	}
+
 	accountsDir, err := GetAccountsDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(accountsDir, email+".json"), nil
+	return filepath.Join(accountsDir, AccountFileName(id)), nil
 }
 
 // GetConfigDir returns the configuration directory path
@@ -152,6 +260,51 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(configDir, ConfigFileName), nil
 }
 
+// GetLockPath returns the path of the lock file guarding a file in the config directory
+func GetLockPath(fileName string) (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, LocksDir, fileName+".lock"), nil
+}
+
+// LockFile takes the cross-process lock guarding a file in the config directory
+func LockFile(fileName string, timeout time.Duration) (*filelock.Lock, error) {
+	path, err := GetLockPath(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return filelock.Acquire(context.Background(), path, filelock.Options{Timeout: timeout})
+}
+
+// GetEndpointStatePath returns the full path to the API endpoint state file
+func GetEndpointStatePath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, EndpointStateFileName), nil
+}
+
+// GetTierRegistryPath returns the full path to the tier registry file
+func GetTierRegistryPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, TierRegistryFileName), nil
+}
+
+// GetActivityPath returns the full path to the account activity file
+func GetActivityPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, ActivityFileName), nil
+}
+
 // LoadConfig loads the application configuration from the default path.
 func LoadConfig() (*Config, error) {
 	path, err := GetConfigPath()
@@ -193,3 +346,26 @@ func SaveConfig(cfg *Config) error {
 
 	return AtomicWrite(path, data, 0600)
 }
+
+// UpdateConfig loads the configuration, applies fn and saves it while holding the
+// config lock, so concurrent processes do not overwrite each other's changes.
+// Nothing is saved if fn returns an error.
+func UpdateConfig(fn func(cfg *Config) error) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	lock, err := LockFile(ConfigFileName, ConfigLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := fn(cfg); err != nil {
+		return err
+	}
+	return SaveConfig(cfg)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -85,3 +86,51 @@ func TestAtomicWrite(t *testing.T) {
 		t.Errorf("expected %s, got %s", string(content), string(data))
 	}
 }
+
+func TestUpdateConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	const writers = 20
+	done := make(chan error)
+	for i := 0; i < writers; i++ {
+		go func() {
+			done <- UpdateConfig(func(cfg *Config) error {
+				cfg.API.TimeoutSeconds++
+				return nil
+			})
+		}()
+	}
+	for i := 0; i < writers; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("UpdateConfig failed: %v", err)
+		}
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.API.TimeoutSeconds != writers {
+		t.Errorf("lost updates: got %d, want %d", cfg.API.TimeoutSeconds, writers)
+	}
+
+	// An error from fn leaves the config untouched
+	errAbort := os.ErrInvalid
+	if err := UpdateConfig(func(cfg *Config) error {
+		cfg.API.TimeoutSeconds = 0
+		return errAbort
+	}); !errors.Is(err, errAbort) {
+		t.Errorf("expected fn's error, got %v", err)
+	}
+	if cfg, _ := LoadConfig(); cfg.API.TimeoutSeconds != writers {
+		t.Error("config was saved despite the error")
+	}
+
+	lockPath, err := GetLockPath(ConfigFileName)
+	if err != nil {
+		t.Fatalf("GetLockPath failed: %v", err)
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("expected the lock file at %s: %v", lockPath, err)
+	}
+}
@@ -0,0 +1,78 @@
+package fakeserver
+
+import (
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/models"
+)
+
+// Common tier IDs returned by loadCodeAssist
+const (
+	TierFree     = "free-tier"
+	TierStandard = "standard-tier"
+	TierLegacy   = "legacy-tier"
+)
+
+// NewModel returns a model with the given remaining quota fraction, resetting after resetIn
+func NewModel(displayName string, remaining float64, resetIn time.Duration) models.Model {
+	return models.Model{
+		DisplayName:   displayName,
+		Model:         displayName,
+		ModelProvider: "MODEL_PROVIDER_GOOGLE",
+		QuotaInfo: models.ModelQuotaInfo{
+			RemainingFraction: remaining,
+			ResetTime:         time.Now().Add(resetIn).UTC().Truncate(time.Second),
+		},
+	}
+}
+
+// ExhaustedModel returns a model whose quota is used up
+func ExhaustedModel(displayName string, resetIn time.Duration) models.Model {
+	m := NewModel(displayName, 0, resetIn)
+	m.QuotaInfo.IsExhausted = true
+	return m
+}
+
+// DefaultModels returns a typical set of models with mixed quota levels
+func DefaultModels() map[string]models.Model {
+	return map[string]models.Model{
+		"gemini-3-flash":    NewModel("Gemini 3 Flash", 1.0, 5*time.Hour),
+		"gemini-3-pro-high": NewModel("Gemini 3 Pro (High)", 0.4, 3*time.Hour),
+		"claude-sonnet-4-5": NewModel("Claude Sonnet 4.5", 0.1, time.Hour),
+		"gpt-oss-120b":      ExhaustedModel("GPT-OSS 120B", 2*time.Hour),
+	}
+}
+
+// OnboardedAccount returns an account that already has a project on the free tier
+func OnboardedAccount(email string) Account {
+	return Account{
+		Email:          email,
+		ProjectID:      "project-" + email,
+		CurrentTier:    &models.Tier{ID: TierFree},
+		Models:         DefaultModels(),
+		DefaultModelID: "gemini-3-flash",
+	}
+}
+
+// NewUserAccount returns an account that must be onboarded. onboardUser
+// answers done:false polls times before returning the project.
+func NewUserAccount(email string, polls int) Account {
+	return Account{
+		Email: email,
+		AllowedTiers: []models.Tier{
+			{ID: TierLegacy},
+			{ID: TierFree, IsDefault: true},
+		},
+		OnboardPolls:     polls,
+		OnboardProjectID: "onboarded-" + email,
+		Models:           DefaultModels(),
+		DefaultModelID:   "gemini-3-flash",
+	}
+}
+
+// PaidAccount returns an account that already has a project on the given paid tier
+func PaidAccount(email string, tier models.Tier) Account {
+	acc := OnboardedAccount(email)
+	acc.PaidTier = &tier
+	return acc
+}
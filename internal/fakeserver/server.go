@@ -0,0 +1,638 @@
+// Package fakeserver provides an in-process stand-in for the Cloud Code API and the
+// Google OAuth2 token/userinfo endpoints, so the client, the auth refresh logic and
+// whole CLI commands can be tested offline.
+package fakeserver
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/models"
+	"golang.org/x/oauth2"
+)
+
+// Method names used to inject faults and count calls
+const (
+	MethodLoadCodeAssist       = "loadCodeAssist"
+	MethodOnboardUser          = "onboardUser"
+	MethodFetchAvailableModels = "fetchAvailableModels"
+	MethodToken                = "token"
+	MethodUserInfo             = "userinfo"
+	MethodAuthorize            = "authorize"
+	MethodRevoke               = "revoke"
+)
+
+// Paths served by the fake server
+const (
+	TokenPath    = "/token"
+	UserInfoPath = "/oauth2/v2/userinfo"
+	AuthPath     = "/o/oauth2/v2/auth"
+	RevokePath   = "/revoke"
+)
+
+// Account is the server-side state of a fake Google account
+type Account struct {
+	Email        string
+	RefreshToken string
+
+	// ProjectID is returned by loadCodeAssist. Leave it empty to require onboarding.
+	ProjectID    string
+	CurrentTier  *models.Tier
+	PaidTier     *models.Tier
+	AllowedTiers []models.Tier
+
+	// OnboardPolls is the number of done:false responses onboardUser returns before completing
+	OnboardPolls int
+	// OnboardProjectID is the project returned once onboarding completes
+	OnboardProjectID string
+
+	Models         map[string]models.Model
+	DefaultModelID string
+}
+
+// Fault is a scripted response returned instead of the normal one
+type Fault struct {
+	// Status is the HTTP status to return. Zero only applies Delay and then serves normally.
+	Status int
+	// Body is the response body, a google.rpc error body is generated when empty
+	Body string
+	// RetryAfter is sent as the Retry-After header when set
+	RetryAfter string
+	// Delay is waited before responding, to simulate slow responses
+	Delay time.Duration
+}
+
+// RateLimitBurst returns n consecutive 429 faults carrying the given Retry-After value
+func RateLimitBurst(n int, retryAfter string) []Fault {
+	faults := make([]Fault, n)
+	for i := range faults {
+		faults[i] = Fault{Status: http.StatusTooManyRequests, RetryAfter: retryAfter}
+	}
+	return faults
+}
+
+// Unauthorized returns a 401 fault
+func Unauthorized() Fault {
+	return Fault{Status: http.StatusUnauthorized}
+}
+
+// ServerError returns a 5xx fault with the given status
+func ServerError(status int) Fault {
+	return Fault{Status: status}
+}
+
+// Slow returns a fault that delays an otherwise normal response
+func Slow(delay time.Duration) Fault {
+	return Fault{Delay: delay}
+}
+
+// Server is a fake Cloud Code API and Google OAuth2 server
+type Server struct {
+	server *httptest.Server
+
+	mu            sync.Mutex
+	accounts      map[string]*Account
+	accessTokens  map[string]string
+	refreshTokens map[string]string
+	onboardPolls  map[string]int
+	faults        map[string][]Fault
+	calls         map[string]int
+	tokenSeq      int
+
+	// authorizing is the account that approves authorization requests
+	authorizing string
+	authCodes   map[string]authCode
+	lastAuth    url.Values
+}
+
+// authCode is an issued authorization code waiting to be exchanged
+type authCode struct {
+	email         string
+	redirectURI   string
+	codeChallenge string
+}
+
+// New starts a fake server. Close it when done.
+func New() *Server {
+	s := &Server{
+		accounts:      make(map[string]*Account),
+		accessTokens:  make(map[string]string),
+		refreshTokens: make(map[string]string),
+		onboardPolls:  make(map[string]int),
+		authCodes:     make(map[string]authCode),
+		faults:        make(map[string][]Fault),
+		calls:         make(map[string]int),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// URL returns the base URL of the server, usable as the Cloud Code API endpoint
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// TokenURL returns the URL of the fake OAuth2 token endpoint
+func (s *Server) TokenURL() string {
+	return s.server.URL + TokenPath
+}
+
+// UserInfoURL returns the URL of the fake UserInfo endpoint
+func (s *Server) UserInfoURL() string {
+	return s.server.URL + UserInfoPath
+}
+
+// AuthURL returns the URL of the fake authorization (consent) endpoint
+func (s *Server) AuthURL() string {
+	return s.server.URL + AuthPath
+}
+
+// RevokeURL returns the OAuth2 token revocation endpoint of the fake server
+func (s *Server) RevokeURL() string {
+	return s.server.URL + RevokePath
+}
+
+// OAuthEndpoint returns the fake OAuth2 endpoint
+func (s *Server) OAuthEndpoint() oauth2.Endpoint {
+	return oauth2.Endpoint{
+		AuthURL:  s.AuthURL(),
+		TokenURL: s.TokenURL(),
+	}
+}
+
+// AddAccount registers an account and returns a valid access token for it.
+// A refresh token is generated if the account has none.
+func (s *Server) AddAccount(acc Account) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if acc.RefreshToken == "" {
+		acc.RefreshToken = "refresh-" + acc.Email
+	}
+	s.accounts[acc.Email] = &acc
+	s.refreshTokens[acc.RefreshToken] = acc.Email
+	return s.issueAccessToken(acc.Email)
+}
+
+// RefreshToken returns the refresh token registered for an account
+func (s *Server) RefreshToken(email string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if acc, ok := s.accounts[email]; ok {
+		return acc.RefreshToken
+	}
+	return ""
+}
+
+// RevokeAccessTokens invalidates all access tokens of an account, so the next API call gets 401
+func (s *Server) RevokeAccessTokens(email string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for token, owner := range s.accessTokens {
+		if owner == email {
+			delete(s.accessTokens, token)
+		}
+	}
+}
+
+// Revoked reports whether the grant of an account was revoked through the revocation endpoint
+func (s *Server) Revoked(email string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, owner := range s.refreshTokens {
+		if owner == email {
+			return false
+		}
+	}
+	_, known := s.accounts[email]
+	return known
+}
+
+// SetProject changes the project of an account, e.g. to simulate a project that was moved or deleted
+func (s *Server) SetProject(email, projectID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if acc, ok := s.accounts[email]; ok {
+		acc.ProjectID = projectID
+	}
+}
+
+// Authorize makes the account approve subsequent authorization requests, as if
+// the user signed in with it on the consent screen
+func (s *Server) Authorize(email string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authorizing = email
+}
+
+// LastAuthorization returns the query parameters of the last authorization request
+func (s *Server) LastAuthorization() url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastAuth
+}
+
+// InjectFaults queues faults for a method. Each request consumes one fault.
+func (s *Server) InjectFaults(method string, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[method] = append(s.faults[method], faults...)
+}
+
+// Calls returns how many requests a method received, including faulted ones
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// issueAccessToken creates a new access token. It must be called while holding the lock.
+func (s *Server) issueAccessToken(email string) string {
+	s.tokenSeq++
+	token := fmt.Sprintf("access-%d-%s", s.tokenSeq, email)
+	s.accessTokens[token] = email
+	return token
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	method := methodFor(r.URL.Path)
+	if method == "" {
+		writeRPCError(w, http.StatusNotFound, "NOT_FOUND", "unknown path "+r.URL.Path)
+		return
+	}
+
+	s.mu.Lock()
+	s.calls[method]++
+	var fault *Fault
+	if queue := s.faults[method]; len(queue) > 0 {
+		fault = &queue[0]
+		s.faults[method] = queue[1:]
+	}
+	s.mu.Unlock()
+
+	if fault != nil {
+		if fault.Delay > 0 {
+			select {
+			case <-time.After(fault.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Status != 0 {
+			writeFault(w, *fault)
+			return
+		}
+	}
+
+	switch method {
+	case MethodToken:
+		s.handleToken(w, r)
+	case MethodUserInfo:
+		s.handleUserInfo(w, r)
+	case MethodAuthorize:
+		s.handleAuthorize(w, r)
+	case MethodRevoke:
+		s.handleRevoke(w, r)
+	case MethodLoadCodeAssist:
+		s.handleLoadCodeAssist(w, r)
+	case MethodOnboardUser:
+		s.handleOnboardUser(w, r)
+	case MethodFetchAvailableModels:
+		s.handleFetchAvailableModels(w, r)
+	}
+}
+
+func methodFor(path string) string {
+	switch path {
+	case TokenPath:
+		return MethodToken
+	case UserInfoPath:
+		return MethodUserInfo
+	case AuthPath:
+		return MethodAuthorize
+	case RevokePath:
+		return MethodRevoke
+	case "/v1internal:loadCodeAssist":
+		return MethodLoadCodeAssist
+	case "/v1internal:onboardUser":
+		return MethodOnboardUser
+	case "/v1internal:fetchAvailableModels":
+		return MethodFetchAvailableModels
+	default:
+		return ""
+	}
+}
+
+// authenticate returns the account of the bearer token, writing a 401 if there is none
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (*Account, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	s.mu.Lock()
+	email, ok := s.accessTokens[token]
+	acc := s.accounts[email]
+	s.mu.Unlock()
+
+	if !ok || acc == nil {
+		writeRPCError(w, http.StatusUnauthorized, "UNAUTHENTICATED", "Request had invalid authentication credentials.")
+		return nil, false
+	}
+	return acc, true
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, "invalid_request")
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "refresh_token":
+		s.handleRefreshGrant(w, r)
+	case "authorization_code":
+		s.handleAuthCodeGrant(w, r)
+	default:
+		writeOAuthError(w, "unsupported_grant_type")
+	}
+}
+
+func (s *Server) handleRefreshGrant(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	email, ok := s.refreshTokens[r.PostForm.Get("refresh_token")]
+	var accessToken string
+	if ok {
+		accessToken = s.issueAccessToken(email)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeOAuthError(w, "invalid_grant")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+// handleAuthCodeGrant exchanges an authorization code. Codes are single-use and,
+// if the authorization request carried a PKCE challenge, only the matching verifier redeems them.
+func (s *Server) handleAuthCodeGrant(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	code, ok := s.authCodes[r.PostForm.Get("code")]
+	delete(s.authCodes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	if !ok || code.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeOAuthError(w, "invalid_grant")
+		return
+	}
+	if code.codeChallenge != "" && s256(r.PostForm.Get("code_verifier")) != code.codeChallenge {
+		writeOAuthError(w, "invalid_grant")
+		return
+	}
+
+	s.mu.Lock()
+	acc := s.accounts[code.email]
+	accessToken := s.issueAccessToken(code.email)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  accessToken,
+		"refresh_token": acc.RefreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+	})
+}
+
+// handleAuthorize plays the consent screen: the account set with Authorize
+// approves the request and is redirected back with a code
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	if query.Get("client_id") == "" || redirectURI == "" || query.Get("response_type") != "code" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if method := query.Get("code_challenge_method"); query.Get("code_challenge") != "" && method != "S256" {
+		http.Error(w, "unsupported code_challenge_method", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.lastAuth = query
+	email := s.authorizing
+	_, known := s.accounts[email]
+	var code string
+	if known {
+		s.tokenSeq++
+		code = fmt.Sprintf("code-%d", s.tokenSeq)
+		s.authCodes[code] = authCode{
+			email:         email,
+			redirectURI:   redirectURI,
+			codeChallenge: query.Get("code_challenge"),
+		}
+	}
+	s.mu.Unlock()
+
+	params := url.Values{"state": {query.Get("state")}}
+	if known {
+		params.Set("code", code)
+	} else {
+		params.Set("error", "access_denied")
+	}
+	http.Redirect(w, r, redirectURI+"?"+params.Encode(), http.StatusFound)
+}
+
+// handleRevoke revokes the grant a refresh or access token belongs to, which
+// invalidates the refresh token and every access token of the account like Google does
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOAuthError(w, "invalid_request")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, "invalid_request")
+		return
+	}
+	token := r.PostForm.Get("token")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email, ok := s.refreshTokens[token]
+	if !ok {
+		email, ok = s.accessTokens[token]
+	}
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "invalid_token",
+			"error_description": "Token expired or revoked",
+		})
+		return
+	}
+
+	for refresh, owner := range s.refreshTokens {
+		if owner == email {
+			delete(s.refreshTokens, refresh)
+		}
+	}
+	for access, owner := range s.accessTokens {
+		if owner == email {
+			delete(s.accessTokens, access)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// s256 returns the PKCE S256 challenge of a verifier
+func s256(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	acc, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"email": acc.Email})
+}
+
+func (s *Server) handleLoadCodeAssist(w http.ResponseWriter, r *http.Request) {
+	acc, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	resp := models.LoadCodeAssistResponse{
+		ProjectID:    acc.ProjectID,
+		CurrentTier:  acc.CurrentTier,
+		PaidTier:     acc.PaidTier,
+		AllowedTiers: acc.AllowedTiers,
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleOnboardUser(w http.ResponseWriter, r *http.Request) {
+	acc, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.OnboardUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TierID == "" {
+		writeRPCError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "tierId is required")
+		return
+	}
+
+	s.mu.Lock()
+	s.onboardPolls[acc.Email]++
+	polls := s.onboardPolls[acc.Email]
+	done := polls > acc.OnboardPolls
+	if done {
+		acc.ProjectID = acc.OnboardProjectID
+		acc.CurrentTier = &models.Tier{ID: req.TierID}
+	}
+	s.mu.Unlock()
+
+	resp := models.OnboardUserResponse{Done: done}
+	if done {
+		resp.Response.CloudAICompanionProject = map[string]string{"id": acc.OnboardProjectID}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleFetchAvailableModels(w http.ResponseWriter, r *http.Request) {
+	acc, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.FetchAvailableModelsRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	s.mu.Lock()
+	projectID := acc.ProjectID
+	s.mu.Unlock()
+
+	// Requests for a project the account no longer has access to are denied
+	if req.Project != "" && req.Project != projectID {
+		writeRPCError(w, http.StatusForbidden, "PERMISSION_DENIED", "permission denied on project "+req.Project)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.FetchAvailableModelsResponse{
+		Models:            acc.Models,
+		DefaultAgentModel: acc.DefaultModelID,
+	})
+}
+
+func writeFault(w http.ResponseWriter, fault Fault) {
+	if fault.RetryAfter != "" {
+		w.Header().Set("Retry-After", fault.RetryAfter)
+	}
+	if fault.Body != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(fault.Status)
+		_, _ = w.Write([]byte(fault.Body))
+		return
+	}
+	writeRPCError(w, fault.Status, rpcStatusFor(fault.Status), http.StatusText(fault.Status))
+}
+
+func rpcStatusFor(status int) string {
+	switch {
+	case status == http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case status == http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case status == http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case status >= 500:
+		return "UNAVAILABLE"
+	default:
+		return "INVALID_ARGUMENT"
+	}
+}
+
+func writeRPCError(w http.ResponseWriter, status int, rpcStatus, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    status,
+			"message": message,
+			"status":  rpcStatus,
+		},
+	})
+}
+
+func writeOAuthError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,371 @@
+package fakeserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Connection security offered by the SMTP stand-in
+const (
+	SMTPPlain    = ""
+	SMTPStartTLS = "starttls"
+	SMTPTLS      = "tls"
+)
+
+// SMTPOptions configures the SMTP stand-in
+type SMTPOptions struct {
+	// Security is SMTPPlain, SMTPStartTLS (required before MAIL) or SMTPTLS (implicit)
+	Security string
+	// Username and Password require AUTH PLAIN or LOGIN before MAIL when set
+	Username string
+	Password string
+}
+
+// SMTPMessage is a message accepted by the SMTP stand-in
+type SMTPMessage struct {
+	From string
+	To   []string
+	// Data is the raw message as sent after DATA, with CRLF line endings
+	Data string
+	// TLS reports whether the message was sent over an encrypted connection
+	TLS bool
+	// Username is the account the client authenticated as, if any
+	Username string
+}
+
+// SMTPServer is an in-process SMTP server that records the messages it receives
+type SMTPServer struct {
+	opts      SMTPOptions
+	listener  net.Listener
+	tlsConfig *tls.Config
+	certPool  *x509.CertPool
+	wg        sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []SMTPMessage
+}
+
+// NewSMTP starts an SMTP stand-in on 127.0.0.1 with a self-signed certificate.
+// Close it when done.
+func NewSMTP(opts SMTPOptions) *SMTPServer {
+	cert, pool, err := selfSignedCert()
+	if err != nil {
+		panic(fmt.Sprintf("fakeserver: failed to create certificate: %v", err))
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("fakeserver: failed to listen: %v", err))
+	}
+
+	s := &SMTPServer{
+		opts:      opts,
+		listener:  listener,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		certPool:  pool,
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Addr returns the host:port the server listens on
+func (s *SMTPServer) Addr() string {
+	return net.JoinHostPort(s.Host(), strconv.Itoa(s.Port()))
+}
+
+// Host returns the address the server listens on, without the port
+func (s *SMTPServer) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port returns the port the server listens on
+func (s *SMTPServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// CertPool trusts the server's self-signed certificate
+func (s *SMTPServer) CertPool() *x509.CertPool {
+	return s.certPool
+}
+
+// Messages returns the messages received so far
+func (s *SMTPServer) Messages() []SMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SMTPMessage(nil), s.messages...)
+}
+
+// Close shuts the server down and waits for open sessions to end
+func (s *SMTPServer) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *SMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+			s.session(conn)
+		}()
+	}
+}
+
+// smtpSession is the state of one client connection
+type smtpSession struct {
+	conn     net.Conn
+	text     *textproto.Conn
+	tls      bool
+	username string
+	msg      *SMTPMessage
+}
+
+func (s *SMTPServer) session(conn net.Conn) {
+	sess := &smtpSession{conn: conn}
+	if s.opts.Security == SMTPTLS {
+		tlsConn := tls.Server(conn, s.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return
+		}
+		sess.conn, sess.tls = tlsConn, true
+	}
+	sess.text = textproto.NewConn(sess.conn)
+
+	sess.reply(220, "fakeserver ESMTP ready")
+	for {
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		if !s.command(sess, strings.ToUpper(verb), arg) {
+			return
+		}
+	}
+}
+
+// command handles one SMTP command and reports whether the session continues
+func (s *SMTPServer) command(sess *smtpSession, verb, arg string) bool {
+	authRequired := s.opts.Username != "" && sess.username == ""
+	tlsRequired := s.opts.Security == SMTPStartTLS && !sess.tls
+
+	switch verb {
+	case "EHLO", "HELO":
+		lines := []string{"fakeserver"}
+		if s.opts.Security == SMTPStartTLS && !sess.tls {
+			lines = append(lines, "STARTTLS")
+		}
+		if s.opts.Username != "" {
+			lines = append(lines, "AUTH PLAIN LOGIN")
+		}
+		lines = append(lines, "8BITMIME")
+		sess.replyLines(250, lines)
+
+	case "STARTTLS":
+		if s.opts.Security != SMTPStartTLS || sess.tls {
+			sess.reply(502, "STARTTLS not available")
+			return true
+		}
+		sess.reply(220, "ready to start TLS")
+		tlsConn := tls.Server(sess.conn, s.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return false
+		}
+		sess.conn, sess.tls = tlsConn, true
+		sess.text = textproto.NewConn(tlsConn)
+
+	case "AUTH":
+		if tlsRequired {
+			sess.reply(530, "must issue STARTTLS first")
+			return true
+		}
+		username, password, err := sess.readAuth(arg)
+		if err != nil {
+			sess.reply(501, err.Error())
+			return true
+		}
+		if username != s.opts.Username || password != s.opts.Password {
+			sess.reply(535, "authentication failed")
+			return true
+		}
+		sess.username = username
+		sess.reply(235, "authenticated")
+
+	case "MAIL":
+		switch {
+		case tlsRequired:
+			sess.reply(530, "must issue STARTTLS first")
+		case authRequired:
+			sess.reply(530, "authentication required")
+		default:
+			sess.msg = &SMTPMessage{From: smtpAddress(arg, "FROM:"), TLS: sess.tls, Username: sess.username}
+			sess.reply(250, "ok")
+		}
+
+	case "RCPT":
+		if sess.msg == nil {
+			sess.reply(503, "need MAIL first")
+			return true
+		}
+		sess.msg.To = append(sess.msg.To, smtpAddress(arg, "TO:"))
+		sess.reply(250, "ok")
+
+	case "DATA":
+		if sess.msg == nil || len(sess.msg.To) == 0 {
+			sess.reply(503, "need RCPT first")
+			return true
+		}
+		sess.reply(354, "end data with <CR><LF>.<CR><LF>")
+		data, err := io.ReadAll(sess.text.DotReader())
+		if err != nil {
+			return false
+		}
+		sess.msg.Data = strings.ReplaceAll(string(data), "\n", "\r\n")
+		s.mu.Lock()
+		s.messages = append(s.messages, *sess.msg)
+		s.mu.Unlock()
+		sess.msg = nil
+		sess.reply(250, "queued")
+
+	case "RSET":
+		sess.msg = nil
+		sess.reply(250, "ok")
+
+	case "NOOP":
+		sess.reply(250, "ok")
+
+	case "QUIT":
+		sess.reply(221, "bye")
+		return false
+
+	default:
+		sess.reply(502, "command not implemented")
+	}
+	return true
+}
+
+// readAuth reads the credentials of an AUTH PLAIN or AUTH LOGIN exchange
+func (sess *smtpSession) readAuth(arg string) (string, string, error) {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		if initial == "" {
+			sess.reply(334, "")
+			line, err := sess.text.ReadLine()
+			if err != nil {
+				return "", "", err
+			}
+			initial = line
+		}
+		decoded, err := base64.StdEncoding.DecodeString(initial)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid base64")
+		}
+		parts := strings.Split(string(decoded), "\x00")
+		if len(parts) != 3 {
+			return "", "", fmt.Errorf("invalid PLAIN credentials")
+		}
+		return parts[1], parts[2], nil
+
+	case "LOGIN":
+		var values []string
+		for _, prompt := range []string{"Username:", "Password:"} {
+			sess.reply(334, base64.StdEncoding.EncodeToString([]byte(prompt)))
+			line, err := sess.text.ReadLine()
+			if err != nil {
+				return "", "", err
+			}
+			value, err := base64.StdEncoding.DecodeString(line)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid base64")
+			}
+			values = append(values, string(value))
+		}
+		return values[0], values[1], nil
+
+	default:
+		return "", "", fmt.Errorf("unsupported mechanism %s", mechanism)
+	}
+}
+
+func (sess *smtpSession) reply(code int, text string) {
+	_ = sess.text.PrintfLine("%d %s", code, text)
+}
+
+func (sess *smtpSession) replyLines(code int, lines []string) {
+	w := sess.text.W
+	for i, line := range lines {
+		sep := "-"
+		if i == len(lines)-1 {
+			sep = " "
+		}
+		fmt.Fprintf(w, "%d%s%s\r\n", code, sep, line)
+	}
+	_ = w.Flush()
+}
+
+// smtpAddress extracts the address from a MAIL FROM:<a> or RCPT TO:<a> argument
+func smtpAddress(arg, prefix string) string {
+	arg = strings.TrimSpace(arg)
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	addr, _, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	return strings.Trim(addr, "<>")
+}
+
+// selfSignedCert creates a certificate for 127.0.0.1 and localhost and a pool trusting it
+func selfSignedCert() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "fakeserver"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool, nil
+}
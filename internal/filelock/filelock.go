@@ -0,0 +1,280 @@
+// Package filelock provides exclusive advisory locks on files, shared by every
+// ag-quota process on the machine. They guard read-modify-write of the config
+// and account files, e.g. so that a cron job and an interactive run do not both
+// refresh the same token.
+package filelock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// DefaultTimeout is how long Acquire waits for a lock held by another process
+	DefaultTimeout = 30 * time.Second
+
+	pollInterval = 25 * time.Millisecond
+)
+
+// ErrTimeout is returned when a lock could not be acquired in time
+var ErrTimeout = errors.New("timed out waiting for lock")
+
+// Options tune how a lock is acquired. Zero values use the defaults.
+type Options struct {
+	Timeout time.Duration
+}
+
+// Holder describes the process holding a lock. It is written into the lock file
+// to explain timeouts and detect locks whose holder has exited.
+type Holder struct {
+	PID        int       `json:"pid"`
+	Hostname   string    `json:"hostname,omitempty"`
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+// Lock is an acquired lock. Release it when the protected operation is done.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire takes the lock at path, creating the lock file and its directory if
+// needed. It waits up to the timeout for another process to release it. The
+// operating system releases the lock when its holder exits, so a lock is only
+// broken when the process recorded as its holder is known to have exited on
+// this host and the lock is still held, e.g. through a descriptor it leaked to
+// a child. A lock held by a running process, however long, is waited for.
+func Acquire(ctx context.Context, path string, opts Options) (*Lock, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	for {
+		lock, holder, err := attempt(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		if lock != nil {
+			return lock, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, timeoutError(path, holder)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// attempt makes one attempt at the lock under its guard, breaking it if its
+// holder has exited. It returns the current holder, if known, when another
+// process holds the lock.
+func attempt(ctx context.Context, path string) (*Lock, *Holder, error) {
+	guard, err := acquireGuard(ctx, path+".guard")
+	if err != nil {
+		if errors.Is(err, ErrTimeout) {
+			holder, _ := ReadHolder(path)
+			return nil, nil, timeoutError(path, holder)
+		}
+		return nil, nil, err
+	}
+	defer releaseGuard(guard)
+
+	lock, err := tryAcquire(path)
+	if err != nil || lock != nil {
+		return lock, nil, err
+	}
+
+	holder, err := breakIfDead(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, holder, nil
+}
+
+// acquireGuard takes the guard lock, which is only held for a moment by each
+// process that takes or breaks the lock. Holding it, a locked lock file always
+// names its holder, and no other process removes the lock file.
+func acquireGuard(ctx context.Context, path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			return file, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ErrTimeout
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func releaseGuard(file *os.File) {
+	_ = unlockFile(file)
+	file.Close()
+}
+
+// breakIfDead removes the lock file at path if the process recorded in it has
+// exited. It must be called under the guard, after the lock was found held.
+// The holder is read through the same handle that is compared with the file at
+// path, so only the lock file that was judged stale is removed.
+func breakIfDead(path string) (*Holder, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	holder, err := parseHolder(data)
+	if err != nil || holder == nil || !holder.dead() {
+		return holder, nil
+	}
+
+	current, errPath := os.Stat(path)
+	opened, errFile := file.Stat()
+	if errPath != nil || errFile != nil || !os.SameFile(current, opened) {
+		return nil, nil
+	}
+
+	// The lock file is replaced rather than unlocked: whatever still holds the
+	// lock keeps it on the old file, and tryAcquire notices when the file it
+	// locked is no longer the one at path
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to break stale lock %s: %w", path, err)
+	}
+	return nil, nil
+}
+
+func timeoutError(path string, holder *Holder) error {
+	if holder != nil {
+		return fmt.Errorf("%w %s: held by pid %d since %s", ErrTimeout, path, holder.PID, holder.AcquiredAt.Local().Format(time.TimeOnly))
+	}
+	return fmt.Errorf("%w %s", ErrTimeout, path)
+}
+
+// tryAcquire makes one attempt at the lock. It returns nil without an error
+// when another process holds it.
+func tryAcquire(path string) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	locked, err := tryLockFile(file)
+	if err != nil || !locked {
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		return nil, nil
+	}
+
+	// A stale lock may have been broken between opening and locking the file,
+	// in which case the lock is on a file nobody else will open again
+	current, errPath := os.Stat(path)
+	opened, errFile := file.Stat()
+	if errPath != nil || errFile != nil || !os.SameFile(current, opened) {
+		_ = unlockFile(file)
+		file.Close()
+		return nil, nil
+	}
+
+	lock := &Lock{file: file}
+	if err := lock.writeHolder(); err != nil {
+		_ = lock.Release()
+		return nil, err
+	}
+	return lock, nil
+}
+
+func (l *Lock) writeHolder() error {
+	hostname, _ := os.Hostname()
+	data, err := json.Marshal(Holder{PID: os.Getpid(), Hostname: hostname, AcquiredAt: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	if err := l.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if _, err := l.file.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// Release unlocks the lock. The lock file is kept for the next process.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	_ = l.file.Truncate(0)
+	errUnlock := unlockFile(l.file)
+	errClose := l.file.Close()
+	l.file = nil
+
+	if errUnlock != nil {
+		return errUnlock
+	}
+	return errClose
+}
+
+// ReadHolder returns the process recorded in the lock file at path, or nil if
+// the lock is free
+func ReadHolder(path string) (*Holder, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseHolder(data)
+}
+
+func parseHolder(data []byte) (*Holder, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var holder Holder
+	if err := json.Unmarshal(data, &holder); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file: %w", err)
+	}
+	return &holder, nil
+}
+
+// dead reports whether the holder is known to have exited. Only processes on
+// this host can be checked.
+func (h *Holder) dead() bool {
+	hostname, _ := os.Hostname()
+	return h.PID > 0 && h.Hostname == hostname && !processAlive(h.PID)
+}
@@ -0,0 +1,300 @@
+package filelock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// envHelper makes the test binary act as one of the processes of TestAcquire_MultiProcess
+	envHelper = "AG_QUOTA_FILELOCK_HELPER"
+
+	// envBreaker makes the test binary act as one of the processes of TestAcquire_ConcurrentBreakers
+	envBreaker = "AG_QUOTA_FILELOCK_BREAKER"
+)
+
+func TestAcquire_Timeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "config.json.lock")
+
+	lock, err := Acquire(context.Background(), path, Options{})
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	holder, err := ReadHolder(path)
+	if err != nil || holder == nil || holder.PID != os.Getpid() {
+		t.Fatalf("expected this process as the holder, got %+v, %v", holder, err)
+	}
+
+	// Locks taken through separate opens conflict even within one process
+	start := time.Now()
+	_, err = Acquire(context.Background(), path, Options{Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("pid %d", os.Getpid())) {
+		t.Errorf("expected the holder in the error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("gave up after %s, before the timeout", elapsed)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if holder, _ := ReadHolder(path); holder != nil {
+		t.Errorf("expected no holder after release, got %+v", holder)
+	}
+
+	again, err := Acquire(context.Background(), path, Options{Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Acquire after release failed: %v", err)
+	}
+	_ = again.Release()
+}
+
+func TestAcquire_BreaksLockOfExitedHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "account.json.lock")
+	hostname, _ := os.Hostname()
+	pid := exitedPID(t)
+
+	// The lock is still held, e.g. through a descriptor leaked to a child, but
+	// the process recorded as its holder has exited
+	leaked, err := Acquire(context.Background(), path, Options{})
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer leaked.Release()
+	writeHolder(t, path, Holder{PID: pid, Hostname: hostname, AcquiredAt: time.Now()})
+
+	lock, err := Acquire(context.Background(), path, Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("expected the stale lock to be broken, got %v", err)
+	}
+	defer lock.Release()
+
+	// The new lock has a live holder and is respected
+	_, err = Acquire(context.Background(), path, Options{Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected ErrTimeout for a fresh lock, got %v", err)
+	}
+}
+
+func TestAcquire_WaitsForLongHeldLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "account.json.lock")
+	hostname, _ := os.Hostname()
+
+	slow, err := Acquire(context.Background(), path, Options{})
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer slow.Release()
+
+	// A running process that has held the lock for an hour, e.g. while waiting
+	// for a passphrase
+	writeHolder(t, path, Holder{PID: os.Getpid(), Hostname: hostname, AcquiredAt: time.Now().Add(-time.Hour)})
+
+	_, err = Acquire(context.Background(), path, Options{Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("pid %d", os.Getpid())) {
+		t.Errorf("expected the holder in the error, got %v", err)
+	}
+}
+
+func TestHolder_Dead(t *testing.T) {
+	hostname, _ := os.Hostname()
+	pid := exitedPID(t)
+
+	tests := []struct {
+		name   string
+		holder Holder
+		dead   bool
+	}{
+		{"live", Holder{PID: os.Getpid(), Hostname: hostname, AcquiredAt: time.Now()}, false},
+		{"live and held long", Holder{PID: os.Getpid(), Hostname: hostname, AcquiredAt: time.Now().Add(-time.Hour)}, false},
+		{"other host", Holder{PID: pid, Hostname: hostname + "-other", AcquiredAt: time.Now()}, false},
+		{"exited", Holder{PID: pid, Hostname: hostname, AcquiredAt: time.Now()}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.holder.dead(); got != tt.dead {
+			t.Errorf("%s: dead = %v, want %v", tt.name, got, tt.dead)
+		}
+	}
+}
+
+func TestAcquire_ConcurrentBreakers(t *testing.T) {
+	const processes = 2
+
+	dir := t.TempDir()
+	hostname, _ := os.Hostname()
+	pid := exitedPID(t)
+
+	leaked, err := Acquire(context.Background(), filepath.Join(dir, "counter.lock"), Options{})
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer leaked.Release()
+	writeHolder(t, filepath.Join(dir, "counter.lock"), Holder{PID: pid, Hostname: hostname, AcquiredAt: time.Now()})
+
+	cmds := make([]*exec.Cmd, processes)
+	for i := range cmds {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperBreaker$")
+		cmd.Env = append(os.Environ(), envBreaker+"="+dir)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("failed to start process %d: %v", i, err)
+		}
+		cmds[i] = cmd
+	}
+
+	// Release both processes at once, so that both find the same stale lock
+	time.Sleep(200 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "start"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("process %d failed: %v", i, err)
+		}
+	}
+}
+
+// TestHelperBreaker takes the stale lock of TestAcquire_ConcurrentBreakers and
+// fails if another process is inside the lock at the same time
+func TestHelperBreaker(t *testing.T) {
+	dir := os.Getenv(envBreaker)
+	if dir == "" {
+		return
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "start")); err == nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	lock, err := Acquire(context.Background(), filepath.Join(dir, "counter.lock"), Options{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	inside := filepath.Join(dir, "inside")
+	marker, err := os.OpenFile(inside, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("another process holds the lock too: %v", err)
+	}
+	marker.Close()
+	time.Sleep(100 * time.Millisecond)
+	if err := os.Remove(inside); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+}
+
+func TestAcquire_MultiProcess(t *testing.T) {
+	const processes, iterations = 4, 25
+
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "counter")
+	if err := os.WriteFile(counter, []byte("0"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := make([]*exec.Cmd, processes)
+	for i := range cmds {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+		cmd.Env = append(os.Environ(), envHelper+"="+dir, "AG_QUOTA_FILELOCK_ITERATIONS="+strconv.Itoa(iterations))
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("failed to start process %d: %v", i, err)
+		}
+		cmds[i] = cmd
+	}
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("process %d failed: %v", i, err)
+		}
+	}
+
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != strconv.Itoa(processes*iterations) {
+		t.Errorf("lost updates: counter is %s, want %d", got, processes*iterations)
+	}
+}
+
+// TestHelperProcess increments the counter of TestAcquire_MultiProcess under the
+// lock, with a pause between read and write that loses updates without it
+func TestHelperProcess(t *testing.T) {
+	dir := os.Getenv(envHelper)
+	if dir == "" {
+		return
+	}
+	iterations, _ := strconv.Atoi(os.Getenv("AG_QUOTA_FILELOCK_ITERATIONS"))
+
+	counter := filepath.Join(dir, "counter")
+	for range iterations {
+		lock, err := Acquire(context.Background(), filepath.Join(dir, "counter.lock"), Options{Timeout: 30 * time.Second})
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+
+		data, err := os.ReadFile(counter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _ := strconv.Atoi(string(data))
+		time.Sleep(time.Millisecond)
+		if err := os.WriteFile(counter, []byte(strconv.Itoa(n+1)), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := lock.Release(); err != nil {
+			t.Fatalf("Release failed: %v", err)
+		}
+	}
+}
+
+func writeHolder(t *testing.T, path string, holder Holder) {
+	t.Helper()
+	data, err := json.Marshal(holder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exitedPID returns the PID of a process that has exited. It skips the test
+// where exited processes cannot be told from running ones.
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("failed to run helper process: %v", err)
+	}
+	if !processAlive(os.Getpid()) || processAlive(cmd.Process.Pid) {
+		t.Skip("cannot detect exited processes on this platform")
+	}
+	return cmd.Process.Pid
+}
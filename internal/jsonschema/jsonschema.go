@@ -0,0 +1,217 @@
+// Package jsonschema generates JSON Schema documents from Go types, so published
+// schemas always match what encoding/json produces.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of generated documents
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema node
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           *Properties        `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+}
+
+// Properties keeps object properties in struct field order
+type Properties struct {
+	names   []string
+	schemas map[string]*Schema
+}
+
+func (p *Properties) set(name string, schema *Schema) {
+	if p.schemas == nil {
+		p.schemas = make(map[string]*Schema)
+	}
+	if _, ok := p.schemas[name]; !ok {
+		p.names = append(p.names, name)
+	}
+	p.schemas[name] = schema
+}
+
+// MarshalJSON writes the properties in field order rather than sorted by name
+func (p *Properties) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, name := range p.names {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(p.schemas[name])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	return []byte(b.String()), nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generate builds the schema of v's type. Named struct types are placed in $defs.
+//
+// Fields are described with struct tags: `json` decides the property name and
+// whether it is required (fields without omitempty are required), `description`
+// sets the description and `enum` lists the allowed values separated by commas.
+func Generate(v interface{}, id, title string) (*Schema, error) {
+	g := &generator{defs: make(map[string]*Schema)}
+
+	root, err := g.schemaFor(reflect.TypeOf(v), true)
+	if err != nil {
+		return nil, err
+	}
+
+	root.Schema = Draft
+	root.ID = id
+	root.Title = title
+	if len(g.defs) > 0 {
+		root.Defs = g.defs
+	}
+	return root, nil
+}
+
+type generator struct {
+	defs map[string]*Schema
+}
+
+func (g *generator) schemaFor(t reflect.Type, root bool) (*Schema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.schemaFor(t.Elem(), false)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := g.schemaFor(t.Elem(), false)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Struct:
+		if root {
+			return g.structSchema(t)
+		}
+		return g.ref(t)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// ref returns a reference to the definition of a named struct, generating it on first use
+func (g *generator) ref(t reflect.Type) (*Schema, error) {
+	name := t.Name()
+	if name == "" {
+		return g.structSchema(t)
+	}
+
+	if _, ok := g.defs[name]; !ok {
+		// Reserve the name first so recursive types terminate
+		g.defs[name] = nil
+		schema, err := g.structSchema(t)
+		if err != nil {
+			return nil, err
+		}
+		g.defs[name] = schema
+	}
+	return &Schema{Ref: "#/$defs/" + name}, nil
+}
+
+func (g *generator) structSchema(t reflect.Type) (*Schema, error) {
+	schema := &Schema{Type: "object", Properties: &Properties{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty, skip := parseJSONTag(field)
+		if skip {
+			continue
+		}
+
+		prop, err := g.schemaFor(field.Type, false)
+		if err != nil {
+			return nil, fmt.Errorf("field %s.%s: %w", t.Name(), field.Name, err)
+		}
+		// Draft 2020-12 allows keywords next to $ref, so references can be annotated too
+		prop.Description = field.Tag.Get("description")
+		if enum := field.Tag.Get("enum"); enum != "" {
+			prop.Enum = strings.Split(enum, ",")
+		}
+
+		schema.Properties.set(name, prop)
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema, nil
+}
+
+// parseJSONTag returns the property name of a field as encoding/json would
+func parseJSONTag(field reflect.StructField) (name string, omitEmpty, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" || opt == "omitzero" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
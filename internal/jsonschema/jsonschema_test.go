@@ -0,0 +1,102 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testItem struct {
+	Name string `json:"name"`
+}
+
+type testDoc struct {
+	ID       string            `json:"id" description:"Identifier"`
+	Count    int               `json:"count,omitempty"`
+	Ratio    float64           `json:"ratio"`
+	Status   string            `json:"status" enum:"OK,FAILED"`
+	Created  time.Time         `json:"created"`
+	Items    []testItem        `json:"items"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Parent   *testItem         `json:"parent,omitempty"`
+	Internal string            `json:"-"`
+	hidden   string
+}
+
+func TestGenerate(t *testing.T) {
+	schema, err := Generate(testDoc{hidden: "x"}, "https://example.com/doc.json", "Doc")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("failed to marshal schema: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid schema JSON: %v", err)
+	}
+
+	if got["$schema"] != Draft || got["$id"] != "https://example.com/doc.json" || got["title"] != "Doc" {
+		t.Errorf("unexpected schema header: %v", got)
+	}
+
+	props, _ := got["properties"].(map[string]interface{})
+	if len(props) != 8 {
+		t.Errorf("expected 8 properties, got %d: %v", len(props), props)
+	}
+	expectations := map[string]string{
+		"id":      `{"description":"Identifier","type":"string"}`,
+		"count":   `{"type":"integer"}`,
+		"ratio":   `{"type":"number"}`,
+		"status":  `{"enum":["OK","FAILED"],"type":"string"}`,
+		"created": `{"format":"date-time","type":"string"}`,
+		"items":   `{"items":{"$ref":"#/$defs/testItem"},"type":"array"}`,
+		"labels":  `{"additionalProperties":{"type":"string"},"type":"object"}`,
+		"parent":  `{"$ref":"#/$defs/testItem"}`,
+	}
+	for name, want := range expectations {
+		prop, err := json.Marshal(props[name])
+		if err != nil {
+			t.Fatalf("failed to marshal property %s: %v", name, err)
+		}
+		if string(prop) != want {
+			t.Errorf("property %s: expected %s, got %s", name, want, prop)
+		}
+	}
+
+	required, _ := json.Marshal(got["required"])
+	if string(required) != `["id","ratio","status","created","items"]` {
+		t.Errorf("unexpected required fields: %s", required)
+	}
+
+	if _, ok := got["$defs"].(map[string]interface{})["testItem"]; !ok {
+		t.Error("expected testItem in $defs")
+	}
+}
+
+func TestGenerate_PropertyOrder(t *testing.T) {
+	schema, err := Generate(testDoc{}, "", "")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	data, err := json.Marshal(schema.Properties)
+	if err != nil {
+		t.Fatalf("failed to marshal properties: %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"id":`) || strings.Index(string(data), `"ratio"`) > strings.Index(string(data), `"status"`) {
+		t.Errorf("properties not in field order: %s", data)
+	}
+}
+
+func TestGenerate_Unsupported(t *testing.T) {
+	if _, err := Generate(struct {
+		C chan int `json:"c"`
+	}{}, "", ""); err == nil {
+		t.Error("expected an error for unsupported types")
+	}
+}
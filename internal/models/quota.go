@@ -4,23 +4,27 @@ import "time"
 
 // ModelQuota represents quota information for a single model
 type ModelQuota struct {
-	ModelID           string
-	DisplayName       string
-	Label             string
-	Provider          string
-	RemainingFraction float64
-	ResetTime         time.Time
-	IsExhausted       bool
+	ModelID           string    `json:"model_id"`
+	DisplayName       string    `json:"display_name"`
+	Label             string    `json:"label,omitempty"`
+	Provider          string    `json:"provider,omitempty"`
+	RemainingFraction float64   `json:"remaining_fraction"`
+	ResetTime         time.Time `json:"reset_time"`
+	IsExhausted       bool      `json:"is_exhausted"`
 }
 
 // QuotaSummary represents the complete quota information
 type QuotaSummary struct {
-	Email          string
-	TierName       string
-	ProjectID      string
-	Models         []ModelQuota
-	DefaultModelID string
-	FetchedAt      time.Time
+	Email    string `json:"email"`
+	TierName string `json:"tier_name"`
+	// Tier holds the raw tier ID and the server's tier metadata
+	Tier           *TierInfo    `json:"tier,omitempty"`
+	ProjectID      string       `json:"project_id,omitempty"`
+	Models         []ModelQuota `json:"models"`
+	DefaultModelID string       `json:"default_model_id,omitempty"`
+	// Endpoint is the Cloud Code API endpoint that served the data
+	Endpoint  string    `json:"endpoint,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
 }
 
 // LoadCodeAssistRequest represents the request to load code assist
@@ -40,11 +44,6 @@ type LoadCodeAssistResponse struct {
 	AllowedTiers            []Tier      `json:"allowedTiers,omitempty"`
 }
 
-type Tier struct {
-	ID        string `json:"id,omitempty"`
-	IsDefault bool   `json:"isDefault,omitempty"`
-}
-
 // OnboardUserRequest represents the request to onboard user
 type OnboardUserRequest struct {
 	TierID   string   `json:"tierId,omitempty"`
@@ -139,20 +138,8 @@ func (q ModelQuota) GetStatusString() string {
 	return "HEALTHY"
 }
 
-// MapTierToName maps a Tier ID to a human-readable name and emoji
+// MapTierToName maps a Tier ID to a human-readable name and emoji.
+// Prefer TierInfo, which also uses the display name returned by the server.
 func MapTierToName(tierID string) string {
-	switch tierID {
-	case "GEMINI_ADVANCED", "GEMINI_ULTRA":
-		return "Ultra 🚀"
-	case "GEMINI_PRO", "CLAUDE_PRO":
-		return "Pro 💎"
-	case "":
-		return "Free 📦"
-	default:
-		// Attempt to guess if it contains PRO or ADVANCED
-		if tierID == "FREE" {
-			return "Free 📦"
-		}
-		return "Free 📦" // Default fallback
-	}
+	return NewTierInfo(&Tier{ID: tierID}).Label
 }
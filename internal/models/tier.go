@@ -0,0 +1,177 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Tier is a Cloud Code tier as returned by loadCodeAssist
+type Tier struct {
+	ID                                 string         `json:"id,omitempty"`
+	Name                               string         `json:"name,omitempty"`
+	Description                        string         `json:"description,omitempty"`
+	IsDefault                          bool           `json:"isDefault,omitempty"`
+	UserDefinedCloudAICompanionProject bool           `json:"userDefinedCloudaicompanionProject,omitempty"`
+	PrivacyNotice                      *PrivacyNotice `json:"privacyNotice,omitempty"`
+	UpgradeSubscriptionURI             string         `json:"upgradeSubscriptionUri,omitempty"`
+	UpgradeSubscriptionText            string         `json:"upgradeSubscriptionText,omitempty"`
+}
+
+// PrivacyNotice is the privacy notice attached to a tier
+type PrivacyNotice struct {
+	ShowNotice bool   `json:"showNotice,omitempty"`
+	NoticeText string `json:"noticeText,omitempty"`
+}
+
+// TierInfo describes the tier of an account for display
+type TierInfo struct {
+	// ID is the raw tier ID returned by the server
+	ID string `json:"id"`
+	// Label is the name shown to users, e.g. "Pro 💎" or "Unknown (some-tier)"
+	Label string `json:"label"`
+	// Name is the display name returned by the server, if any
+	Name          string `json:"name,omitempty"`
+	Description   string `json:"description,omitempty"`
+	PrivacyNotice string `json:"privacy_notice,omitempty"`
+	UpgradeURL    string `json:"upgrade_url,omitempty"`
+	UpgradeText   string `json:"upgrade_text,omitempty"`
+	// Known reports whether the tier ID is one of the built-in tiers
+	Known bool `json:"known"`
+}
+
+// tierLabels maps the tier IDs we know about to their labels
+var tierLabels = map[string]string{
+	"free-tier":       "Free 📦",
+	"FREE":            "Free 📦",
+	"legacy-tier":     "Legacy 📦",
+	"standard-tier":   "Standard 💼",
+	"g1-pro-tier":     "Pro 💎",
+	"GEMINI_PRO":      "Pro 💎",
+	"CLAUDE_PRO":      "Pro 💎",
+	"g1-ultra-tier":   "Ultra 🚀",
+	"GEMINI_ULTRA":    "Ultra 🚀",
+	"GEMINI_ADVANCED": "Ultra 🚀",
+}
+
+// NewTierInfo builds the display information of a tier. Built-in labels take
+// precedence, then the server's display name. Anything else is shown as unknown
+// rather than guessed.
+func NewTierInfo(tier *Tier) TierInfo {
+	if tier == nil {
+		tier = &Tier{}
+	}
+
+	info := TierInfo{
+		ID:          tier.ID,
+		Name:        tier.Name,
+		Description: tier.Description,
+		UpgradeURL:  tier.UpgradeSubscriptionURI,
+		UpgradeText: tier.UpgradeSubscriptionText,
+	}
+	if tier.PrivacyNotice != nil && tier.PrivacyNotice.ShowNotice {
+		info.PrivacyNotice = tier.PrivacyNotice.NoticeText
+	}
+
+	label, known := tierLabels[tier.ID]
+	switch {
+	case known:
+		info.Label = label
+		info.Known = true
+	case tier.Name != "":
+		info.Label = tier.Name
+	case tier.ID != "":
+		info.Label = fmt.Sprintf("Unknown (%s)", tier.ID)
+	default:
+		info.Label = "Unknown"
+	}
+
+	return info
+}
+
+// TierRegistry remembers the tiers returned by the server, so the server's
+// metadata is still available when only the tier ID is known (e.g. when cached)
+type TierRegistry struct {
+	mu    sync.RWMutex
+	tiers map[string]Tier
+}
+
+// NewTierRegistry creates a registry holding the given tiers
+func NewTierRegistry(tiers ...Tier) *TierRegistry {
+	r := &TierRegistry{tiers: make(map[string]Tier)}
+	for i := range tiers {
+		r.Register(&tiers[i])
+	}
+	return r
+}
+
+// Register records a tier. Fields missing from the tier keep their previously
+// registered values. It reports whether the registry changed.
+func (r *TierRegistry) Register(tier *Tier) bool {
+	if tier == nil || tier.ID == "" {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing := r.tiers[tier.ID]
+	merged := *tier
+	// IsDefault only makes sense within one response and is not kept
+	merged.IsDefault = false
+	if merged.Name == "" {
+		merged.Name = existing.Name
+	}
+	if merged.Description == "" {
+		merged.Description = existing.Description
+	}
+	if merged.PrivacyNotice == nil {
+		merged.PrivacyNotice = existing.PrivacyNotice
+	}
+	if merged.UpgradeSubscriptionURI == "" {
+		merged.UpgradeSubscriptionURI = existing.UpgradeSubscriptionURI
+	}
+	if merged.UpgradeSubscriptionText == "" {
+		merged.UpgradeSubscriptionText = existing.UpgradeSubscriptionText
+	}
+
+	if tiersEqual(existing, merged) {
+		return false
+	}
+	r.tiers[tier.ID] = merged
+	return true
+}
+
+// Lookup returns the display information of a tier ID, using the registered server metadata if any
+func (r *TierRegistry) Lookup(tierID string) TierInfo {
+	r.mu.RLock()
+	tier, ok := r.tiers[tierID]
+	r.mu.RUnlock()
+
+	if !ok {
+		tier = Tier{ID: tierID}
+	}
+	return NewTierInfo(&tier)
+}
+
+// Tiers returns all registered tiers
+func (r *TierRegistry) Tiers() []Tier {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tiers := make([]Tier, 0, len(r.tiers))
+	for _, t := range r.tiers {
+		tiers = append(tiers, t)
+	}
+	return tiers
+}
+
+func tiersEqual(a, b Tier) bool {
+	if (a.PrivacyNotice == nil) != (b.PrivacyNotice == nil) {
+		return false
+	}
+	if a.PrivacyNotice != nil && *a.PrivacyNotice != *b.PrivacyNotice {
+		return false
+	}
+	a.PrivacyNotice, b.PrivacyNotice = nil, nil
+	return a == b
+}
@@ -0,0 +1,92 @@
+package models
+
+import "testing"
+
+func TestNewTierInfo(t *testing.T) {
+	tests := []struct {
+		name      string
+		tier      *Tier
+		wantLabel string
+		wantKnown bool
+	}{
+		{"Known tier", &Tier{ID: "g1-pro-tier", Name: "Google AI Pro"}, "Pro 💎", true},
+		{"Unknown tier with server name", &Tier{ID: "enterprise-tier", Name: "Enterprise"}, "Enterprise", false},
+		{"Unknown tier without name", &Tier{ID: "mystery-tier"}, "Unknown (mystery-tier)", false},
+		{"No tier", nil, "Unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := NewTierInfo(tt.tier)
+			if info.Label != tt.wantLabel {
+				t.Errorf("expected label %q, got %q", tt.wantLabel, info.Label)
+			}
+			if info.Known != tt.wantKnown {
+				t.Errorf("expected known=%v, got %v", tt.wantKnown, info.Known)
+			}
+			if tt.tier != nil && info.ID != tt.tier.ID {
+				t.Errorf("expected raw ID %q, got %q", tt.tier.ID, info.ID)
+			}
+		})
+	}
+}
+
+func TestNewTierInfo_ServerFields(t *testing.T) {
+	info := NewTierInfo(&Tier{
+		ID:                      "standard-tier",
+		Name:                    "Gemini Code Assist Standard",
+		Description:             "Unlimited coding assistant",
+		PrivacyNotice:           &PrivacyNotice{ShowNotice: true, NoticeText: "Your data is not used for training"},
+		UpgradeSubscriptionURI:  "https://example.com/upgrade",
+		UpgradeSubscriptionText: "Upgrade now",
+	})
+
+	if info.Name != "Gemini Code Assist Standard" || info.Description != "Unlimited coding assistant" {
+		t.Errorf("server name and description not kept: %+v", info)
+	}
+	if info.PrivacyNotice != "Your data is not used for training" {
+		t.Errorf("unexpected privacy notice: %q", info.PrivacyNotice)
+	}
+	if info.UpgradeURL != "https://example.com/upgrade" || info.UpgradeText != "Upgrade now" {
+		t.Errorf("upgrade fields not kept: %+v", info)
+	}
+}
+
+func TestMapTierToName(t *testing.T) {
+	if got := MapTierToName("GEMINI_ULTRA"); got != "Ultra 🚀" {
+		t.Errorf("expected Ultra 🚀, got %s", got)
+	}
+	if got := MapTierToName("paid-seat"); got != "Unknown (paid-seat)" {
+		t.Errorf("unknown tiers must not be reported as Free, got %s", got)
+	}
+}
+
+func TestTierRegistry(t *testing.T) {
+	registry := NewTierRegistry(Tier{ID: "enterprise-tier", Name: "Enterprise", Description: "For teams"})
+
+	if info := registry.Lookup("enterprise-tier"); info.Label != "Enterprise" || info.Description != "For teams" {
+		t.Errorf("unexpected lookup result: %+v", info)
+	}
+
+	// Registering the same tier without metadata keeps what is known
+	if registry.Register(&Tier{ID: "enterprise-tier", IsDefault: true}) {
+		t.Error("expected no change when registering a tier without new metadata")
+	}
+	if info := registry.Lookup("enterprise-tier"); info.Name != "Enterprise" {
+		t.Errorf("metadata was lost: %+v", info)
+	}
+
+	if !registry.Register(&Tier{ID: "enterprise-tier", Name: "Enterprise Plus"}) {
+		t.Error("expected a change when the server name changes")
+	}
+	if info := registry.Lookup("enterprise-tier"); info.Label != "Enterprise Plus" {
+		t.Errorf("expected updated name, got %+v", info)
+	}
+
+	if info := registry.Lookup("other-tier"); info.Label != "Unknown (other-tier)" {
+		t.Errorf("unexpected lookup result for unregistered tier: %+v", info)
+	}
+	if registry.Register(&Tier{}) {
+		t.Error("tiers without ID must be ignored")
+	}
+}
@@ -0,0 +1,234 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Discord embed limits
+const (
+	discordMaxTitleLength       = 256
+	discordMaxDescriptionLength = 4096
+	discordMaxFieldNameLength   = 256
+	discordMaxFieldValueLength  = 1024
+	discordMaxFields            = 25
+	discordMaxEmbedLength       = 6000
+)
+
+const (
+	// discordMaxRetries is how often a rate-limited message is retried
+	discordMaxRetries = 3
+	// discordMaxRetryWait is the longest rate-limit wait before giving up
+	discordMaxRetryWait = 30 * time.Second
+)
+
+// DiscordNotifier implements the Notifier interface for Discord webhooks
+type DiscordNotifier struct {
+	webhookURL string
+	formatter  *MessageFormatter
+	client     *http.Client
+}
+
+// NewDiscordNotifier creates a new Discord notifier posting to a channel webhook URL
+func NewDiscordNotifier(webhookURL string) *DiscordNotifier {
+	return &DiscordNotifier{
+		webhookURL: webhookURL,
+		// Discord renders <t:unix:R> as a live countdown in the reader's locale
+		formatter: &MessageFormatter{resetText: func(reset time.Time) string {
+			return fmt.Sprintf("<t:%d:R>", reset.Unix())
+		}},
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (d *DiscordNotifier) Name() string {
+	return "discord"
+}
+
+func (d *DiscordNotifier) IsEnabled() bool {
+	return d.webhookURL != ""
+}
+
+// discordPayload is the body of a webhook execution
+type discordPayload struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+}
+
+type discordField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// DiscordResponse represents an error or rate-limit response from Discord
+type DiscordResponse struct {
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after"`
+	Global     bool    `json:"global"`
+}
+
+// Send posts the message as an embed. Rate-limited requests are retried after
+// the wait Discord asks for.
+func (d *DiscordNotifier) Send(ctx context.Context, msg Message) error {
+	if !d.IsEnabled() {
+		return fmt.Errorf("discord notifier not configured")
+	}
+
+	body, err := json.Marshal(discordPayload{Embeds: []discordEmbed{d.buildEmbed(msg, time.Now())}})
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := d.post(ctx, body)
+		if err == nil || retryAfter == 0 {
+			return err
+		}
+		if attempt == discordMaxRetries || retryAfter > discordMaxRetryWait {
+			return fmt.Errorf("%w (retry after %s)", err, retryAfter.Round(time.Millisecond))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryAfter):
+		}
+	}
+}
+
+// post executes the webhook once. For a 429 response it returns how long to wait.
+func (d *DiscordNotifier) post(ctx context.Context, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", d.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+
+	var dcResp DiscordResponse
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&dcResp)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return discordRetryAfter(resp.Header, dcResp), fmt.Errorf("discord rate limit exceeded")
+	}
+	if decodeErr == nil && dcResp.Message != "" {
+		return 0, fmt.Errorf("discord API error (%d): %s", resp.StatusCode, dcResp.Message)
+	}
+	return 0, fmt.Errorf("discord API error: status code %d", resp.StatusCode)
+}
+
+// discordRetryAfter reads the rate-limit wait from the body, falling back to
+// the Retry-After header and then to one second
+func discordRetryAfter(header http.Header, resp DiscordResponse) time.Duration {
+	if resp.RetryAfter > 0 {
+		return time.Duration(resp.RetryAfter * float64(time.Second))
+	}
+	if seconds, err := strconv.ParseFloat(header.Get("Retry-After"), 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return time.Second
+}
+
+// buildEmbed lays out the message as an embed with one field per account, or
+// the plain body as description for messages without changes
+func (d *DiscordNotifier) buildEmbed(msg Message, now time.Time) discordEmbed {
+	embed := discordEmbed{
+		Title:     truncate(fmt.Sprintf("%s %s", severityEmoji(msg.Severity), msg.Title), discordMaxTitleLength),
+		Color:     discordColor(msg.Severity),
+		Timestamp: now.UTC().Format(time.RFC3339),
+	}
+
+	if len(msg.Changes) == 0 {
+		embed.Description = truncate(msg.Body, discordMaxDescriptionLength)
+		return embed
+	}
+
+	// Leave room for the field that summarizes accounts which did not fit
+	budget := discordMaxEmbedLength - len([]rune(embed.Title)) - 100
+	sections := d.formatter.FormatAccounts(msg.Changes)
+	for i, section := range sections {
+		field := discordField{
+			Name:  truncate("👤 "+section.Account, discordMaxFieldNameLength),
+			Value: truncate(strings.TrimSpace(section.Text), discordMaxFieldValueLength),
+		}
+		size := len([]rune(field.Name)) + len([]rune(field.Value))
+
+		if len(embed.Fields) == discordMaxFields-1 && i < len(sections)-1 || size > budget {
+			embed.Fields = append(embed.Fields, discordField{
+				Name:  "…",
+				Value: fmt.Sprintf("and %d more accounts", len(sections)-i),
+			})
+			break
+		}
+		embed.Fields = append(embed.Fields, field)
+		budget -= size
+	}
+	return embed
+}
+
+// discordColor returns the embed color for a severity
+func discordColor(severity Severity) int {
+	switch severity {
+	case SeverityCritical:
+		return 0xED4245
+	case SeverityWarning:
+		return 0xFEE75C
+	case SeverityRecovery:
+		return 0x57F287
+	default:
+		return 0x5865F2
+	}
+}
+
+// Validate checks that the webhook exists by fetching it
+func (d *DiscordNotifier) Validate(ctx context.Context) error {
+	if d.webhookURL == "" {
+		return fmt.Errorf("webhook URL is empty")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", d.webhookURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var dcResp DiscordResponse
+		if err := json.NewDecoder(resp.Body).Decode(&dcResp); err == nil && dcResp.Message != "" {
+			return fmt.Errorf("invalid webhook: %s", dcResp.Message)
+		}
+		return fmt.Errorf("invalid webhook: status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
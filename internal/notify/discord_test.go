@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiscordNotifier_Send(t *testing.T) {
+	ctx := context.Background()
+	resetTime := time.Now().Add(2 * time.Hour)
+	changes := []StatusChange{
+		{Account: "a@example.com", DisplayName: "Model A", OldStatus: "WARNING", NewStatus: "EMPTY", OldPercentage: 30, NewPercentage: 0, ResetTime: resetTime},
+		{Account: "b@example.com", DisplayName: "Model B", OldStatus: "HEALTHY", NewStatus: "WARNING", OldPercentage: 90, NewPercentage: 45},
+	}
+	msg := NewMessageFormatter().FormatChanges(changes)
+
+	t.Run("Success", func(t *testing.T) {
+		var payload discordPayload
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Errorf("invalid payload: %v", err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		dn := NewDiscordNotifier(server.URL)
+		if err := dn.Send(ctx, msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(payload.Embeds) != 1 {
+			t.Fatalf("expected 1 embed, got %d", len(payload.Embeds))
+		}
+		embed := payload.Embeds[0]
+		if embed.Title != "🚨 "+msg.Title || embed.Color != discordColor(SeverityCritical) {
+			t.Errorf("unexpected title or color: %q %#x", embed.Title, embed.Color)
+		}
+		if len(embed.Fields) != 2 || embed.Fields[0].Name != "👤 a@example.com" || embed.Fields[1].Name != "👤 b@example.com" {
+			t.Fatalf("expected one field per account, got %+v", embed.Fields)
+		}
+		if want := fmt.Sprintf("Model A | 0%% (↓ 30%%) ⏳ <t:%d:R>", resetTime.Unix()); !strings.Contains(embed.Fields[0].Value, want) {
+			t.Errorf("field missing countdown %q:\n%s", want, embed.Fields[0].Value)
+		}
+		if !strings.Contains(embed.Fields[1].Value, "Model B | 45% (↓ 45%)") {
+			t.Errorf("unexpected field: %s", embed.Fields[1].Value)
+		}
+	})
+
+	t.Run("Rate Limited", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 0.01, "global": false}`))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		dn := NewDiscordNotifier(server.URL)
+		if err := dn.Send(ctx, msg); err != nil {
+			t.Fatalf("expected the retry to succeed, got %v", err)
+		}
+		if calls != 2 {
+			t.Errorf("expected 2 calls, got %d", calls)
+		}
+	})
+
+	t.Run("Rate Limited Too Long", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		dn := NewDiscordNotifier(server.URL)
+		err := dn.Send(ctx, msg)
+		if err == nil || !strings.Contains(err.Error(), "rate limit") {
+			t.Errorf("expected rate limit error, got %v", err)
+		}
+	})
+
+	t.Run("API Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message": "Invalid Form Body", "code": 50035}`))
+		}))
+		defer server.Close()
+
+		dn := NewDiscordNotifier(server.URL)
+		err := dn.Send(ctx, msg)
+		if err == nil || !strings.Contains(err.Error(), "Invalid Form Body") {
+			t.Errorf("expected API error, got %v", err)
+		}
+	})
+
+	t.Run("Not Configured", func(t *testing.T) {
+		dn := NewDiscordNotifier("")
+		if dn.IsEnabled() {
+			t.Error("expected notifier without webhook URL to be disabled")
+		}
+		if err := dn.Send(ctx, msg); err == nil {
+			t.Error("expected error when not configured")
+		}
+	})
+}
+
+func TestDiscordNotifier_BuildEmbed(t *testing.T) {
+	dn := NewDiscordNotifier("https://discord.com/api/webhooks/1/x")
+
+	t.Run("Without Changes", func(t *testing.T) {
+		embed := dn.buildEmbed(Message{Title: "Hello", Body: "plain body", Severity: SeverityRecovery}, time.Now())
+		if embed.Description != "plain body" || len(embed.Fields) != 0 || embed.Color != discordColor(SeverityRecovery) {
+			t.Errorf("unexpected embed: %+v", embed)
+		}
+	})
+
+	t.Run("Too Many Accounts", func(t *testing.T) {
+		var changes []StatusChange
+		for i := 0; i < discordMaxFields+5; i++ {
+			changes = append(changes, StatusChange{Account: fmt.Sprintf("user%02d@example.com", i), DisplayName: "M", OldStatus: "INITIAL", NewStatus: "HEALTHY", NewPercentage: 100})
+		}
+		embed := dn.buildEmbed(NewMessageFormatter().FormatChanges(changes), time.Now())
+		if len(embed.Fields) != discordMaxFields {
+			t.Fatalf("expected %d fields, got %d", discordMaxFields, len(embed.Fields))
+		}
+		if last := embed.Fields[discordMaxFields-1]; last.Value != "and 6 more accounts" {
+			t.Errorf("expected a summary of the remaining accounts, got %+v", last)
+		}
+	})
+
+	t.Run("Embed Size Limit", func(t *testing.T) {
+		var changes []StatusChange
+		for i := 0; i < 10; i++ {
+			for j := 0; j < 20; j++ {
+				changes = append(changes, StatusChange{Account: fmt.Sprintf("user%d@example.com", i), DisplayName: fmt.Sprintf("A rather long model name %d", j), OldStatus: "INITIAL", NewStatus: "WARNING", NewPercentage: 40})
+			}
+		}
+		embed := dn.buildEmbed(NewMessageFormatter().FormatChanges(changes), time.Now())
+
+		size := len([]rune(embed.Title))
+		for _, field := range embed.Fields {
+			if len([]rune(field.Value)) > discordMaxFieldValueLength {
+				t.Errorf("field value longer than %d", discordMaxFieldValueLength)
+			}
+			size += len([]rune(field.Name)) + len([]rune(field.Value))
+		}
+		if size > discordMaxEmbedLength {
+			t.Errorf("embed is %d characters, more than %d", size, discordMaxEmbedLength)
+		}
+		if last := embed.Fields[len(embed.Fields)-1]; !strings.HasPrefix(last.Value, "and ") {
+			t.Errorf("expected a summary of the remaining accounts, got %+v", last)
+		}
+	})
+}
+
+func TestDiscordNotifier_Validate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("expected GET, got %s", r.Method)
+		}
+		if strings.HasSuffix(r.URL.Path, "/bad") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Unknown Webhook", "code": 10015}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "1", "name": "quota"}`))
+	}))
+	defer server.Close()
+
+	if err := NewDiscordNotifier(server.URL + "/good").Validate(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := NewDiscordNotifier(server.URL + "/bad").Validate(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "Unknown Webhook") {
+		t.Errorf("expected Unknown Webhook error, got %v", err)
+	}
+}
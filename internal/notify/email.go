@@ -0,0 +1,392 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Connection security for SMTP
+const (
+	// EmailSecurityStartTLS upgrades a plain connection with STARTTLS (default, port 587)
+	EmailSecurityStartTLS = "starttls"
+	// EmailSecurityTLS connects with TLS from the start (port 465)
+	EmailSecurityTLS = "tls"
+	// EmailSecurityNone sends unencrypted, e.g. to a relay on localhost (port 25)
+	EmailSecurityNone = "none"
+)
+
+// DefaultEmailSubjectTemplate puts the highest severity in front of the title
+const DefaultEmailSubjectTemplate = `[ag-quota] {{upper .Severity}}: {{.Title}}`
+
+// emailTimeout bounds a whole SMTP session
+const emailTimeout = 30 * time.Second
+
+// EmailOptions configures an EmailNotifier. Zero values use the defaults.
+type EmailOptions struct {
+	Host string
+	// Port defaults to 587 for STARTTLS, 465 for TLS and 25 without encryption
+	Port int
+	// Security is EmailSecurityStartTLS (default), EmailSecurityTLS or EmailSecurityNone
+	Security string
+	// Username and Password authenticate with AUTH PLAIN when set
+	Username string
+	Password string
+	From     string
+	To       []string
+	// SubjectTemplate is a text/template executed with an EmailSubject
+	SubjectTemplate string
+}
+
+// EmailSubject is the data the subject template is executed with
+type EmailSubject struct {
+	Title string
+	// Severity is the highest severity of the message, e.g. "critical"
+	Severity string
+	// Changes and Accounts count the status changes and the accounts they belong to
+	Changes  int
+	Accounts int
+}
+
+// EmailNotifier implements the Notifier interface for SMTP
+type EmailNotifier struct {
+	host      string
+	port      int
+	security  string
+	username  string
+	password  string
+	from      *mail.Address
+	to        []*mail.Address
+	subject   *template.Template
+	formatter *MessageFormatter
+	// rootCAs verifies the server certificate; nil uses the system roots
+	rootCAs *x509.CertPool
+}
+
+// NewEmailNotifier creates an email notifier. It fails on invalid addresses,
+// an unknown security mode or a subject template that does not parse.
+func NewEmailNotifier(opts EmailOptions) (*EmailNotifier, error) {
+	e := &EmailNotifier{
+		host:      opts.Host,
+		port:      opts.Port,
+		security:  strings.ToLower(opts.Security),
+		username:  opts.Username,
+		password:  opts.Password,
+		formatter: NewMessageFormatter(),
+	}
+
+	defaultPort := 587
+	switch e.security {
+	case "", EmailSecurityStartTLS:
+		e.security = EmailSecurityStartTLS
+	case EmailSecurityTLS:
+		defaultPort = 465
+	case EmailSecurityNone:
+		defaultPort = 25
+	default:
+		return nil, fmt.Errorf("unknown email security %q (expected %s, %s or %s)", opts.Security, EmailSecurityStartTLS, EmailSecurityTLS, EmailSecurityNone)
+	}
+	if e.port == 0 {
+		e.port = defaultPort
+	}
+
+	if opts.From != "" {
+		from, err := mail.ParseAddress(opts.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sender %q: %w", opts.From, err)
+		}
+		e.from = from
+	}
+	for _, to := range opts.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+		}
+		e.to = append(e.to, addr)
+	}
+
+	subject, err := ParseEmailSubjectTemplate(opts.SubjectTemplate)
+	if err != nil {
+		return nil, err
+	}
+	e.subject = subject
+
+	return e, nil
+}
+
+// ParseEmailSubjectTemplate parses a subject template, or the default one if text is empty
+func ParseEmailSubjectTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultEmailSubjectTemplate
+	}
+
+	tmpl, err := template.New("subject").Funcs(template.FuncMap{
+		"upper": strings.ToUpper,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func (e *EmailNotifier) Name() string {
+	return "email"
+}
+
+func (e *EmailNotifier) IsEnabled() bool {
+	return e.host != "" && e.from != nil && len(e.to) > 0
+}
+
+// Send delivers the message to all recipients as one email
+func (e *EmailNotifier) Send(ctx context.Context, msg Message) error {
+	if !e.IsEnabled() {
+		return fmt.Errorf("email notifier not configured")
+	}
+
+	data, err := e.buildMessage(msg, time.Now())
+	if err != nil {
+		return err
+	}
+	return e.deliver(ctx, data)
+}
+
+// deliver runs an SMTP session that sends data to the recipients
+func (e *EmailNotifier) deliver(ctx context.Context, data []byte) error {
+	addr := net.JoinHostPort(e.host, strconv.Itoa(e.port))
+	tlsConfig := &tls.Config{ServerName: e.host, RootCAs: e.rootCAs}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if e.security == EmailSecurityTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+
+	deadline := time.Now().Add(emailTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp error: %w", err)
+	}
+	defer client.Close()
+
+	if e.security == EmailSecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	if e.username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("%s does not support authentication", addr)
+		}
+		if err := client.Auth(smtp.PlainAuth("", e.username, e.password, e.host)); err != nil {
+			return fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(e.from.Address); err != nil {
+		return fmt.Errorf("smtp error: %w", err)
+	}
+	for _, to := range e.to {
+		if err := client.Rcpt(to.Address); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", to.Address, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp error: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("smtp error: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp error: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMessage renders the message as a multipart/alternative email with a
+// plain-text and an HTML part
+func (e *EmailNotifier) buildMessage(msg Message, now time.Time) ([]byte, error) {
+	var sections []AccountSection
+	if len(msg.Changes) > 0 {
+		sections = e.formatter.FormatAccounts(msg.Changes)
+	}
+
+	var subject strings.Builder
+	err := e.subject.Execute(&subject, EmailSubject{
+		Title:    msg.Title,
+		Severity: msg.Severity.String(),
+		Changes:  len(msg.Changes),
+		Accounts: len(sections),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render email subject: %w", err)
+	}
+
+	var html bytes.Buffer
+	err = emailHTMLTemplate.Execute(&html, emailHTMLData{
+		Title:    msg.Title,
+		Body:     msg.Body,
+		Color:    severityColor(msg.Severity),
+		Sections: sections,
+		SentAt:   now.Format(time.RFC1123),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render email body: %w", err)
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	var to []string
+	for _, addr := range e.to {
+		to = append(to, addr.String())
+	}
+
+	var buf bytes.Buffer
+	writeHeader := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	writeHeader("From", e.from.String())
+	writeHeader("To", strings.Join(to, ", "))
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject.String()), " ")))
+	writeHeader("Date", now.Format(time.RFC1123Z))
+	writeHeader("Message-ID", e.messageID())
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", parts.Boundary()))
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", emailPlainText(msg, sections)},
+		{"text/html; charset=UTF-8", html.String()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// messageID returns a unique Message-ID in the sender's domain
+func (e *EmailNotifier) messageID() string {
+	random := make([]byte, 12)
+	_, _ = rand.Read(random)
+
+	domain := "ag-quota.local"
+	if _, d, ok := strings.Cut(e.from.Address, "@"); ok && d != "" {
+		domain = d
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(random), domain)
+}
+
+// emailPlainText lays out the message as plain text, one block per account
+func emailPlainText(msg Message, sections []AccountSection) string {
+	var sb strings.Builder
+	sb.WriteString(msg.Title + "\n\n")
+
+	if len(sections) == 0 {
+		sb.WriteString(msg.Body + "\n")
+		return sb.String()
+	}
+
+	for i, section := range sections {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("👤 " + section.Account + "\n")
+		for _, status := range section.Statuses {
+			sb.WriteString("  " + status.Label + "\n")
+			for _, line := range status.Lines {
+				sb.WriteString("    - " + line + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// emailHTMLData is the data the HTML body is rendered with
+type emailHTMLData struct {
+	Title    string
+	Body     string
+	Color    string
+	Sections []AccountSection
+	SentAt   string
+}
+
+var emailHTMLTemplate = htmltemplate.Must(htmltemplate.New("email").Funcs(htmltemplate.FuncMap{
+	"color": severityColor,
+}).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: -apple-system, Segoe UI, Helvetica, Arial, sans-serif; color: #1d1c1d; line-height: 1.4;">
+<h2 style="border-left: 4px solid {{.Color}}; padding-left: 8px;">{{.Title}}</h2>
+{{- range .Sections}}
+<div style="border-left: 4px solid {{color .Severity}}; padding: 4px 12px; margin: 12px 0;">
+<h3 style="margin: 4px 0;">👤 {{.Account}}</h3>
+{{- range .Statuses}}
+<p style="margin: 8px 0 4px;"><strong>{{.Label}}</strong></p>
+<ul style="margin: 0; padding-left: 20px;">
+{{- range .Lines}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</div>
+{{- else}}
+<pre style="font-family: inherit; white-space: pre-wrap;">{{.Body}}</pre>
+{{- end}}
+<p style="color: #616061; font-size: 12px;">Sent by ag-quota on {{.SentAt}}</p>
+</body>
+</html>
+`))
@@ -0,0 +1,255 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/fakeserver"
+)
+
+// parseEmail splits a raw email into its decoded subject and the plain-text and HTML parts
+func parseEmail(t *testing.T, data string) (subject, plain, html string) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid email: %v", err)
+	}
+	subject, err = new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("invalid subject: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative, got %q (%v)", mediaType, err)
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid part: %v", err)
+		}
+		// multipart.Reader decodes quoted-printable parts itself
+		content, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+		switch {
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain"):
+			plain = string(content)
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/html"):
+			html = string(content)
+		}
+	}
+	return subject, plain, html
+}
+
+func TestEmailNotifier_Send(t *testing.T) {
+	ctx := context.Background()
+	changes := []StatusChange{
+		{Account: "a@example.com", DisplayName: "Model <A>", OldStatus: "WARNING", NewStatus: "EMPTY", OldPercentage: 30, NewPercentage: 0, ResetTime: time.Now().Add(2*time.Hour + time.Minute)},
+		{Account: "b@example.com", DisplayName: "Model B", OldStatus: "CRITICAL", NewStatus: "HEALTHY", OldPercentage: 10, NewPercentage: 100},
+	}
+	msg := NewMessageFormatter().FormatChanges(changes)
+
+	t.Run("STARTTLS With Auth", func(t *testing.T) {
+		srv := fakeserver.NewSMTP(fakeserver.SMTPOptions{Security: fakeserver.SMTPStartTLS, Username: "bot", Password: "pw"})
+		defer srv.Close()
+
+		en, err := NewEmailNotifier(EmailOptions{
+			Host:     srv.Host(),
+			Port:     srv.Port(),
+			Username: "bot",
+			Password: "pw",
+			From:     "ag-quota <quota@example.com>",
+			To:       []string{"ops@example.com", "Lead <lead@example.com>"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		en.rootCAs = srv.CertPool()
+
+		if err := en.Send(ctx, msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		messages := srv.Messages()
+		if len(messages) != 1 {
+			t.Fatalf("expected 1 message, got %d", len(messages))
+		}
+		got := messages[0]
+		if !got.TLS || got.Username != "bot" {
+			t.Errorf("expected an authenticated TLS session, got tls=%v user=%q", got.TLS, got.Username)
+		}
+		if got.From != "quota@example.com" || len(got.To) != 2 || got.To[1] != "lead@example.com" {
+			t.Errorf("unexpected envelope: from %s to %v", got.From, got.To)
+		}
+
+		subject, plain, html := parseEmail(t, got.Data)
+		if want := "[ag-quota] CRITICAL: " + msg.Title; subject != want {
+			t.Errorf("expected subject %q, got %q", want, subject)
+		}
+		for _, want := range []string{"👤 a@example.com", "  ❌ Empty", "    - Model <A> | 0% (↓ 30%) ⏳ 2h 1m", "👤 b@example.com", "Model B | 100% (↑ 90%)"} {
+			if !strings.Contains(plain, want) {
+				t.Errorf("plain text missing %q:\n%s", want, plain)
+			}
+		}
+		if strings.Contains(plain, "*") {
+			t.Errorf("plain text should not contain Markdown:\n%s", plain)
+		}
+		for _, want := range []string{"<h3 style=\"margin: 4px 0;\">👤 a@example.com</h3>", "<strong>❌ Empty</strong>", "<li>Model &lt;A&gt; | 0% (↓ 30%) ⏳ 2h 1m</li>", severityColor(SeverityCritical), severityColor(SeverityRecovery)} {
+			if !strings.Contains(html, want) {
+				t.Errorf("HTML missing %q:\n%s", want, html)
+			}
+		}
+	})
+
+	t.Run("Implicit TLS", func(t *testing.T) {
+		srv := fakeserver.NewSMTP(fakeserver.SMTPOptions{Security: fakeserver.SMTPTLS})
+		defer srv.Close()
+
+		en, err := NewEmailNotifier(EmailOptions{Host: srv.Host(), Port: srv.Port(), Security: EmailSecurityTLS, From: "quota@example.com", To: []string{"ops@example.com"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		en.rootCAs = srv.CertPool()
+
+		if err := en.Send(ctx, msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if messages := srv.Messages(); len(messages) != 1 || !messages[0].TLS {
+			t.Errorf("expected 1 message over TLS, got %+v", messages)
+		}
+	})
+
+	t.Run("Untrusted Certificate", func(t *testing.T) {
+		srv := fakeserver.NewSMTP(fakeserver.SMTPOptions{Security: fakeserver.SMTPStartTLS})
+		defer srv.Close()
+
+		en, err := NewEmailNotifier(EmailOptions{Host: srv.Host(), Port: srv.Port(), From: "quota@example.com", To: []string{"ops@example.com"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := en.Send(ctx, msg); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+			t.Errorf("expected STARTTLS error, got %v", err)
+		}
+		if len(srv.Messages()) != 0 {
+			t.Error("no message should be delivered")
+		}
+	})
+
+	t.Run("STARTTLS Not Offered", func(t *testing.T) {
+		srv := fakeserver.NewSMTP(fakeserver.SMTPOptions{})
+		defer srv.Close()
+
+		en, err := NewEmailNotifier(EmailOptions{Host: srv.Host(), Port: srv.Port(), From: "quota@example.com", To: []string{"ops@example.com"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := en.Send(ctx, msg); err == nil || !strings.Contains(err.Error(), "does not support STARTTLS") {
+			t.Errorf("expected STARTTLS error, got %v", err)
+		}
+	})
+
+	t.Run("Wrong Password", func(t *testing.T) {
+		srv := fakeserver.NewSMTP(fakeserver.SMTPOptions{Username: "bot", Password: "pw"})
+		defer srv.Close()
+
+		en, err := NewEmailNotifier(EmailOptions{Host: srv.Host(), Port: srv.Port(), Security: EmailSecurityNone, Username: "bot", Password: "wrong", From: "quota@example.com", To: []string{"ops@example.com"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := en.Send(ctx, msg); err == nil || !strings.Contains(err.Error(), "authentication failed") {
+			t.Errorf("expected authentication error, got %v", err)
+		}
+	})
+
+	t.Run("Not Configured", func(t *testing.T) {
+		en, err := NewEmailNotifier(EmailOptions{Host: "smtp.example.com"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if en.IsEnabled() {
+			t.Error("expected notifier without sender and recipients to be disabled")
+		}
+		if err := en.Send(ctx, msg); err == nil {
+			t.Error("expected error when not configured")
+		}
+	})
+}
+
+func TestNewEmailNotifier(t *testing.T) {
+	for name, opts := range map[string]EmailOptions{
+		"security":  {Security: "ssl"},
+		"sender":    {From: "not an address"},
+		"recipient": {To: []string{"ops@example.com", "@"}},
+		"subject":   {SubjectTemplate: "{{.Title"},
+	} {
+		if _, err := NewEmailNotifier(opts); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	for security, port := range map[string]int{"": 587, EmailSecurityStartTLS: 587, EmailSecurityTLS: 465, EmailSecurityNone: 25} {
+		en, err := NewEmailNotifier(EmailOptions{Security: security})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if en.port != port {
+			t.Errorf("%q: expected default port %d, got %d", security, port, en.port)
+		}
+	}
+}
+
+func TestEmailNotifier_BuildMessage(t *testing.T) {
+	en, err := NewEmailNotifier(EmailOptions{
+		Host:            "smtp.example.com",
+		From:            "quota@example.com",
+		To:              []string{"ops@example.com"},
+		SubjectTemplate: "{{.Severity}} on {{.Accounts}} accounts\n({{.Changes}} changes)",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changes := []StatusChange{
+		{Account: "a@example.com", DisplayName: "M1", OldStatus: "HEALTHY", NewStatus: "WARNING", OldPercentage: 80, NewPercentage: 40},
+		{Account: "a@example.com", DisplayName: "M2", OldStatus: "HEALTHY", NewStatus: "WARNING", OldPercentage: 80, NewPercentage: 30},
+		{Account: "b@example.com", DisplayName: "M1", OldStatus: "HEALTHY", NewStatus: "WARNING", OldPercentage: 80, NewPercentage: 40},
+	}
+	data, err := en.buildMessage(NewMessageFormatter().FormatChanges(changes), time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	subject, _, _ := parseEmail(t, string(data))
+	if subject != "warning on 2 accounts (3 changes)" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+
+	// Messages without changes fall back to the body
+	data, err = en.buildMessage(Message{Title: "Plain", Body: "just <text>"}, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, plain, html := parseEmail(t, string(data))
+	if !strings.Contains(plain, "just <text>") || !strings.Contains(html, "just &lt;text&gt;") {
+		t.Errorf("expected the body in both parts:\n%s\n%s", plain, html)
+	}
+
+	// Lines are short enough for any SMTP server
+	for _, line := range strings.Split(string(data), "\r\n") {
+		if len(line) > 998 {
+			t.Errorf("line longer than 998 characters: %d", len(line))
+		}
+	}
+}
@@ -8,13 +8,36 @@ import (
 )
 
 // MessageFormatter handles building notification messages
-type MessageFormatter struct{}
+type MessageFormatter struct {
+	// resetText formats when a model resets; nil shows the time remaining
+	resetText func(reset time.Time) string
+}
 
 // NewMessageFormatter creates a new message formatter
 func NewMessageFormatter() *MessageFormatter {
 	return &MessageFormatter{}
 }
 
+// AccountSection is the part of a notification about one account
+type AccountSection struct {
+	Account string
+	// Text lists the changed models under a header per status, in Markdown
+	Text string
+	// Severity is the highest severity among the account's changes
+	Severity Severity
+	// Statuses are the same changes as Text, for layouts other than Markdown
+	Statuses []StatusSection
+}
+
+// StatusSection lists the models of an account that changed to one status
+type StatusSection struct {
+	Status string
+	// Label names the status with its emoji, e.g. "⚠️ Warning"
+	Label string
+	// Lines describe one model each, e.g. "Model Name | 40% (↓ 50%)"
+	Lines []string
+}
+
 // FormatChanges aggregates multiple status changes into a single notification message grouped by Account.
 func (f *MessageFormatter) FormatChanges(changes []StatusChange) Message {
 	if len(changes) == 0 {
@@ -23,22 +46,36 @@ func (f *MessageFormatter) FormatChanges(changes []StatusChange) Message {
 
 	// Determine overall severity
 	maxSeverity := SeverityInfo
-	isInitial := false
 	for _, c := range changes {
 		severity := f.getSeverity(c.NewStatus)
 		if severity > maxSeverity {
 			maxSeverity = severity
 		}
-		if c.OldStatus == "INITIAL" {
-			isInitial = true
-		}
 	}
 
 	title := "🔄 Status Update"
-	if isInitial {
+	if isInitialBatch(changes) {
 		title = "📊 Quota Summary"
 	}
 
+	var parts []string
+	for _, section := range f.FormatAccounts(changes) {
+		parts = append(parts, fmt.Sprintf("👤 *%s*\n%s", section.Account, section.Text))
+	}
+
+	return Message{
+		Title:    title,
+		Body:     strings.Join(parts, "\n\n"),
+		Severity: maxSeverity,
+		Changes:  changes,
+	}
+}
+
+// FormatAccounts groups changes by account and status the way FormatChanges lays
+// out its body, for notifiers that show each account separately
+func (f *MessageFormatter) FormatAccounts(changes []StatusChange) []AccountSection {
+	isInitial := isInitialBatch(changes)
+
 	// Group by Account -> Status
 	var accounts []string
 	accountGroups := make(map[string]map[string][]StatusChange)
@@ -55,14 +92,12 @@ func (f *MessageFormatter) FormatChanges(changes []StatusChange) Message {
 		accountGroups[acc][c.NewStatus] = append(accountGroups[acc][c.NewStatus], c)
 	}
 
-	var sb strings.Builder
 	statusOrder := []string{"HEALTHY", "WARNING", "CRITICAL", "EMPTY"}
 
-	for i, acc := range accounts {
-		if i > 0 {
-			sb.WriteString("\n")
-		}
-		sb.WriteString(fmt.Sprintf("👤 *%s*\n", acc))
+	var sections []AccountSection
+	for _, acc := range accounts {
+		var sb strings.Builder
+		section := AccountSection{Account: acc, Severity: SeverityInfo}
 
 		group := accountGroups[acc]
 		for _, status := range statusOrder {
@@ -70,8 +105,12 @@ func (f *MessageFormatter) FormatChanges(changes []StatusChange) Message {
 			if len(items) == 0 {
 				continue
 			}
+			if severity := f.getSeverity(status); severity > section.Severity {
+				section.Severity = severity
+			}
 
 			sb.WriteString(fmt.Sprintf("  %s\n", f.getStatusHeader(status)))
+			statusSection := StatusSection{Status: status, Label: strings.ReplaceAll(f.getStatusHeader(status), "*", "")}
 
 			// Sort models alphabetically A-Z
 			sort.Slice(items, func(i, j int) bool {
@@ -79,37 +118,58 @@ func (f *MessageFormatter) FormatChanges(changes []StatusChange) Message {
 			})
 
 			for _, c := range items {
-				// Base line: - Model Name | X%
-				line := fmt.Sprintf("    - %s | %d%%", c.DisplayName, c.NewPercentage)
-
-				// Delta logic for updates: (↓ 70%)
-				if !isInitial && c.OldStatus != "UNKNOWN" && c.OldStatus != "INITIAL" {
-					delta := c.NewPercentage - c.OldPercentage
-					if delta < 0 {
-						line += fmt.Sprintf(" (↓ %d%%)", -delta)
-					} else if delta > 0 {
-						line += fmt.Sprintf(" (↑ %d%%)", delta)
-					}
-				}
-
-				// Reset time for Critical/Empty: ⏳ 2h 30m
-				if (status == "EMPTY" || status == "CRITICAL") && !c.ResetTime.IsZero() {
-					remaining := time.Until(c.ResetTime)
-					if remaining > 0 {
-						line += fmt.Sprintf(" ⏳ %s", FormatTimeRemaining(remaining))
-					}
-				}
-
-				sb.WriteString(line + "\n")
+				line := f.formatChangeLine(c, isInitial)
+				sb.WriteString("    - " + line + "\n")
+				statusSection.Lines = append(statusSection.Lines, line)
 			}
+			section.Statuses = append(section.Statuses, statusSection)
 		}
+
+		section.Text = strings.TrimRight(sb.String(), "\n")
+		sections = append(sections, section)
 	}
+	return sections
+}
 
-	return Message{
-		Title:    title,
-		Body:     strings.TrimSpace(sb.String()),
-		Severity: maxSeverity,
+// formatChangeLine formats one model as "Model Name | X%" with its delta and reset time
+func (f *MessageFormatter) formatChangeLine(c StatusChange, isInitial bool) string {
+	// Base line: Model Name | X%
+	line := fmt.Sprintf("%s | %d%%", c.DisplayName, c.NewPercentage)
+
+	// Delta logic for updates: (↓ 70%)
+	if !isInitial && c.OldStatus != "UNKNOWN" && c.OldStatus != "INITIAL" {
+		delta := c.NewPercentage - c.OldPercentage
+		if delta < 0 {
+			line += fmt.Sprintf(" (↓ %d%%)", -delta)
+		} else if delta > 0 {
+			line += fmt.Sprintf(" (↑ %d%%)", delta)
+		}
+	}
+
+	// Reset time for Critical/Empty: ⏳ 2h 30m
+	if (c.NewStatus == "EMPTY" || c.NewStatus == "CRITICAL") && !c.ResetTime.IsZero() {
+		remaining := time.Until(c.ResetTime)
+		if remaining > 0 {
+			if f.resetText != nil {
+				line += fmt.Sprintf(" ⏳ %s", f.resetText(c.ResetTime))
+			} else {
+				line += fmt.Sprintf(" ⏳ %s", FormatTimeRemaining(remaining))
+			}
+		}
+	}
+
+	return line
+}
+
+// isInitialBatch reports whether the changes include a first fetch, which is
+// shown as a summary without deltas
+func isInitialBatch(changes []StatusChange) bool {
+	for _, c := range changes {
+		if c.OldStatus == "INITIAL" {
+			return true
+		}
 	}
+	return false
 }
 
 func (f *MessageFormatter) getSeverity(status string) Severity {
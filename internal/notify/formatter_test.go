@@ -173,4 +173,30 @@ func TestMessageFormatter(t *testing.T) {
 			t.Errorf("models not sorted alphabetically: A-Model(%d), M-Model(%d), Z-Model(%d)", aIdx, mIdx, zIdx)
 		}
 	})
+	t.Run("Account Sections", func(t *testing.T) {
+		changes := []StatusChange{
+			{Account: "acc1@gmail.com", DisplayName: "Model A", OldStatus: "HEALTHY", NewStatus: "WARNING", OldPercentage: 90, NewPercentage: 40},
+			{Account: "acc2@gmail.com", DisplayName: "Model B", OldStatus: "WARNING", NewStatus: "HEALTHY", OldPercentage: 30, NewPercentage: 100},
+			{Account: "acc1@gmail.com", DisplayName: "Model C", OldStatus: "WARNING", NewStatus: "EMPTY", OldPercentage: 20, NewPercentage: 0},
+		}
+
+		sections := formatter.FormatAccounts(changes)
+		if len(sections) != 2 || sections[0].Account != "acc1@gmail.com" || sections[1].Account != "acc2@gmail.com" {
+			t.Fatalf("expected sections for acc1 then acc2, got %+v", sections)
+		}
+		if sections[0].Severity != SeverityCritical || sections[1].Severity != SeverityRecovery {
+			t.Errorf("unexpected severities: %v, %v", sections[0].Severity, sections[1].Severity)
+		}
+
+		// The message body is the sections under an account header each
+		msg := formatter.FormatChanges(changes)
+		for _, section := range sections {
+			if !strings.Contains(msg.Body, "👤 *"+section.Account+"*\n"+section.Text) {
+				t.Errorf("body does not contain the section of %s:\n%s", section.Account, msg.Body)
+			}
+		}
+		if len(msg.Changes) != len(changes) {
+			t.Errorf("expected the message to carry %d changes, got %d", len(changes), len(msg.Changes))
+		}
+	})
 }
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GotifyNotifier implements the Notifier interface for a Gotify server
+type GotifyNotifier struct {
+	serverURL string
+	token     string
+	client    *http.Client
+}
+
+// NewGotifyNotifier creates a notifier posting to a Gotify server with an application token
+func NewGotifyNotifier(serverURL, token string) *GotifyNotifier {
+	return &GotifyNotifier{
+		serverURL: strings.TrimSuffix(serverURL, "/"),
+		token:     token,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (g *GotifyNotifier) Name() string {
+	return "gotify"
+}
+
+func (g *GotifyNotifier) IsEnabled() bool {
+	return g.serverURL != "" && g.token != ""
+}
+
+// gotifyPayload is the body of a create-message request
+type gotifyPayload struct {
+	Title    string         `json:"title"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras,omitempty"`
+}
+
+// GotifyResponse represents an error response from Gotify
+type GotifyResponse struct {
+	Error            string `json:"error"`
+	ErrorCode        int    `json:"errorCode"`
+	ErrorDescription string `json:"errorDescription"`
+}
+
+// Send posts the message to the server
+func (g *GotifyNotifier) Send(ctx context.Context, msg Message) error {
+	if !g.IsEnabled() {
+		return fmt.Errorf("gotify notifier not configured")
+	}
+
+	body, err := json.Marshal(gotifyPayload{
+		Title:    fmt.Sprintf("%s %s", severityEmoji(msg.Severity), msg.Title),
+		Message:  msg.Body,
+		Priority: gotifyPriority(msg.Severity),
+		Extras: map[string]any{
+			"client::display": map[string]string{"contentType": "text/markdown"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", g.serverURL+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.token)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var gotifyResp GotifyResponse
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&gotifyResp); err == nil && gotifyResp.ErrorDescription != "" {
+			return fmt.Errorf("gotify API error (%d): %s", resp.StatusCode, gotifyResp.ErrorDescription)
+		}
+		return fmt.Errorf("gotify API error: status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// gotifyPriority maps a severity to a Gotify priority; 8 and above pop up on Android
+func gotifyPriority(severity Severity) int {
+	switch severity {
+	case SeverityCritical:
+		return 8
+	case SeverityWarning:
+		return 6
+	case SeverityRecovery:
+		return 4
+	default:
+		return 2
+	}
+}
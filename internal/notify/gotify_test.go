@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGotifyNotifier_Send(t *testing.T) {
+	ctx := context.Background()
+	msg := Message{Title: "Test Title", Body: "Test *Body*", Severity: SeverityWarning}
+
+	t.Run("Success", func(t *testing.T) {
+		var payload gotifyPayload
+		var path, key string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, key = r.URL.Path, r.Header.Get("X-Gotify-Key")
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Errorf("invalid payload: %v", err)
+			}
+			_, _ = w.Write([]byte(`{"id": 1}`))
+		}))
+		defer server.Close()
+
+		gn := NewGotifyNotifier(server.URL+"/gotify/", "app-token")
+		if err := gn.Send(ctx, msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if path != "/gotify/message" || key != "app-token" {
+			t.Errorf("unexpected request: path %s, key %q", path, key)
+		}
+		if payload.Title != "⚠️ Test Title" || payload.Message != msg.Body || payload.Priority != 6 {
+			t.Errorf("unexpected payload: %+v", payload)
+		}
+		display, _ := payload.Extras["client::display"].(map[string]any)
+		if display["contentType"] != "text/markdown" {
+			t.Errorf("expected Markdown display, got %v", payload.Extras)
+		}
+	})
+
+	t.Run("API Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "Unauthorized", "errorCode": 401, "errorDescription": "you need to provide a valid access token"}`))
+		}))
+		defer server.Close()
+
+		err := NewGotifyNotifier(server.URL, "bad").Send(ctx, msg)
+		if err == nil || !strings.Contains(err.Error(), "valid access token") {
+			t.Errorf("expected unauthorized error, got %v", err)
+		}
+	})
+
+	t.Run("Not Configured", func(t *testing.T) {
+		gn := NewGotifyNotifier("https://gotify.example.com", "")
+		if gn.IsEnabled() {
+			t.Error("expected notifier without token to be disabled")
+		}
+		if err := gn.Send(ctx, msg); err == nil {
+			t.Error("expected error when not configured")
+		}
+	})
+}
+
+func TestGotifyPriority(t *testing.T) {
+	for severity, want := range map[Severity]int{SeverityInfo: 2, SeverityRecovery: 4, SeverityWarning: 6, SeverityCritical: 8} {
+		if got := gotifyPriority(severity); got != want {
+			t.Errorf("%v: expected priority %d, got %d", severity, want, got)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package notify
 
 import (
 	"context"
+	"sort"
 	"sync"
 )
 
@@ -15,11 +16,65 @@ const (
 	SeverityCritical
 )
 
+// String returns the lowercase name of the severity, e.g. "warning"
+func (s Severity) String() string {
+	switch s {
+	case SeverityRecovery:
+		return "recovery"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "info"
+	}
+}
+
+// severityColor returns the hex color that marks a severity, e.g. in Slack attachments
+func severityColor(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return "#E01E5A"
+	case SeverityWarning:
+		return "#ECB22E"
+	case SeverityRecovery:
+		return "#2EB67D"
+	default:
+		return "#36C5F0"
+	}
+}
+
+// severityEmoji returns the emoji that prefixes titles of the severity
+func severityEmoji(severity Severity) string {
+	switch severity {
+	case SeverityWarning:
+		return "⚠️"
+	case SeverityCritical:
+		return "🚨"
+	case SeverityRecovery:
+		return "✅"
+	default:
+		return "ℹ️"
+	}
+}
+
+// truncate shortens text to at most limit characters, marking the cut with an ellipsis
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
 // Message represents a notification message
 type Message struct {
 	Title    string
 	Body     string
 	Severity Severity
+	// Changes are the status changes the message was built from, for notifiers
+	// that render their own layout
+	Changes []StatusChange
 }
 
 // Notifier is the interface that all notification channels must implement
@@ -76,7 +131,7 @@ func (r *Registry) NotifyAll(ctx context.Context, msg Message) []error {
 	return errs
 }
 
-// List returns names of all registered notifiers
+// List returns names of all registered notifiers, sorted
 func (r *Registry) List() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -85,5 +140,6 @@ func (r *Registry) List() []string {
 	for name := range r.notifiers {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
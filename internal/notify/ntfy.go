@@ -0,0 +1,179 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// statusTags are the ntfy tags shown as emojis for each status, matching the status headers
+var statusTags = []struct {
+	status string
+	tag    string
+}{
+	{"HEALTHY", "white_check_mark"},
+	{"WARNING", "warning"},
+	{"CRITICAL", "no_entry"},
+	{"EMPTY", "x"},
+}
+
+// NtfyNotifier implements the Notifier interface for ntfy (ntfy.sh or self-hosted)
+type NtfyNotifier struct {
+	topicURL string
+	token    string
+	client   *http.Client
+}
+
+// NewNtfyNotifier creates a notifier publishing to a topic URL such as
+// https://ntfy.sh/my-topic. The token is an access token for protected topics.
+func NewNtfyNotifier(topicURL, token string) *NtfyNotifier {
+	return &NtfyNotifier{
+		topicURL: topicURL,
+		token:    token,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (n *NtfyNotifier) Name() string {
+	return "ntfy"
+}
+
+func (n *NtfyNotifier) IsEnabled() bool {
+	return n.topicURL != ""
+}
+
+// ntfyPayload is the body of a JSON publish request
+type ntfyPayload struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title,omitempty"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags,omitempty"`
+	Markdown bool     `json:"markdown"`
+}
+
+// NtfyResponse represents an error response from ntfy
+type NtfyResponse struct {
+	Code  int    `json:"code"`
+	Error string `json:"error"`
+}
+
+// Send publishes the message to the topic
+func (n *NtfyNotifier) Send(ctx context.Context, msg Message) error {
+	if !n.IsEnabled() {
+		return fmt.Errorf("ntfy notifier not configured")
+	}
+
+	// JSON messages are published to the server root, which keeps the
+	// title and emojis out of HTTP headers
+	serverURL, topic, err := SplitNtfyTopicURL(n.topicURL)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(ntfyPayload{
+		Topic:    topic,
+		Title:    msg.Title,
+		Message:  msg.Body,
+		Priority: ntfyPriority(msg.Severity),
+		Tags:     ntfyTags(msg),
+		Markdown: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", serverURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var ntfyResp NtfyResponse
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&ntfyResp); err == nil && ntfyResp.Error != "" {
+			return fmt.Errorf("ntfy API error (%d): %s", resp.StatusCode, ntfyResp.Error)
+		}
+		return fmt.Errorf("ntfy API error: status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// SplitNtfyTopicURL splits a topic URL into the server URL and the topic name
+func SplitNtfyTopicURL(topicURL string) (string, string, error) {
+	u, err := url.Parse(topicURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid ntfy topic URL: %w", err)
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || i < 0 || path[i+1:] == "" {
+		return "", "", fmt.Errorf("invalid ntfy topic URL %q: expected e.g. https://ntfy.sh/my-topic", topicURL)
+	}
+
+	topic := path[i+1:]
+	u.Path = path[:i+1]
+	u.RawPath, u.RawQuery, u.Fragment = "", "", ""
+	return u.String(), topic, nil
+}
+
+// ntfyPriority maps a severity to an ntfy priority from 1 (min) to 5 (urgent)
+func ntfyPriority(severity Severity) int {
+	switch severity {
+	case SeverityCritical:
+		return 5
+	case SeverityWarning:
+		return 4
+	case SeverityRecovery:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// ntfyTags returns the emoji tags of the statuses in the message, or of its
+// severity when it carries no changes
+func ntfyTags(msg Message) []string {
+	var tags []string
+	for _, st := range statusTags {
+		for _, c := range msg.Changes {
+			if c.NewStatus == st.status {
+				tags = append(tags, st.tag)
+				break
+			}
+		}
+	}
+	if len(tags) > 0 {
+		return tags
+	}
+
+	switch msg.Severity {
+	case SeverityCritical:
+		return []string{"rotating_light"}
+	case SeverityWarning:
+		return []string{"warning"}
+	case SeverityRecovery:
+		return []string{"white_check_mark"}
+	default:
+		return []string{"information_source"}
+	}
+}
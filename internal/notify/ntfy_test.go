@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNtfyNotifier_Send(t *testing.T) {
+	ctx := context.Background()
+	changes := []StatusChange{
+		{Account: "a@example.com", DisplayName: "Model A", OldStatus: "WARNING", NewStatus: "EMPTY", OldPercentage: 30, NewPercentage: 0},
+		{Account: "a@example.com", DisplayName: "Model B", OldStatus: "HEALTHY", NewStatus: "WARNING", OldPercentage: 90, NewPercentage: 45},
+	}
+	msg := NewMessageFormatter().FormatChanges(changes)
+
+	t.Run("Success", func(t *testing.T) {
+		var payload ntfyPayload
+		var path, auth string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, auth = r.URL.Path, r.Header.Get("Authorization")
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Errorf("invalid payload: %v", err)
+			}
+			_, _ = w.Write([]byte(`{"id": "abc"}`))
+		}))
+		defer server.Close()
+
+		nn := NewNtfyNotifier(server.URL+"/ntfy/on-call", "tk_secret")
+		if err := nn.Send(ctx, msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if path != "/ntfy/" || auth != "Bearer tk_secret" {
+			t.Errorf("unexpected request: path %s, auth %q", path, auth)
+		}
+		if payload.Topic != "on-call" || payload.Title != msg.Title || payload.Message != msg.Body || !payload.Markdown {
+			t.Errorf("unexpected payload: %+v", payload)
+		}
+		if payload.Priority != 5 {
+			t.Errorf("expected urgent priority for critical, got %d", payload.Priority)
+		}
+		if !slices.Equal(payload.Tags, []string{"warning", "x"}) {
+			t.Errorf("expected status tags in status order, got %v", payload.Tags)
+		}
+	})
+
+	t.Run("API Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"code": 40301, "http": 403, "error": "forbidden"}`))
+		}))
+		defer server.Close()
+
+		err := NewNtfyNotifier(server.URL+"/on-call", "").Send(ctx, msg)
+		if err == nil || !strings.Contains(err.Error(), "forbidden") {
+			t.Errorf("expected forbidden error, got %v", err)
+		}
+	})
+
+	t.Run("Not Configured", func(t *testing.T) {
+		nn := NewNtfyNotifier("", "")
+		if nn.IsEnabled() {
+			t.Error("expected notifier without topic URL to be disabled")
+		}
+		if err := nn.Send(ctx, msg); err == nil {
+			t.Error("expected error when not configured")
+		}
+	})
+}
+
+func TestSplitNtfyTopicURL(t *testing.T) {
+	for topicURL, want := range map[string][2]string{
+		"https://ntfy.sh/my-topic":              {"https://ntfy.sh/", "my-topic"},
+		"https://push.example.com/ntfy/alerts/": {"https://push.example.com/ntfy/", "alerts"},
+		"http://127.0.0.1:8080/quota?x=1":       {"http://127.0.0.1:8080/", "quota"},
+	} {
+		server, topic, err := SplitNtfyTopicURL(topicURL)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", topicURL, err)
+			continue
+		}
+		if server != want[0] || topic != want[1] {
+			t.Errorf("%s: expected %v, got %s %s", topicURL, want, server, topic)
+		}
+	}
+
+	for _, bad := range []string{"https://ntfy.sh", "https://ntfy.sh/", "ntfy.sh/topic", "ftp://ntfy.sh/topic"} {
+		if _, _, err := SplitNtfyTopicURL(bad); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestNtfyPriorityAndTags(t *testing.T) {
+	for severity, want := range map[Severity]int{SeverityInfo: 2, SeverityRecovery: 3, SeverityWarning: 4, SeverityCritical: 5} {
+		if got := ntfyPriority(severity); got != want {
+			t.Errorf("%v: expected priority %d, got %d", severity, want, got)
+		}
+	}
+
+	if tags := ntfyTags(Message{Severity: SeverityCritical}); !slices.Equal(tags, []string{"rotating_light"}) {
+		t.Errorf("expected severity tag without changes, got %v", tags)
+	}
+}
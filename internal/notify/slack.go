@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Slack Block Kit limits
+const (
+	slackMaxHeaderLength  = 150
+	slackMaxSectionLength = 3000
+	// slackMaxAttachments keeps large batches readable; the rest are summarized
+	slackMaxAttachments = 20
+)
+
+// SlackNotifier implements the Notifier interface for Slack incoming webhooks
+type SlackNotifier struct {
+	webhookURL string
+	formatter  *MessageFormatter
+	client     *http.Client
+}
+
+// NewSlackNotifier creates a new Slack notifier posting to an incoming webhook URL
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		formatter:  NewMessageFormatter(),
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (s *SlackNotifier) Name() string {
+	return "slack"
+}
+
+func (s *SlackNotifier) IsEnabled() bool {
+	return s.webhookURL != ""
+}
+
+// slackPayload is the body of an incoming webhook request
+type slackPayload struct {
+	// Text is shown in push notifications and clients without Block Kit
+	Text        string            `json:"text"`
+	Blocks      []slackBlock      `json:"blocks,omitempty"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	Elements []*slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+// Send posts the message to the webhook, with one attachment per account
+// colored by the account's highest severity
+func (s *SlackNotifier) Send(ctx context.Context, msg Message) error {
+	if !s.IsEnabled() {
+		return fmt.Errorf("slack notifier not configured")
+	}
+
+	body, err := json.Marshal(s.buildPayload(msg))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Slack answers with a short error code such as "invalid_payload"
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if text := strings.TrimSpace(string(detail)); text != "" {
+			return fmt.Errorf("slack API error (%d): %s", resp.StatusCode, text)
+		}
+		return fmt.Errorf("slack API error: status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// buildPayload lays out the message as Block Kit: the title as a header, then
+// an attachment per account, or the plain body for messages without changes
+func (s *SlackNotifier) buildPayload(msg Message) slackPayload {
+	payload := slackPayload{
+		Text: fmt.Sprintf("%s %s", severityEmoji(msg.Severity), msg.Title),
+		Blocks: []slackBlock{{
+			Type: "header",
+			Text: &slackText{Type: "plain_text", Text: truncate(msg.Title, slackMaxHeaderLength), Emoji: true},
+		}},
+	}
+
+	if len(msg.Changes) == 0 {
+		payload.Attachments = append(payload.Attachments, slackAttachment{
+			Color:  severityColor(msg.Severity),
+			Blocks: []slackBlock{slackSection(msg.Body)},
+		})
+		return payload
+	}
+
+	sections := s.formatter.FormatAccounts(msg.Changes)
+	for i, section := range sections {
+		if i == slackMaxAttachments {
+			payload.Attachments = append(payload.Attachments, slackAttachment{
+				Color: severityColor(SeverityInfo),
+				Blocks: []slackBlock{{
+					Type:     "context",
+					Elements: []*slackText{{Type: "mrkdwn", Text: fmt.Sprintf("…and %d more accounts", len(sections)-i)}},
+				}},
+			})
+			break
+		}
+		payload.Attachments = append(payload.Attachments, slackAttachment{
+			Color:  severityColor(section.Severity),
+			Blocks: []slackBlock{slackSection(fmt.Sprintf("👤 *%s*\n%s", section.Account, section.Text))},
+		})
+	}
+	return payload
+}
+
+func slackSection(text string) slackBlock {
+	return slackBlock{
+		Type: "section",
+		Text: &slackText{Type: "mrkdwn", Text: truncate(text, slackMaxSectionLength)},
+	}
+}
@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlackNotifier_Send(t *testing.T) {
+	ctx := context.Background()
+	changes := []StatusChange{
+		{Account: "a@example.com", DisplayName: "Model B", OldStatus: "HEALTHY", NewStatus: "WARNING", OldPercentage: 90, NewPercentage: 40},
+		{Account: "a@example.com", DisplayName: "Model A", OldStatus: "WARNING", NewStatus: "EMPTY", OldPercentage: 30, NewPercentage: 0, ResetTime: time.Now().Add(90 * time.Minute)},
+		{Account: "b@example.com", DisplayName: "Model C", OldStatus: "CRITICAL", NewStatus: "HEALTHY", OldPercentage: 10, NewPercentage: 100},
+	}
+	msg := NewMessageFormatter().FormatChanges(changes)
+
+	t.Run("Success", func(t *testing.T) {
+		var payload slackPayload
+		client := &http.Client{
+			Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+				if req.URL.String() != "https://hooks.slack.com/services/T/B/X" {
+					t.Errorf("unexpected URL: %s", req.URL)
+				}
+				if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+					t.Errorf("invalid payload: %v", err)
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewBufferString("ok")),
+					Header:     make(http.Header),
+				}
+			}),
+		}
+
+		sn := NewSlackNotifier("https://hooks.slack.com/services/T/B/X")
+		sn.client = client
+		if err := sn.Send(ctx, msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if payload.Text != "🚨 "+msg.Title {
+			t.Errorf("unexpected fallback text: %s", payload.Text)
+		}
+		if len(payload.Blocks) != 1 || payload.Blocks[0].Type != "header" || payload.Blocks[0].Text.Text != msg.Title {
+			t.Errorf("expected a header block with the title, got %+v", payload.Blocks)
+		}
+		if len(payload.Attachments) != 2 {
+			t.Fatalf("expected one attachment per account, got %d", len(payload.Attachments))
+		}
+
+		first := payload.Attachments[0]
+		if first.Color != severityColor(SeverityCritical) {
+			t.Errorf("expected critical color for a@example.com, got %s", first.Color)
+		}
+		text := first.Blocks[0].Text.Text
+		for _, want := range []string{"👤 *a@example.com*", "⚠️ *Warning*", "Model B | 40% (↓ 50%)", "❌ *Empty*", "Model A | 0% (↓ 30%) ⏳ 1h 30m"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("section missing %q:\n%s", want, text)
+			}
+		}
+		if strings.Index(text, "*Warning*") > strings.Index(text, "*Empty*") {
+			t.Errorf("statuses out of order:\n%s", text)
+		}
+
+		if second := payload.Attachments[1]; second.Color != severityColor(SeverityRecovery) || !strings.Contains(second.Blocks[0].Text.Text, "Model C | 100% (↑ 90%)") {
+			t.Errorf("unexpected attachment for b@example.com: %+v", second)
+		}
+	})
+
+	t.Run("API Error", func(t *testing.T) {
+		client := &http.Client{
+			Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+				return &http.Response{
+					StatusCode: http.StatusBadRequest,
+					Body:       io.NopCloser(bytes.NewBufferString("invalid_payload")),
+					Header:     make(http.Header),
+				}
+			}),
+		}
+
+		sn := NewSlackNotifier("https://hooks.slack.com/services/T/B/X")
+		sn.client = client
+		err := sn.Send(ctx, msg)
+		if err == nil || !strings.Contains(err.Error(), "invalid_payload") {
+			t.Errorf("expected invalid_payload error, got %v", err)
+		}
+	})
+
+	t.Run("Not Configured", func(t *testing.T) {
+		sn := NewSlackNotifier("")
+		if sn.IsEnabled() {
+			t.Error("expected notifier without webhook URL to be disabled")
+		}
+		if err := sn.Send(ctx, msg); err == nil {
+			t.Error("expected error when not configured")
+		}
+	})
+}
+
+func TestSlackNotifier_BuildPayload(t *testing.T) {
+	sn := NewSlackNotifier("https://hooks.slack.com/services/T/B/X")
+
+	t.Run("Without Changes", func(t *testing.T) {
+		payload := sn.buildPayload(Message{Title: "Hello", Body: "plain body", Severity: SeverityWarning})
+		if len(payload.Attachments) != 1 || payload.Attachments[0].Color != severityColor(SeverityWarning) {
+			t.Fatalf("expected one warning attachment, got %+v", payload.Attachments)
+		}
+		if payload.Attachments[0].Blocks[0].Text.Text != "plain body" {
+			t.Errorf("expected the body as section text, got %+v", payload.Attachments[0].Blocks[0])
+		}
+	})
+
+	t.Run("Many Accounts", func(t *testing.T) {
+		var changes []StatusChange
+		for i := 0; i < slackMaxAttachments+5; i++ {
+			changes = append(changes, StatusChange{Account: strings.Repeat("x", i+1) + "@example.com", DisplayName: "M", OldStatus: "INITIAL", NewStatus: "HEALTHY", NewPercentage: 100})
+		}
+		payload := sn.buildPayload(NewMessageFormatter().FormatChanges(changes))
+		if len(payload.Attachments) != slackMaxAttachments+1 {
+			t.Fatalf("expected %d attachments, got %d", slackMaxAttachments+1, len(payload.Attachments))
+		}
+		last := payload.Attachments[slackMaxAttachments].Blocks[0]
+		if last.Type != "context" || last.Elements[0].Text != "…and 5 more accounts" {
+			t.Errorf("expected a summary of the remaining accounts, got %+v", last)
+		}
+	})
+
+	t.Run("Long Title", func(t *testing.T) {
+		payload := sn.buildPayload(Message{Title: strings.Repeat("t", 200)})
+		if got := []rune(payload.Blocks[0].Text.Text); len(got) != slackMaxHeaderLength {
+			t.Errorf("expected header truncated to %d, got %d", slackMaxHeaderLength, len(got))
+		}
+	})
+}
@@ -9,13 +9,13 @@ import (
 
 // StatusChange represents a change in quota status for a model
 type StatusChange struct {
-	Account       string
-	DisplayName   string
-	OldStatus     string
-	NewStatus     string
-	OldPercentage int
-	NewPercentage int
-	ResetTime     time.Time
+	Account       string    `json:"account"`
+	DisplayName   string    `json:"display_name"`
+	OldStatus     string    `json:"old_status"`
+	NewStatus     string    `json:"new_status"`
+	OldPercentage int       `json:"old_percentage"`
+	NewPercentage int       `json:"new_percentage"`
+	ResetTime     time.Time `json:"reset_time,omitzero"`
 }
 
 // StateTracker monitors status changes between fetches
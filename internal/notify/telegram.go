@@ -74,18 +74,7 @@ func (t *TelegramNotifier) Send(ctx context.Context, msg Message) error {
 	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.token)
 
 	// Format message with MarkdownV2-friendly style
-	// severity emoji
-	emoji := "ℹ️"
-	switch msg.Severity {
-	case SeverityWarning:
-		emoji = "⚠️"
-	case SeverityCritical:
-		emoji = "🚨"
-	case SeverityRecovery:
-		emoji = "✅"
-	}
-
-	text := fmt.Sprintf("%s *%s*", emoji, msg.Title)
+	text := fmt.Sprintf("%s *%s*", severityEmoji(msg.Severity), msg.Title)
 	text += "\n\n" + msg.Body
 
 	body, err := json.Marshal(map[string]string{
@@ -0,0 +1,187 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// DefaultWebhookMethod is used when no method is configured
+	DefaultWebhookMethod = http.MethodPost
+	// DefaultWebhookSignatureHeader carries the HMAC signature of the body
+	DefaultWebhookSignatureHeader = "X-AG-Quota-Signature"
+)
+
+// DefaultWebhookTemplate renders the message and its changes as JSON
+const DefaultWebhookTemplate = `{"title":{{json .Title}},"body":{{json .Body}},"severity":{{json .Severity}},"sent_at":{{json .SentAt}},"changes":{{json .Changes}}}`
+
+// maxWebhookErrorBody bounds how much of an error response is reported
+const maxWebhookErrorBody = 512
+
+// WebhookOptions configures a WebhookNotifier. Zero values use the defaults.
+type WebhookOptions struct {
+	Method  string
+	Headers map[string]string
+	// Secret signs the body with HMAC-SHA256 in the signature header; no
+	// signature is sent without a secret
+	Secret          string
+	SignatureHeader string
+	// BodyTemplate is a text/template executed with a WebhookPayload
+	BodyTemplate string
+}
+
+// WebhookPayload is the data the body template is executed with
+type WebhookPayload struct {
+	Title    string
+	Body     string
+	Severity string
+	SentAt   time.Time
+	Message  Message
+	Changes  []StatusChange
+}
+
+// WebhookNotifier implements the Notifier interface for arbitrary HTTP endpoints
+type WebhookNotifier struct {
+	url             string
+	method          string
+	headers         map[string]string
+	secret          string
+	signatureHeader string
+	tmpl            *template.Template
+	client          *http.Client
+}
+
+// NewWebhookNotifier creates a webhook notifier. It fails if the body template does not parse.
+func NewWebhookNotifier(url string, opts WebhookOptions) (*WebhookNotifier, error) {
+	tmpl, err := ParseWebhookTemplate(opts.BodyTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	method := strings.ToUpper(opts.Method)
+	if method == "" {
+		method = DefaultWebhookMethod
+	}
+	signatureHeader := opts.SignatureHeader
+	if signatureHeader == "" {
+		signatureHeader = DefaultWebhookSignatureHeader
+	}
+
+	return &WebhookNotifier{
+		url:             url,
+		method:          method,
+		headers:         opts.Headers,
+		secret:          opts.Secret,
+		signatureHeader: signatureHeader,
+		tmpl:            tmpl,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}, nil
+}
+
+// ParseWebhookTemplate parses a body template, or the default one if text is empty.
+// Templates can use the json function to encode any value, e.g. {{json .Title}}.
+func ParseWebhookTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultWebhookTemplate
+	}
+
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func (w *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (w *WebhookNotifier) IsEnabled() bool {
+	return w.url != ""
+}
+
+// Send renders the body template and sends it to the configured URL
+func (w *WebhookNotifier) Send(ctx context.Context, msg Message) error {
+	if !w.IsEnabled() {
+		return fmt.Errorf("webhook notifier not configured")
+	}
+
+	body, err := w.render(msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, w.method, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range w.headers {
+		req.Header.Set(name, value)
+	}
+	if w.secret != "" {
+		req.Header.Set(w.signatureHeader, "sha256="+SignWebhookBody(w.secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorBody))
+		if text := strings.TrimSpace(string(detail)); text != "" {
+			return fmt.Errorf("webhook error (%d): %s", resp.StatusCode, text)
+		}
+		return fmt.Errorf("webhook error: status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// render executes the body template for a message
+func (w *WebhookNotifier) render(msg Message, now time.Time) ([]byte, error) {
+	payload := WebhookPayload{
+		Title:    msg.Title,
+		Body:     msg.Body,
+		Severity: msg.Severity.String(),
+		SentAt:   now.UTC(),
+		Message:  msg,
+		Changes:  msg.Changes,
+	}
+	if payload.Changes == nil {
+		payload.Changes = []StatusChange{}
+	}
+
+	var buf bytes.Buffer
+	if err := w.tmpl.Execute(&buf, payload); err != nil {
+		return nil, fmt.Errorf("failed to render webhook body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SignWebhookBody returns the hex HMAC-SHA256 of body keyed with secret, as
+// sent in the signature header after "sha256="
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier_Send(t *testing.T) {
+	ctx := context.Background()
+	msg := Message{
+		Title:    "Test \"Title\"",
+		Body:     "Test Body",
+		Severity: SeverityCritical,
+		Changes: []StatusChange{
+			{Account: "user@example.com", DisplayName: "Gemini 3 Pro", OldStatus: "WARNING", NewStatus: "CRITICAL", OldPercentage: 40, NewPercentage: 10},
+		},
+	}
+
+	t.Run("Default Template", func(t *testing.T) {
+		var got map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("expected POST, got %s", r.Method)
+			}
+			if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("unexpected content type: %s", ct)
+			}
+			if sig := r.Header.Get(DefaultWebhookSignatureHeader); sig != "" {
+				t.Errorf("unexpected signature without secret: %s", sig)
+			}
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Errorf("body is not JSON: %v", err)
+			}
+		}))
+		defer server.Close()
+
+		wn, err := NewWebhookNotifier(server.URL, WebhookOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := wn.Send(ctx, msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got["title"] != msg.Title || got["severity"] != "critical" {
+			t.Errorf("unexpected payload: %v", got)
+		}
+		changes, ok := got["changes"].([]any)
+		if !ok || len(changes) != 1 {
+			t.Fatalf("expected 1 change, got %v", got["changes"])
+		}
+		if change := changes[0].(map[string]any); change["display_name"] != "Gemini 3 Pro" || change["new_percentage"] != float64(10) {
+			t.Errorf("unexpected change: %v", change)
+		}
+	})
+
+	t.Run("Custom Template Headers And Signature", func(t *testing.T) {
+		var body []byte
+		var header http.Header
+		var method string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method, header = r.Method, r.Header
+			body, _ = io.ReadAll(r.Body)
+		}))
+		defer server.Close()
+
+		wn, err := NewWebhookNotifier(server.URL, WebhookOptions{
+			Method:          "put",
+			Headers:         map[string]string{"Authorization": "Bearer abc", "Content-Type": "text/plain"},
+			Secret:          "s3cret",
+			SignatureHeader: "X-Hub-Signature-256",
+			BodyTemplate:    `{{.Severity}}:{{range .Changes}} {{.Account}}/{{.DisplayName}}={{.NewPercentage}}{{end}} [{{.Message.Title}}]`,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := wn.Send(ctx, msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if method != http.MethodPut {
+			t.Errorf("expected PUT, got %s", method)
+		}
+		if want := `critical: user@example.com/Gemini 3 Pro=10 [Test "Title"]`; string(body) != want {
+			t.Errorf("expected body %q, got %q", want, body)
+		}
+		if header.Get("Authorization") != "Bearer abc" || header.Get("Content-Type") != "text/plain" {
+			t.Errorf("configured headers not sent: %v", header)
+		}
+		if want := "sha256=" + SignWebhookBody("s3cret", body); header.Get("X-Hub-Signature-256") != want {
+			t.Errorf("expected signature %s, got %s", want, header.Get("X-Hub-Signature-256"))
+		}
+	})
+
+	t.Run("Error Response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		wn, err := NewWebhookNotifier(server.URL, WebhookOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		err = wn.Send(ctx, msg)
+		if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "nope") {
+			t.Errorf("expected 403 error with body, got %v", err)
+		}
+	})
+
+	t.Run("Not Configured", func(t *testing.T) {
+		wn, err := NewWebhookNotifier("", WebhookOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if wn.IsEnabled() {
+			t.Error("expected notifier without URL to be disabled")
+		}
+		if err := wn.Send(ctx, msg); err == nil {
+			t.Error("expected error when not configured")
+		}
+	})
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	if _, err := NewWebhookNotifier("http://example.com", WebhookOptions{BodyTemplate: "{{.Title"}); err == nil {
+		t.Error("expected parse error for a broken template")
+	}
+
+	wn, err := NewWebhookNotifier("http://example.com", WebhookOptions{BodyTemplate: "{{.Missing}}"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := wn.render(Message{}, time.Now()); err == nil {
+		t.Error("expected render error for an unknown field")
+	}
+
+	wn, err = NewWebhookNotifier("http://example.com", WebhookOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, err := wn.render(Message{Title: "Empty"}, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(body), `"changes":[]`) {
+		t.Errorf("expected empty changes array, got %s", body)
+	}
+}
+
+func TestSeverityString(t *testing.T) {
+	for severity, want := range map[Severity]string{
+		SeverityInfo:     "info",
+		SeverityRecovery: "recovery",
+		SeverityWarning:  "warning",
+		SeverityCritical: "critical",
+	} {
+		if got := severity.String(); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/gundamkid/anti-gravity-quota/internal/auth"
@@ -30,8 +31,28 @@ func DisplayAccountsList(accounts []auth.AccountInfo) {
 	style.Color.Separator = text.Colors{text.FgCyan}
 	t.SetStyle(style)
 
+	// The notes and error columns are only shown when some account has them
+	showNotes, showErrors := false, false
+	for _, acc := range accounts {
+		if acc.Notes != "" {
+			showNotes = true
+		}
+		if acc.Failing() {
+			showErrors = true
+		}
+	}
+
+	now := time.Now()
+
 	// Header
-	t.AppendHeader(table.Row{"", "Account", "Plan", "Status"})
+	header := table.Row{"", "Account", "Alias", "Labels", "Plan", "Status", "Last Used"}
+	if showErrors {
+		header = append(header, "Last Error")
+	}
+	if showNotes {
+		header = append(header, "Notes")
+	}
+	t.AppendHeader(header)
 
 	// Rows
 	for _, acc := range accounts {
@@ -45,7 +66,18 @@ func DisplayAccountsList(accounts []auth.AccountInfo) {
 			status = color.RedString("✗ Expired")
 		}
 
-		t.AppendRow(table.Row{marker, acc.Email, acc.TierName, status})
+		row := table.Row{marker, accountColor(acc.Color).Sprint(acc.Email), acc.Alias, strings.Join(acc.Labels, ", "), acc.TierName, status, formatAgo(acc.LastUsed, now)}
+		if showErrors {
+			lastError := ""
+			if acc.Failing() {
+				lastError = color.RedString("%s: %s", formatAgo(acc.LastError, now), truncateText(acc.LastErrorText, 40))
+			}
+			row = append(row, lastError)
+		}
+		if showNotes {
+			row = append(row, acc.Notes)
+		}
+		t.AppendRow(row)
 	}
 
 	// Indent the table slightly for better look
@@ -58,3 +90,53 @@ func DisplayAccountsList(accounts []auth.AccountInfo) {
 	fmt.Println("  " + color.YellowString("★") + " = Default account")
 	fmt.Println()
 }
+
+// accountColor returns the color an account was marked with, or no color
+func accountColor(name string) *color.Color {
+	switch name {
+	case "red":
+		return color.New(color.FgRed)
+	case "green":
+		return color.New(color.FgGreen)
+	case "yellow":
+		return color.New(color.FgYellow)
+	case "blue":
+		return color.New(color.FgBlue)
+	case "magenta":
+		return color.New(color.FgMagenta)
+	case "cyan":
+		return color.New(color.FgCyan)
+	case "white":
+		return color.New(color.FgWhite)
+	default:
+		return color.New()
+	}
+}
+
+// formatAgo formats how long ago t was, e.g. "3h ago"
+func formatAgo(t, now time.Time) string {
+	if t.IsZero() {
+		return color.HiBlackString("never")
+	}
+
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// truncateText shortens text to at most n runes
+func truncateText(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -54,6 +54,48 @@ func TestDisplayAccountsList(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Accounts with metadata",
+			accounts: []auth.AccountInfo{
+				{
+					Email:      "someone.long.name+work@gmail.com",
+					IsDefault:  true,
+					TokenValid: true,
+					Alias:      "work",
+					Labels:     []string{"ci", "team-A"},
+					Notes:      "Shared with the build runners",
+					Color:      "magenta",
+				},
+				{
+					Email:      "personal@gmail.com",
+					TokenValid: true,
+					Labels:     []string{"personal"},
+					Color:      "unknown",
+				},
+			},
+		},
+		{
+			name: "Accounts with activity",
+			accounts: []auth.AccountInfo{
+				{
+					Email:       "active@gmail.com",
+					IsDefault:   true,
+					TokenValid:  true,
+					LastUsed:    time.Now().Add(-5 * time.Minute),
+					LastSuccess: time.Now().Add(-5 * time.Minute),
+				},
+				{
+					Email:         "broken@gmail.com",
+					LastUsed:      time.Now().Add(-72 * time.Hour),
+					LastSuccess:   time.Now().Add(-30 * 24 * time.Hour),
+					LastError:     time.Now().Add(-72 * time.Hour),
+					LastErrorText: "failed to refresh token for broken@gmail.com: oauth2: \"invalid_grant\"",
+				},
+				{
+					Email: "never@gmail.com",
+				},
+			},
+		},
 		{
 			name:     "Empty accounts list",
 			accounts: []auth.AccountInfo{},
@@ -68,3 +110,26 @@ func TestDisplayAccountsList(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatAgo(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{now.Add(-30 * time.Second), "just now"},
+		{now.Add(-5 * time.Minute), "5m ago"},
+		{now.Add(-3 * time.Hour), "3h ago"},
+		{now.Add(-40 * 24 * time.Hour), "40d ago"},
+	}
+	for _, tt := range tests {
+		if got := formatAgo(tt.t, now); got != tt.want {
+			t.Errorf("formatAgo(%v) = %q, want %q", now.Sub(tt.t), got, tt.want)
+		}
+	}
+
+	if got := truncateText("abcdef", 4); got != "abc…" {
+		t.Errorf("truncateText = %q", got)
+	}
+}
@@ -25,16 +25,6 @@ func ClearTerminal() {
 	fmt.Print("\033[H\033[2J")
 }
 
-// DisplayQuotaSummaryJSON displays quota information in JSON format
-func DisplayQuotaSummaryJSON(summary *models.QuotaSummary) error {
-	data, err := json.MarshalIndent(summary, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %w", err)
-	}
-	fmt.Println(string(data))
-	return nil
-}
-
 // DisplayQuotaSummary displays quota information in a formatted table
 func DisplayQuotaSummary(summary *models.QuotaSummary, opts DisplayOptions) {
 	// Header
@@ -43,7 +33,7 @@ func DisplayQuotaSummary(summary *models.QuotaSummary, opts DisplayOptions) {
 	if summary.Email != "" {
 		tier := summary.TierName
 		if tier == "" {
-			tier = "Free 📦"
+			tier = "Unknown"
 		}
 		fmt.Printf("  📧 %s [%s]\n", summary.Email, tier)
 	}
@@ -193,6 +183,27 @@ func DisplayError(message string, err error) {
 	}
 }
 
+// ErrorOutput is the JSON shape of errors written to stderr in JSON mode
+type ErrorOutput struct {
+	Error   string `json:"error"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// DisplayErrorJSON writes an error to stderr in JSON format
+func DisplayErrorJSON(message string, code string, err error) {
+	out := ErrorOutput{Error: message, Code: code}
+	if err != nil {
+		out.Message = err.Error()
+	}
+	data, mErr := json.Marshal(out)
+	if mErr != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", message, err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(data))
+}
+
 // DisplayNotLoggedIn displays a message when user is not logged in
 func DisplayNotLoggedIn() {
 	color.Red("Not logged in")
@@ -238,16 +249,8 @@ type AccountQuotaResult struct {
 	Email        string               `json:"email"`
 	QuotaSummary *models.QuotaSummary `json:"quota_summary,omitempty"`
 	Error        string               `json:"error,omitempty"`
-}
-
-// DisplayAllAccountsQuotaJSON displays quota for all accounts in JSON format
-func DisplayAllAccountsQuotaJSON(results []*AccountQuotaResult) error {
-	data, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %w", err)
-	}
-	fmt.Println(string(data))
-	return nil
+	// ErrorCode is the machine-readable code of Error (e.g. RATE_LIMITED)
+	ErrorCode string `json:"error_code,omitempty"`
 }
 
 // DisplayAllAccountsQuota displays quota for all accounts in a formatted table
@@ -267,7 +270,11 @@ func DisplayAllAccountsQuota(results []*AccountQuotaResult, opts DisplayOptions)
 		// Account header
 		if result.Error != "" {
 			color.Red("  ✗ %s", result.Email)
-			fmt.Printf("    Error: %s\n", result.Error)
+			if result.ErrorCode != "" {
+				fmt.Printf("    Error [%s]: %s\n", result.ErrorCode, result.Error)
+			} else {
+				fmt.Printf("    Error: %s\n", result.Error)
+			}
 			fmt.Println()
 			continue
 		}
@@ -281,7 +288,7 @@ func DisplayAllAccountsQuota(results []*AccountQuotaResult, opts DisplayOptions)
 		// Display account email and tier
 		tier := result.QuotaSummary.TierName
 		if tier == "" {
-			tier = "Free 📦"
+			tier = "Unknown"
 		}
 		color.Cyan("  📧 %s [%s]", result.Email, tier)
 		fmt.Println()
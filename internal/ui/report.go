@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/jsonschema"
+	"github.com/gundamkid/anti-gravity-quota/internal/models"
+)
+
+//go:generate go test -run TestQuotaReportSchema -update
+
+// QuotaReportSchemaVersion is the version of the --json output. It is increased
+// whenever a field is removed or changes meaning; new fields may be added without it.
+const QuotaReportSchemaVersion = 1
+
+// QuotaReportSchemaID is the published location of the --json output schema
+const QuotaReportSchemaID = "https://raw.githubusercontent.com/gundamkid/anti-gravity-quota/main/docs/schema/quota-report.schema.json"
+
+// QuotaReport is the --json output, the same for one account or many
+type QuotaReport struct {
+	SchemaVersion int             `json:"schema_version" description:"Version of this schema, increased on breaking changes"`
+	GeneratedAt   time.Time       `json:"generated_at" description:"When the report was generated (UTC)"`
+	Accounts      []AccountReport `json:"accounts"`
+}
+
+// AccountReport is the quota of one account, or the error that prevented fetching it
+type AccountReport struct {
+	Email          string           `json:"email"`
+	Tier           *models.TierInfo `json:"tier,omitempty"`
+	ProjectID      string           `json:"project_id,omitempty"`
+	DefaultModelID string           `json:"default_model_id,omitempty"`
+	Endpoint       string           `json:"endpoint,omitempty" description:"Cloud Code API endpoint that served the data"`
+	FetchedAt      *time.Time       `json:"fetched_at,omitempty"`
+	Models         []ModelReport    `json:"models" description:"Models sorted by ID, empty if the fetch failed"`
+	Error          *ErrorReport     `json:"error,omitempty"`
+}
+
+// ModelReport is the quota of one model
+type ModelReport struct {
+	ModelID           string     `json:"model_id"`
+	DisplayName       string     `json:"display_name"`
+	Label             string     `json:"label,omitempty"`
+	Provider          string     `json:"provider,omitempty"`
+	RemainingFraction float64    `json:"remaining_fraction" description:"Remaining quota between 0 and 1"`
+	Percentage        int        `json:"percentage" description:"Remaining quota in percent, rounded down"`
+	Status            string     `json:"status" enum:"HEALTHY,WARNING,CRITICAL,EMPTY"`
+	IsExhausted       bool       `json:"is_exhausted"`
+	ResetTime         *time.Time `json:"reset_time,omitempty"`
+	SecondsUntilReset *int64     `json:"seconds_until_reset,omitempty" description:"Seconds from generated_at until the quota resets, 0 if already due"`
+}
+
+// ErrorReport describes why the quota of an account could not be fetched
+type ErrorReport struct {
+	Code    string `json:"code,omitempty" description:"Machine-readable error code, e.g. RATE_LIMITED"`
+	Message string `json:"message"`
+}
+
+// NewQuotaReport builds the --json output for the given results as of now
+func NewQuotaReport(results []*AccountQuotaResult, now time.Time) QuotaReport {
+	report := QuotaReport{
+		SchemaVersion: QuotaReportSchemaVersion,
+		GeneratedAt:   isoTime(now),
+		Accounts:      make([]AccountReport, 0, len(results)),
+	}
+
+	for _, res := range results {
+		if res == nil {
+			continue
+		}
+		report.Accounts = append(report.Accounts, newAccountReport(res, now))
+	}
+
+	return report
+}
+
+func newAccountReport(res *AccountQuotaResult, now time.Time) AccountReport {
+	account := AccountReport{
+		Email:  res.Email,
+		Models: []ModelReport{},
+	}
+
+	if res.Error != "" {
+		account.Error = &ErrorReport{Code: res.ErrorCode, Message: res.Error}
+	}
+
+	summary := res.QuotaSummary
+	if summary == nil {
+		return account
+	}
+
+	if summary.Tier != nil {
+		tier := *summary.Tier
+		account.Tier = &tier
+	}
+	account.ProjectID = summary.ProjectID
+	account.DefaultModelID = summary.DefaultModelID
+	account.Endpoint = summary.Endpoint
+	if !summary.FetchedAt.IsZero() {
+		fetchedAt := isoTime(summary.FetchedAt)
+		account.FetchedAt = &fetchedAt
+	}
+
+	for _, q := range summary.Models {
+		account.Models = append(account.Models, newModelReport(q, now))
+	}
+	sort.Slice(account.Models, func(i, j int) bool {
+		return account.Models[i].ModelID < account.Models[j].ModelID
+	})
+
+	return account
+}
+
+func newModelReport(q models.ModelQuota, now time.Time) ModelReport {
+	model := ModelReport{
+		ModelID:           q.ModelID,
+		DisplayName:       q.DisplayName,
+		Label:             q.Label,
+		Provider:          q.Provider,
+		RemainingFraction: q.RemainingFraction,
+		Percentage:        q.GetRemainingPercentage(),
+		Status:            q.GetStatusString(),
+		IsExhausted:       q.IsExhausted,
+	}
+
+	if !q.ResetTime.IsZero() {
+		resetTime := isoTime(q.ResetTime)
+		seconds := int64(max(q.ResetTime.Sub(now), 0) / time.Second)
+		model.ResetTime = &resetTime
+		model.SecondsUntilReset = &seconds
+	}
+
+	return model
+}
+
+// isoTime normalizes a timestamp to UTC with second precision
+func isoTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
+
+// QuotaReportSchema returns the JSON Schema of the --json output
+func QuotaReportSchema() ([]byte, error) {
+	schema, err := jsonschema.Generate(QuotaReport{}, QuotaReportSchemaID, "ag-quota quota report")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema: %w", err)
+	}
+	schema.Description = fmt.Sprintf("Output of ag-quota --json (schema version %d)", QuotaReportSchemaVersion)
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// DisplayQuotaReportJSON displays the quota of one or more accounts in JSON format
+func DisplayQuotaReportJSON(results []*AccountQuotaResult) error {
+	data, err := json.MarshalIndent(NewQuotaReport(results, time.Now()), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
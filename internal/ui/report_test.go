@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gundamkid/anti-gravity-quota/internal/models"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// schemaPath is where the published JSON Schema lives, relative to this package
+const schemaPath = "../../docs/schema/quota-report.schema.json"
+
+// checkGolden compares got with a golden file, or rewrites it with -update
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("failed to update %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s (run with -update to create it): %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date, run 'go test ./internal/ui -update' and review the diff.\ngot:\n%s", path, got)
+	}
+}
+
+func reportJSON(t *testing.T, results []*AccountQuotaResult, now time.Time) []byte {
+	t.Helper()
+
+	data, err := json.MarshalIndent(NewQuotaReport(results, now), "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal report: %v", err)
+	}
+	return append(data, '\n')
+}
+
+func testSummary(now time.Time) *models.QuotaSummary {
+	tier := models.NewTierInfo(&models.Tier{ID: "g1-pro-tier", Name: "Google AI Pro"})
+	return &models.QuotaSummary{
+		Email:          "user@example.com",
+		TierName:       tier.Label,
+		Tier:           &tier,
+		ProjectID:      "project-1",
+		DefaultModelID: "gemini-3-flash",
+		Endpoint:       "https://cloudcode-pa.googleapis.com",
+		FetchedAt:      now.Add(-time.Second),
+		Models: []models.ModelQuota{
+			{ModelID: "gemini-3-flash", DisplayName: "Gemini 3 Flash", Provider: "MODEL_PROVIDER_GOOGLE", RemainingFraction: 1, ResetTime: now.Add(5 * time.Hour)},
+			{ModelID: "claude-sonnet-4-5", DisplayName: "Claude Sonnet 4.5", Provider: "MODEL_PROVIDER_ANTHROPIC", RemainingFraction: 0.15, ResetTime: now.Add(90 * time.Minute)},
+			{ModelID: "gpt-oss-120b", DisplayName: "GPT-OSS 120B", IsExhausted: true, ResetTime: now.Add(-time.Minute)},
+		},
+	}
+}
+
+func TestQuotaReport_SingleAccount(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.FixedZone("CET", 3600))
+	results := []*AccountQuotaResult{{Email: "user@example.com", QuotaSummary: testSummary(now)}}
+
+	checkGolden(t, filepath.Join("testdata", "report_single.golden.json"), reportJSON(t, results, now))
+}
+
+func TestQuotaReport_AllAccounts(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	results := []*AccountQuotaResult{
+		{Email: "user@example.com", QuotaSummary: testSummary(now)},
+		{Email: "limited@example.com", Error: "API error 429 (RESOURCE_EXHAUSTED): quota exceeded", ErrorCode: "RATE_LIMITED"},
+	}
+
+	checkGolden(t, filepath.Join("testdata", "report_all.golden.json"), reportJSON(t, results, now))
+}
+
+func TestQuotaReport_DerivedFields(t *testing.T) {
+	now := time.Now()
+	report := NewQuotaReport([]*AccountQuotaResult{{Email: "user@example.com", QuotaSummary: testSummary(now)}}, now)
+
+	if report.SchemaVersion != QuotaReportSchemaVersion {
+		t.Errorf("expected schema version %d, got %d", QuotaReportSchemaVersion, report.SchemaVersion)
+	}
+
+	models := report.Accounts[0].Models
+	if models[0].ModelID != "claude-sonnet-4-5" {
+		t.Fatalf("expected models sorted by ID, got %s first", models[0].ModelID)
+	}
+	if models[0].Percentage != 15 || models[0].Status != "CRITICAL" || *models[0].SecondsUntilReset != 5400 {
+		t.Errorf("unexpected derived fields: %+v", models[0])
+	}
+	if *models[2].SecondsUntilReset != 0 {
+		t.Errorf("expected a past reset to count as due, got %d", *models[2].SecondsUntilReset)
+	}
+}
+
+func TestQuotaReportSchema(t *testing.T) {
+	schema, err := QuotaReportSchema()
+	if err != nil {
+		t.Fatalf("QuotaReportSchema failed: %v", err)
+	}
+
+	checkGolden(t, schemaPath, schema)
+}